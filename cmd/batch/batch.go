@@ -0,0 +1,615 @@
+// Package batch provides a gh command to run many inference requests from a JSONL file.
+package batch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/github/gh-models/cmd/run"
+	"github.com/github/gh-models/internal/azuremodels"
+	"github.com/github/gh-models/pkg/command"
+	"github.com/github/gh-models/pkg/prompt"
+	"github.com/github/gh-models/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultConcurrency = 4
+	defaultRetries     = 3
+
+	// maxLineSize is the longest input or output line the command will read.
+	maxLineSize = 16 * 1024 * 1024
+)
+
+// Request represents a single line of the input file. A line either contains an OpenAI
+// batch-style request in Body, or references a prompt file with template variables.
+type Request struct {
+	CustomID string                             `json:"custom_id,omitempty"`
+	Method   string                             `json:"method,omitempty"`
+	URL      string                             `json:"url,omitempty"`
+	Body     *azuremodels.ChatCompletionOptions `json:"body,omitempty"`
+
+	File  string                 `json:"file,omitempty"`
+	Vars  map[string]interface{} `json:"vars,omitempty"`
+	Model string                 `json:"model,omitempty"`
+}
+
+// Result represents a single line of the output file, in the OpenAI batch output format.
+type Result struct {
+	CustomID string    `json:"custom_id"`
+	Response *Response `json:"response"`
+	Error    *Error    `json:"error"`
+}
+
+// Response represents a successful response to a batch request.
+type Response struct {
+	StatusCode int          `json:"status_code"`
+	Body       ResponseBody `json:"body"`
+}
+
+// ResponseBody represents the chat completion returned for a batch request.
+type ResponseBody struct {
+	Model   string                           `json:"model"`
+	Choices []ResponseChoice                 `json:"choices"`
+	Usage   *azuremodels.ChatCompletionUsage `json:"usage,omitempty"`
+}
+
+// ResponseChoice represents a choice in a batch response.
+type ResponseChoice struct {
+	Index        int                     `json:"index"`
+	Message      azuremodels.ChatMessage `json:"message"`
+	FinishReason string                  `json:"finish_reason"`
+}
+
+// Error represents a failed batch request.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewBatchCommand returns a new command to run inference requests in batches.
+func NewBatchCommand(cfg *command.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "batch --input <file>",
+		Short: "Run inference requests from a JSONL file",
+		Long: heredoc.Docf(`
+			Runs every request in a JSONL file and writes one JSON result per line.
+
+			Each input line is either an OpenAI batch-style request:
+
+			  {"custom_id": "req-1", "method": "POST", "url": "/v1/chat/completions", "body": {"model": "openai/gpt-4o-mini", "messages": [{"role": "user", "content": "Hello"}]}}
+
+			or a reference to a prompt file with template variables, optionally overriding its model:
+
+			  {"custom_id": "req-2", "file": "greeting.prompt.yml", "vars": {"name": "Alice"}, "model": "openai/gpt-4.1"}
+
+			Requests run concurrently, and rate limited requests are retried. Results are written in the
+			same order as the input, in the OpenAI batch output format, and identified by their %[1]scustom_id%[1]s.
+			Lines without a %[1]scustom_id%[1]s are given one based on their line number.
+
+			Prompt file paths are relative to the input file. Their variables are checked against the variables
+			the prompt file declares, and with %[1]s--strict-vars%[1]s a request fails to load if its messages
+			reference a variable that is not set.
+
+			With %[1]s--output%[1]s, each result is saved to the output file as soon as its request finishes, and
+			the file is rewritten in input order once every request has finished. Use %[1]s--resume%[1]s to continue
+			a batch that was interrupted: requests that already have a successful result are skipped, and failed
+			requests are run again. Results of requests that are no longer in the input file are dropped.
+		`, "`"),
+		Example: heredoc.Doc(`
+			gh models batch --input requests.jsonl --output results.jsonl
+			gh models batch --input requests.jsonl --output results.jsonl --concurrency 8 --resume
+			cat requests.jsonl | gh models batch --input - > results.jsonl
+		`),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inputPath, _ := cmd.Flags().GetString("input")
+			if inputPath == "" {
+				return errors.New("an input file must be specified with --input")
+			}
+			outputPath, _ := cmd.Flags().GetString("output")
+			resume, _ := cmd.Flags().GetBool("resume")
+			if resume && outputPath == "" {
+				return errors.New("--resume requires --output")
+			}
+
+			concurrency, _ := cmd.Flags().GetInt("concurrency")
+			if concurrency < 1 {
+				return errors.New("--concurrency must be at least 1")
+			}
+			retries, _ := cmd.Flags().GetInt("retries")
+			if retries < 0 {
+				return errors.New("--retries must not be negative")
+			}
+			org, _ := cmd.Flags().GetString("org")
+			strictVars, _ := cmd.Flags().GetBool("strict-vars")
+
+			handler := &batchCommandHandler{
+				ctx:        cmd.Context(),
+				cfg:        cfg,
+				client:     cfg.Client,
+				org:        org,
+				retries:    retries,
+				strictVars: strictVars,
+			}
+
+			models, err := handler.client.ListModels(handler.ctx)
+			if err != nil {
+				return err
+			}
+
+			jobs, err := handler.loadJobs(inputPath, models)
+			if err != nil {
+				return err
+			}
+
+			var completed []Result
+			if resume {
+				completed, err = readCompletedResults(outputPath)
+				if err != nil {
+					return err
+				}
+			}
+			skipped := markCompleted(jobs, completed)
+
+			var save func(Result) error
+			var ordered *os.File
+			out := cfg.Out
+			if outputPath != "" {
+				// Every result is added to the output file as soon as its request finishes, so that an
+				// interrupted batch can be resumed without losing any of them. When resuming, the results
+				// of the previous run stay in the file until it is replaced.
+				flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+				if resume {
+					flags = os.O_CREATE | os.O_RDWR | os.O_APPEND
+				}
+				file, err := os.OpenFile(outputPath, flags, 0644)
+				if err != nil {
+					return fmt.Errorf("failed to create output file: %w", err)
+				}
+				defer file.Close()
+				if err := endLine(file); err != nil {
+					return fmt.Errorf("failed to write output file: %w", err)
+				}
+				save = resultWriter(file)
+
+				// The results are also written in input order to a temporary file, which replaces the
+				// output file once every request has finished
+				ordered, err = os.CreateTemp(filepath.Dir(outputPath), "."+filepath.Base(outputPath)+"-*")
+				if err != nil {
+					return fmt.Errorf("failed to create output file: %w", err)
+				}
+				defer os.Remove(ordered.Name())
+				defer ordered.Close()
+				out = ordered
+			}
+
+			// The results that are kept when resuming are rewritten in input order along with the new ones
+			failed, err := handler.runJobs(jobs, concurrency, save, resultWriter(out))
+			if err != nil {
+				return err
+			}
+			if ordered != nil {
+				if err := replaceFile(ordered, outputPath); err != nil {
+					return fmt.Errorf("failed to write output file: %w", err)
+				}
+			}
+
+			ran := len(jobs) - skipped
+			if outputPath != "" {
+				util.WriteToOut(cfg.ErrOut, fmt.Sprintf("Wrote %d results to %s (%d skipped, %d failed)\n", ran, outputPath, skipped, failed))
+			}
+
+			if failed > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d of %d requests failed", failed, ran)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("input", "", "Path to a JSONL file with one request per line, or - to read from stdin")
+	cmd.Flags().String("output", "", "Path to write JSONL results to (default: stdout)")
+	cmd.Flags().Int("concurrency", defaultConcurrency, "Maximum number of requests to run at the same time")
+	cmd.Flags().Int("retries", defaultRetries, "Maximum number of times to retry a rate limited request")
+	cmd.Flags().Bool("resume", false, "Skip requests that already have a successful result in the output file")
+	cmd.Flags().Bool("strict-vars", false, "Fail if a prompt file references a template variable that is not set")
+	cmd.Flags().String("org", "", "Organization to attribute usage to (omitting will attribute usage to the current actor")
+
+	return cmd
+}
+
+type batchCommandHandler struct {
+	ctx     context.Context
+	cfg     *command.Config
+	client  azuremodels.Client
+	org     string
+	retries int
+	// strictVars makes prompt file requests fail to load when they reference a variable that is not set
+	strictVars bool
+}
+
+// batchJob is a request that is ready to be sent to the model.
+type batchJob struct {
+	customID string
+	req      azuremodels.ChatCompletionOptions
+	// completed is the successful result of a previous run, which is kept instead of sending the request
+	completed *Result
+}
+
+// loadJobs reads and validates every request in the input file before any of them are sent.
+func (h *batchCommandHandler) loadJobs(inputPath string, models []*azuremodels.ModelSummary) ([]batchJob, error) {
+	var in io.Reader
+	// Prompt files are relative to the input file, or to the working directory when reading stdin
+	dir := ""
+	if inputPath == "-" {
+		in = os.Stdin
+	} else {
+		dir = filepath.Dir(inputPath)
+		file, err := os.Open(inputPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open input file: %w", err)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	promptFiles := make(map[string]*prompt.File)
+	seen := make(map[string]int)
+
+	var jobs []batchJob
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var request Request
+		if err := json.Unmarshal([]byte(line), &request); err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON: %w", lineNumber, err)
+		}
+
+		req, err := h.buildRequest(request, dir, promptFiles)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		if req.Model == "" {
+			return nil, fmt.Errorf("line %d: a model must be specified", lineNumber)
+		}
+		req.Model, err = run.ValidateModelName(req.Model, models)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		req.StreamOptions = &azuremodels.StreamOptions{IncludeUsage: true}
+
+		customID := request.CustomID
+		if customID == "" {
+			customID = fmt.Sprintf("request-%d", lineNumber)
+		}
+		if previous, ok := seen[customID]; ok {
+			return nil, fmt.Errorf("line %d: custom_id '%s' is already used on line %d", lineNumber, customID, previous)
+		}
+		seen[customID] = lineNumber
+
+		jobs = append(jobs, batchJob{customID: customID, req: req})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read input file: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// buildRequest converts an input line into a chat completion request. Prompt files are loaded
+// relative to dir and cached in promptFiles.
+func (h *batchCommandHandler) buildRequest(request Request, dir string, promptFiles map[string]*prompt.File) (azuremodels.ChatCompletionOptions, error) {
+	switch {
+	case request.Body != nil && request.File != "":
+		return azuremodels.ChatCompletionOptions{}, errors.New("a request must contain either 'body' or 'file', not both")
+
+	case request.Body != nil:
+		if request.Method != "" && !strings.EqualFold(request.Method, "POST") {
+			return azuremodels.ChatCompletionOptions{}, fmt.Errorf("unsupported method '%s'", request.Method)
+		}
+		if request.URL != "" && !strings.HasSuffix(request.URL, "/chat/completions") {
+			return azuremodels.ChatCompletionOptions{}, fmt.Errorf("unsupported url '%s': only chat completions are supported", request.URL)
+		}
+		req := *request.Body
+		if request.Model != "" {
+			req.Model = request.Model
+		}
+		if len(req.Messages) == 0 {
+			return azuremodels.ChatCompletionOptions{}, errors.New("the request body must contain messages")
+		}
+		return req, nil
+
+	case request.File != "":
+		filePath := request.File
+		if !filepath.IsAbs(filePath) {
+			filePath = filepath.Join(dir, filePath)
+		}
+		pf, ok := promptFiles[filePath]
+		if !ok {
+			var err error
+			pf, err = prompt.LoadFromFile(filePath)
+			if err != nil {
+				return azuremodels.ChatCompletionOptions{}, fmt.Errorf("failed to load prompt file: %w", err)
+			}
+			promptFiles[filePath] = pf
+		}
+
+		vars, err := pf.ResolveVariables(request.Vars)
+		if err != nil {
+			return azuremodels.ChatCompletionOptions{}, err
+		}
+		var opts []prompt.TemplateOption
+		if h.strictVars {
+			opts = append(opts, prompt.WithStrictVariables())
+		}
+
+		var messages []azuremodels.ChatMessage
+		for _, m := range pf.Messages {
+			content, err := prompt.TemplateString(m.Content, vars, opts...)
+			if err != nil {
+				return azuremodels.ChatCompletionOptions{}, err
+			}
+			role, err := prompt.GetAzureChatMessageRole(m.Role)
+			if err != nil {
+				return azuremodels.ChatCompletionOptions{}, err
+			}
+			messages = append(messages, azuremodels.ChatMessage{Role: role, Content: util.Ptr(content)})
+		}
+
+		req := pf.BuildChatCompletionOptions(messages)
+		if request.Model != "" {
+			req.Model = request.Model
+		}
+		return req, nil
+
+	default:
+		return azuremodels.ChatCompletionOptions{}, errors.New("a request must contain either 'body' or 'file'")
+	}
+}
+
+// readCompletedResults reads the successful results from a previous run. A missing file is
+// treated as an empty one, and lines that cannot be parsed, such as a partially written last
+// line, are ignored.
+func readCompletedResults(outputPath string) ([]Result, error) {
+	file, err := os.Open(outputPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open output file: %w", err)
+	}
+	defer file.Close()
+
+	var results []Result
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var result Result
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			continue
+		}
+		if result.Error == nil && result.Response != nil && result.CustomID != "" {
+			results = append(results, result)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read output file: %w", err)
+	}
+
+	return results, nil
+}
+
+// markCompleted attaches the results of a previous run to the jobs they belong to, and returns the
+// number of jobs that will be skipped. Results of requests that are not in the input are dropped.
+func markCompleted(jobs []batchJob, completed []Result) int {
+	done := make(map[string]Result, len(completed))
+	for _, result := range completed {
+		done[result.CustomID] = result
+	}
+
+	skipped := 0
+	for i := range jobs {
+		if result, ok := done[jobs[i].customID]; ok {
+			jobs[i].completed = &result
+			skipped++
+		}
+	}
+	return skipped
+}
+
+// resultWriter returns a function that writes results to w as JSON lines
+func resultWriter(w io.Writer) func(Result) error {
+	return func(result Result) error {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		// Write every line at once so that an interrupted batch can be resumed
+		_, err = w.Write(append(data, '\n'))
+		return err
+	}
+}
+
+// endLine ends the last line of a file that was interrupted in the middle of a line, so that the
+// results added to it start on a line of their own
+func endLine(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	_, err = file.Write([]byte{'\n'})
+	return err
+}
+
+// replaceFile closes the temporary file and moves it to filePath, keeping the mode of the file it replaces
+func replaceFile(temp *os.File, filePath string) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(filePath); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := temp.Chmod(mode); err != nil {
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), filePath)
+}
+
+// runJobs runs the jobs with at most concurrency requests in flight. The result of every request
+// is passed to save, when it is set, as soon as the request finishes, and the results are written
+// in input order as soon as they are available. Jobs that are already completed are written without
+// sending their request or saving them again. It returns the number of failed requests.
+func (h *batchCommandHandler) runJobs(jobs []batchJob, concurrency int, save, write func(Result) error) (int, error) {
+	type indexedResult struct {
+		index  int
+		result Result
+	}
+
+	results := make(chan indexedResult)
+	go func() {
+		sem := make(chan struct{}, concurrency)
+		for i, job := range jobs {
+			if job.completed != nil {
+				results <- indexedResult{index: i, result: *job.completed}
+				continue
+			}
+			sem <- struct{}{}
+			go func(i int, job batchJob) {
+				defer func() { <-sem }()
+				results <- indexedResult{index: i, result: h.runJob(job)}
+			}(i, job)
+		}
+	}()
+
+	pending := make(map[int]Result)
+	next := 0
+	failed := 0
+	var writeErr error
+	for range jobs {
+		r := <-results
+		pending[r.index] = r.result
+		if save != nil && jobs[r.index].completed == nil && writeErr == nil {
+			writeErr = save(r.result)
+		}
+
+		for {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+
+			if result.Error != nil {
+				failed++
+			}
+			// Keep receiving results after a write error so that no worker is left blocked
+			if writeErr == nil {
+				writeErr = write(result)
+			}
+		}
+	}
+
+	return failed, writeErr
+}
+
+// runJob sends a single request, retrying when rate limited.
+func (h *batchCommandHandler) runJob(job batchJob) Result {
+	result := Result{CustomID: job.customID}
+
+	for attempt := 0; ; attempt++ {
+		body, err := h.complete(job.req)
+		if err == nil {
+			result.Response = &Response{StatusCode: 200, Body: *body}
+			return result
+		}
+
+		var rateLimitErr *azuremodels.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			if attempt < h.retries {
+				select {
+				case <-h.ctx.Done():
+					result.Error = &Error{Code: "canceled", Message: h.ctx.Err().Error()}
+					return result
+				case <-time.After(rateLimitErr.RetryAfter):
+					continue
+				}
+			}
+			result.Error = &Error{Code: "rate_limit_exceeded", Message: fmt.Sprintf("rate limit exceeded after %d attempts: %v", attempt+1, err)}
+			return result
+		}
+
+		result.Error = &Error{Code: "request_failed", Message: err.Error()}
+		return result
+	}
+}
+
+func (h *batchCommandHandler) complete(req azuremodels.ChatCompletionOptions) (*ResponseBody, error) {
+	resp, err := h.client.GetChatCompletionStream(h.ctx, req, h.org)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Reader.Close()
+
+	body := &ResponseBody{Model: req.Model}
+	var content strings.Builder
+	finishReason := ""
+	for {
+		completion, err := resp.Reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+
+		if completion.Usage != nil {
+			body.Usage = completion.Usage
+		}
+		for _, choice := range completion.Choices {
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+			if choice.Delta != nil && choice.Delta.Content != nil {
+				content.WriteString(*choice.Delta.Content)
+			} else if choice.Message != nil && choice.Message.Content != nil {
+				content.WriteString(*choice.Message.Content)
+			}
+		}
+	}
+
+	body.Choices = []ResponseChoice{{
+		Message: azuremodels.ChatMessage{
+			Role:    azuremodels.ChatMessageRoleAssistant,
+			Content: util.Ptr(content.String()),
+		},
+		FinishReason: finishReason,
+	}}
+	return body, nil
+}
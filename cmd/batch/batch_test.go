@@ -0,0 +1,350 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/github/gh-models/internal/azuremodels"
+	"github.com/github/gh-models/internal/sse"
+	"github.com/github/gh-models/pkg/command"
+	"github.com/github/gh-models/pkg/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(reply func(req azuremodels.ChatCompletionOptions) (string, error)) (*azuremodels.MockClient, *[]azuremodels.ChatCompletionOptions) {
+	client := azuremodels.NewMockClient()
+	client.MockListModels = func(ctx context.Context) ([]*azuremodels.ModelSummary, error) {
+		return []*azuremodels.ModelSummary{
+			{ID: "openai/model-a", Name: "model-a", Publisher: "openai", Task: "chat-completion"},
+			{ID: "openai/model-b", Name: "model-b", Publisher: "openai", Task: "chat-completion"},
+		}, nil
+	}
+
+	var mu sync.Mutex
+	var requests []azuremodels.ChatCompletionOptions
+	client.MockGetChatCompletionStream = func(ctx context.Context, req azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+
+		content, err := reply(req)
+		if err != nil {
+			return nil, err
+		}
+		return &azuremodels.ChatCompletionResponse{
+			Reader: sse.NewMockEventReader([]azuremodels.ChatCompletion{
+				{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: util.Ptr(content)}, FinishReason: "stop"}}},
+				{Usage: &azuremodels.ChatCompletionUsage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 3}},
+			}),
+		}, nil
+	}
+	return client, &requests
+}
+
+// echoReply replies with the content of the last message, after a delay that makes
+// earlier requests finish last.
+func echoReply(req azuremodels.ChatCompletionOptions) (string, error) {
+	content := *req.Messages[len(req.Messages)-1].Content
+	time.Sleep(time.Duration(10-len(content)) * time.Millisecond)
+	return "echo: " + content, nil
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func readResults(t *testing.T, data []byte) []Result {
+	t.Helper()
+	var results []Result
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var result Result
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &result))
+		results = append(results, result)
+	}
+	return results
+}
+
+func TestBatch(t *testing.T) {
+	t.Run("runs OpenAI batch-style requests in input order", func(t *testing.T) {
+		input := writeFile(t, "requests.jsonl", strings.Join([]string{
+			`{"custom_id": "first", "method": "POST", "url": "/v1/chat/completions", "body": {"model": "openai/model-a", "messages": [{"role": "user", "content": "a"}], "temperature": 0.5}}`,
+			``,
+			`{"body": {"model": "openai/model-b", "messages": [{"role": "user", "content": "bbbb"}]}}`,
+			`{"custom_id": "third", "body": {"model": "openai/model-a", "messages": [{"role": "user", "content": "cccccccc"}]}}`,
+		}, "\n"))
+
+		client, requests := newTestClient(echoReply)
+		out := new(bytes.Buffer)
+		cmd := NewBatchCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--input", input, "--concurrency", "3"})
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+		require.Len(t, *requests, 3)
+
+		results := readResults(t, out.Bytes())
+		require.Len(t, results, 3)
+		require.Equal(t, "first", results[0].CustomID)
+		require.Equal(t, "request-3", results[1].CustomID)
+		require.Equal(t, "third", results[2].CustomID)
+
+		require.Nil(t, results[0].Error)
+		require.Equal(t, 200, results[0].Response.StatusCode)
+		require.Equal(t, "openai/model-a", results[0].Response.Body.Model)
+		require.Equal(t, "echo: a", *results[0].Response.Body.Choices[0].Message.Content)
+		require.Equal(t, "stop", results[0].Response.Body.Choices[0].FinishReason)
+		require.Equal(t, 3, results[0].Response.Body.Usage.TotalTokens)
+		require.Equal(t, "echo: bbbb", *results[1].Response.Body.Choices[0].Message.Content)
+	})
+
+	t.Run("runs prompt file requests with variables", func(t *testing.T) {
+		promptFile := writeFile(t, "greeting.prompt.yml", `
+name: Greeting
+model: openai/model-a
+modelParameters:
+  maxTokens: 10
+messages:
+  - role: system
+    content: You are friendly.
+  - role: user
+    content: "Greet {{name}}"
+`)
+		input := writeFile(t, "requests.jsonl", strings.Join([]string{
+			`{"custom_id": "alice", "file": "` + promptFile + `", "vars": {"name": "Alice"}}`,
+			`{"custom_id": "bob", "file": "` + promptFile + `", "vars": {"name": "Bob"}, "model": "openai/model-b"}`,
+		}, "\n"))
+
+		client, requests := newTestClient(echoReply)
+		out := new(bytes.Buffer)
+		cmd := NewBatchCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--input", input, "--concurrency", "1"})
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+
+		require.Len(t, *requests, 2)
+		require.Equal(t, "openai/model-a", (*requests)[0].Model)
+		require.Equal(t, 10, *(*requests)[0].MaxTokens)
+		require.Equal(t, "You are friendly.", *(*requests)[0].Messages[0].Content)
+		require.Equal(t, "Greet Alice", *(*requests)[0].Messages[1].Content)
+		require.Equal(t, "openai/model-b", (*requests)[1].Model)
+
+		results := readResults(t, out.Bytes())
+		require.Equal(t, "echo: Greet Bob", *results[1].Response.Body.Choices[0].Message.Content)
+	})
+
+	t.Run("resumes from a partially written output file", func(t *testing.T) {
+		input := writeFile(t, "requests.jsonl", strings.Join([]string{
+			`{"custom_id": "one", "body": {"model": "openai/model-a", "messages": [{"role": "user", "content": "1"}]}}`,
+			`{"custom_id": "two", "body": {"model": "openai/model-a", "messages": [{"role": "user", "content": "2"}]}}`,
+			`{"custom_id": "three", "body": {"model": "openai/model-a", "messages": [{"role": "user", "content": "3"}]}}`,
+		}, "\n"))
+		output := writeFile(t, "results.jsonl", strings.Join([]string{
+			`{"custom_id": "one", "response": {"status_code": 200, "body": {"model": "openai/model-a", "choices": [{"index": 0, "message": {"role": "assistant", "content": "previous"}, "finish_reason": "stop"}]}}, "error": null}`,
+			`{"custom_id": "two", "response": null, "error": {"code": "request_failed", "message": "boom"}}`,
+			`{"custom_id": "three", "respo`,
+		}, "\n"))
+
+		client, requests := newTestClient(echoReply)
+		out := new(bytes.Buffer)
+		cmd := NewBatchCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--input", input, "--output", output, "--resume"})
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+		require.Len(t, *requests, 2)
+		require.Contains(t, out.String(), "Wrote 2 results to "+output+" (1 skipped, 0 failed)")
+
+		data, err := os.ReadFile(output)
+		require.NoError(t, err)
+		results := readResults(t, data)
+		require.Len(t, results, 3)
+		require.Equal(t, "one", results[0].CustomID)
+		require.Equal(t, "previous", *results[0].Response.Body.Choices[0].Message.Content)
+		require.Equal(t, "two", results[1].CustomID)
+		require.Equal(t, "echo: 2", *results[1].Response.Body.Choices[0].Message.Content)
+		require.Equal(t, "three", results[2].CustomID)
+	})
+
+	t.Run("resuming keeps results in input order and drops removed requests", func(t *testing.T) {
+		input := writeFile(t, "requests.jsonl", strings.Join([]string{
+			`{"custom_id": "one", "body": {"model": "openai/model-a", "messages": [{"role": "user", "content": "1"}]}}`,
+			`{"custom_id": "two", "body": {"model": "openai/model-a", "messages": [{"role": "user", "content": "2"}]}}`,
+			`{"custom_id": "three", "body": {"model": "openai/model-a", "messages": [{"role": "user", "content": "3"}]}}`,
+		}, "\n"))
+		output := writeFile(t, "results.jsonl", strings.Join([]string{
+			`{"custom_id": "removed", "response": {"status_code": 200, "body": {"model": "openai/model-a", "choices": []}}, "error": null}`,
+			`{"custom_id": "two", "response": {"status_code": 200, "body": {"model": "openai/model-a", "choices": [{"index": 0, "message": {"role": "assistant", "content": "previous"}, "finish_reason": "stop"}]}}, "error": null}`,
+		}, "\n"))
+
+		client, requests := newTestClient(echoReply)
+		out := new(bytes.Buffer)
+		cmd := NewBatchCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--input", input, "--output", output, "--resume"})
+
+		require.NoError(t, cmd.Execute())
+		require.Len(t, *requests, 2)
+
+		data, err := os.ReadFile(output)
+		require.NoError(t, err)
+		results := readResults(t, data)
+		require.Len(t, results, 3)
+		require.Equal(t, "one", results[0].CustomID)
+		require.Equal(t, "two", results[1].CustomID)
+		require.Equal(t, "previous", *results[1].Response.Body.Choices[0].Message.Content)
+		require.Equal(t, "three", results[2].CustomID)
+	})
+
+	t.Run("saves results while earlier requests are still running", func(t *testing.T) {
+		input := writeFile(t, "requests.jsonl", strings.Join([]string{
+			`{"custom_id": "one", "body": {"model": "openai/model-a", "messages": [{"role": "user", "content": "1"}]}}`,
+			`{"custom_id": "two", "body": {"model": "openai/model-a", "messages": [{"role": "user", "content": "2"}]}}`,
+			`{"custom_id": "three", "body": {"model": "openai/model-a", "messages": [{"role": "user", "content": "3"}]}}`,
+		}, "\n"))
+		output := writeFile(t, "results.jsonl", `{"custom_id": "one", "response": {"status_code": 200, "body": {"model": "openai/model-a", "choices": []}}, "error": null}`)
+
+		// The second request only finishes once the result of the third one is in the output file
+		saved := make(chan struct{})
+		client, _ := newTestClient(func(req azuremodels.ChatCompletionOptions) (string, error) {
+			content := *req.Messages[0].Content
+			if content == "2" {
+				<-saved
+			}
+			return "echo: " + content, nil
+		})
+		var savedEarly bool
+		go func() {
+			defer close(saved)
+			for range 500 {
+				data, _ := os.ReadFile(output)
+				if strings.Contains(string(data), `"custom_id":"three"`) {
+					savedEarly = true
+					return
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}()
+
+		out := new(bytes.Buffer)
+		cmd := NewBatchCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--input", input, "--output", output, "--resume", "--concurrency", "2"})
+		require.NoError(t, cmd.Execute())
+		require.True(t, savedEarly)
+
+		data, err := os.ReadFile(output)
+		require.NoError(t, err)
+		results := readResults(t, data)
+		require.Len(t, results, 3)
+		require.Equal(t, "one", results[0].CustomID)
+		require.Equal(t, "two", results[1].CustomID)
+		require.Equal(t, "three", results[2].CustomID)
+	})
+
+	t.Run("resolves prompt files relative to the input file", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "prompts"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "prompts", "greeting.prompt.yml"), []byte(`
+name: Greeting
+model: openai/model-a
+variables:
+  - name: greeting
+    default: Hello
+messages:
+  - role: user
+    content: "{{greeting}} {{name}}"
+`), 0644))
+		input := filepath.Join(dir, "requests.jsonl")
+		require.NoError(t, os.WriteFile(input, []byte(`{"custom_id": "alice", "file": "prompts/greeting.prompt.yml", "vars": {"name": "Alice"}}`), 0644))
+
+		client, requests := newTestClient(echoReply)
+		out := new(bytes.Buffer)
+		cmd := NewBatchCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--input", input})
+		require.NoError(t, cmd.Execute())
+		require.Equal(t, "Hello Alice", *(*requests)[0].Messages[0].Content)
+
+		require.NoError(t, os.WriteFile(input, []byte(`{"custom_id": "nobody", "file": "prompts/greeting.prompt.yml"}`), 0644))
+		cmd = NewBatchCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--input", input, "--strict-vars"})
+		require.ErrorContains(t, cmd.Execute(), "line 1: undefined template variable")
+	})
+
+	t.Run("retries rate limited requests and reports failures", func(t *testing.T) {
+		input := writeFile(t, "requests.jsonl", strings.Join([]string{
+			`{"custom_id": "limited", "body": {"model": "openai/model-a", "messages": [{"role": "user", "content": "limited"}]}}`,
+			`{"custom_id": "broken", "body": {"model": "openai/model-a", "messages": [{"role": "user", "content": "broken"}]}}`,
+		}, "\n"))
+
+		var mu sync.Mutex
+		attempts := 0
+		client, _ := newTestClient(func(req azuremodels.ChatCompletionOptions) (string, error) {
+			switch *req.Messages[0].Content {
+			case "broken":
+				return "", errors.New("bad request")
+			default:
+				mu.Lock()
+				defer mu.Unlock()
+				attempts++
+				if attempts < 3 {
+					return "", &azuremodels.RateLimitError{RetryAfter: time.Millisecond, Message: "slow down"}
+				}
+				return "ok", nil
+			}
+		})
+		out := new(bytes.Buffer)
+		cmd := NewBatchCommand(command.NewConfig(out, new(bytes.Buffer), client, true, 100))
+		cmd.SetArgs([]string{"--input", input, "--retries", "2"})
+
+		err := cmd.Execute()
+		require.EqualError(t, err, "1 of 2 requests failed")
+		require.Equal(t, 3, attempts)
+
+		results := readResults(t, out.Bytes())
+		require.Len(t, results, 2)
+		require.Equal(t, "ok", *results[0].Response.Body.Choices[0].Message.Content)
+		require.Nil(t, results[1].Response)
+		require.Equal(t, "request_failed", results[1].Error.Code)
+		require.Equal(t, "bad request", results[1].Error.Message)
+	})
+
+	t.Run("validates input before sending requests", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			line     string
+			expected string
+		}{
+			{"invalid JSON", `{"body": `, "line 1: invalid JSON"},
+			{"missing request", `{"custom_id": "x"}`, "line 1: a request must contain either 'body' or 'file'"},
+			{"unknown model", `{"body": {"model": "openai/unknown", "messages": [{"role": "user", "content": "hi"}]}}`, "line 1: The specified model 'openai/unknown' is not found"},
+			{"unsupported url", `{"url": "/v1/embeddings", "body": {"model": "openai/model-a", "messages": [{"role": "user", "content": "hi"}]}}`, "unsupported url '/v1/embeddings'"},
+			{"duplicate custom_id", "{\"custom_id\": \"x\", \"body\": {\"model\": \"openai/model-a\", \"messages\": [{\"role\": \"user\", \"content\": \"hi\"}]}}\n{\"custom_id\": \"x\", \"body\": {\"model\": \"openai/model-a\", \"messages\": [{\"role\": \"user\", \"content\": \"hi\"}]}}", "line 2: custom_id 'x' is already used on line 1"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				input := writeFile(t, "requests.jsonl", tt.line)
+				client, requests := newTestClient(echoReply)
+				out := new(bytes.Buffer)
+				cmd := NewBatchCommand(command.NewConfig(out, out, client, true, 100))
+				cmd.SetArgs([]string{"--input", input})
+
+				err := cmd.Execute()
+				require.ErrorContains(t, err, tt.expected)
+				require.Empty(t, *requests)
+			})
+		}
+	})
+}
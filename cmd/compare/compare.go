@@ -0,0 +1,557 @@
+// Package compare provides a gh command to compare the responses of several models side by side.
+package compare
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/briandowns/spinner"
+	"github.com/cli/go-gh/v2/pkg/tableprinter"
+	"github.com/github/gh-models/cmd/run"
+	"github.com/github/gh-models/internal/azuremodels"
+	"github.com/github/gh-models/pkg/command"
+	"github.com/github/gh-models/pkg/prompt"
+	"github.com/github/gh-models/pkg/util"
+	"github.com/mgutz/ansi"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lightGrayUnderline = ansi.ColorFunc("white+du")
+	red                = ansi.ColorFunc("red")
+)
+
+const (
+	layoutColumns = "columns"
+	layoutPanes   = "panes"
+
+	// columnGap is the number of spaces between columns in the columns layout.
+	columnGap = 3
+	// minColumnWidth is the narrowest column the columns layout will render before falling back to panes.
+	minColumnWidth = 30
+)
+
+// ComparisonResult represents the JSON output of a comparison.
+type ComparisonResult struct {
+	Messages []azuremodels.ChatMessage `json:"messages"`
+	Results  []ModelResult             `json:"results"`
+}
+
+// ModelResult represents the response of a single model in a comparison.
+type ModelResult struct {
+	Model        string                           `json:"model"`
+	Content      string                           `json:"content"`
+	FinishReason string                           `json:"finishReason,omitempty"`
+	Usage        *azuremodels.ChatCompletionUsage `json:"usage,omitempty"`
+	LatencyMs    int64                            `json:"latencyMs"`
+	Error        string                           `json:"error,omitempty"`
+}
+
+// NewCompareCommand returns a new command to compare the responses of several models.
+func NewCompareCommand(cfg *command.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "compare --model <model> --model <model> [prompt]",
+		Short: "Compare responses from several models",
+		Long: heredoc.Docf(`
+			Sends the same conversation to several models concurrently and shows their responses
+			side by side, together with the latency and token usage of each model.
+
+			Pass each model with the %[1]s--model%[1]s flag. The prompt can be given as an argument, piped
+			from another command, or loaded from a prompt file with %[1]s--file%[1]s, in which case template
+			variables can be passed with %[1]s--var%[1]s or loaded from a file with %[1]s--var-file%[1]s.
+
+			By default responses are rendered in columns when the terminal is wide enough, and otherwise
+			streamed into sequential panes. Columns are rendered once every model has finished, while panes
+			show each response as it streams. Use %[1]s--layout%[1]s to choose explicitly, or %[1]s--json%[1]s to output
+			structured data. Responses are streamed into panes when there is not room for columns of at
+			least %[2]d characters, even with %[1]s--layout columns%[1]s.
+
+			The command exits with a non-zero status if any model fails to respond.
+		`, "`", minColumnWidth),
+		Example: heredoc.Doc(`
+			gh models compare --model openai/gpt-4o-mini --model openai/gpt-4.1 "why is the sky blue?"
+			gh models compare --model openai/gpt-4o-mini --model mistral-ai/ministral-3b --file prompt.yml --var topic=AI
+			cat README.md | gh models compare --model openai/gpt-4o-mini --model openai/gpt-4.1 --json "summarize this text"
+		`),
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			modelNames, _ := cmd.Flags().GetStringArray("model")
+			if len(modelNames) < 2 {
+				return errors.New("at least two models must be specified with --model")
+			}
+
+			layout, _ := cmd.Flags().GetString("layout")
+			if layout != "" && layout != layoutColumns && layout != layoutPanes {
+				return fmt.Errorf("invalid layout '%s': must be one of %s or %s", layout, layoutColumns, layoutPanes)
+			}
+
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+			org, _ := cmd.Flags().GetString("org")
+
+			templateVars, err := util.ParseTemplateVariables(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			var pf *prompt.File
+			if filePath, _ := cmd.Flags().GetString("file"); filePath != "" {
+				pf, err = prompt.LoadFromFile(filePath)
+				if err != nil {
+					return err
+				}
+			}
+
+			input := strings.Join(args, " ")
+			if run.IsPipe(os.Stdin) {
+				piped, _ := io.ReadAll(os.Stdin)
+				if pipedContent := strings.TrimSpace(string(piped)); pipedContent != "" {
+					if input != "" {
+						input = input + "\n" + pipedContent
+					} else {
+						input = pipedContent
+					}
+				}
+			}
+			if pf == nil && input == "" {
+				return errors.New("a prompt must be provided as an argument, via stdin, or with --file")
+			}
+
+			systemPrompt, _ := cmd.Flags().GetString("system-prompt")
+			messages, err := buildMessages(pf, input, systemPrompt, templateVars)
+			if err != nil {
+				return err
+			}
+
+			handler := &compareCommandHandler{
+				ctx:    cmd.Context(),
+				cfg:    cfg,
+				client: cfg.Client,
+				org:    org,
+			}
+
+			models, err := handler.client.ListModels(handler.ctx)
+			if err != nil {
+				return err
+			}
+
+			resolved := make([]string, 0, len(modelNames))
+			for _, name := range modelNames {
+				modelName, err := run.ValidateModelName(name, models)
+				if err != nil {
+					return err
+				}
+				resolved = append(resolved, modelName)
+			}
+
+			requests := make([]azuremodels.ChatCompletionOptions, len(resolved))
+			for i, modelName := range resolved {
+				var req azuremodels.ChatCompletionOptions
+				if pf != nil {
+					req = pf.BuildChatCompletionOptions(messages)
+				} else {
+					req = azuremodels.ChatCompletionOptions{Messages: messages}
+				}
+				req.Model = modelName
+				req.StreamOptions = &azuremodels.StreamOptions{IncludeUsage: true}
+				requests[i] = req
+			}
+
+			layout = handler.chooseLayout(layout, len(requests))
+
+			streams := handler.startRequests(requests)
+
+			var results []ModelResult
+			switch {
+			case jsonOutput:
+				results = waitForResults(streams)
+				data, err := json.MarshalIndent(ComparisonResult{Messages: messages, Results: results}, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				cfg.WriteToOut(string(data) + "\n")
+				return failedModels(cmd, results)
+			case layout == layoutColumns:
+				sp := spinner.New(spinner.CharSets[14], 100*time.Millisecond, spinner.WithWriter(cfg.ErrOut))
+				sp.Start()
+				results = waitForResults(streams)
+				sp.Stop()
+				handler.renderColumns(results)
+			default:
+				results = handler.streamPanes(streams)
+			}
+
+			if err := handler.renderSummary(results); err != nil {
+				return err
+			}
+			return failedModels(cmd, results)
+		},
+	}
+
+	cmd.Flags().StringArray("model", []string{}, "Model to compare (can be used multiple times: --model a --model b)")
+	cmd.Flags().String("file", "", "Path to a .prompt.yml file.")
+	cmd.Flags().StringArray("var", []string{}, "Template variables for prompt files (can be used multiple times: --var name=value, --var name=@file or --var name=- for stdin)")
+	cmd.Flags().StringArray("var-file", []string{}, "Load template variables from a YAML, JSON or .env file (can be used multiple times)")
+	cmd.Flags().String("system-prompt", "", "Prompt the system.")
+	cmd.Flags().String("layout", "", "How to display responses: columns or panes (default: columns if the terminal is wide enough)")
+	cmd.Flags().Bool("json", false, "Output results in JSON format")
+	cmd.Flags().String("org", "", "Organization to attribute usage to (omitting will attribute usage to the current actor")
+
+	return cmd
+}
+
+type compareCommandHandler struct {
+	ctx    context.Context
+	cfg    *command.Config
+	client azuremodels.Client
+	org    string
+}
+
+// buildMessages builds the conversation sent to every model.
+func buildMessages(pf *prompt.File, input, systemPrompt string, templateVars map[string]string) ([]azuremodels.ChatMessage, error) {
+	var messages []azuremodels.ChatMessage
+	if systemPrompt != "" {
+		messages = append(messages, azuremodels.ChatMessage{
+			Role:    azuremodels.ChatMessageRoleSystem,
+			Content: util.Ptr(systemPrompt),
+		})
+	}
+
+	if pf == nil {
+		return append(messages, azuremodels.ChatMessage{
+			Role:    azuremodels.ChatMessageRoleUser,
+			Content: util.Ptr(input),
+		}), nil
+	}
+
+	templateData := map[string]interface{}{"input": input}
+	for key, value := range templateVars {
+		templateData[key] = value
+	}
+	// Apply the defaults, types and required checks of the variables the prompt file declares
+	templateData, err := pf.ResolveVariables(templateData)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range pf.Messages {
+		content, err := prompt.TemplateString(m.Content, templateData)
+		if err != nil {
+			return nil, err
+		}
+
+		role, err := prompt.GetAzureChatMessageRole(m.Role)
+		if err != nil {
+			return nil, err
+		}
+
+		// A system prompt passed on the command line replaces the one from the file
+		if role == azuremodels.ChatMessageRoleSystem && systemPrompt != "" {
+			continue
+		}
+
+		messages = append(messages, azuremodels.ChatMessage{Role: role, Content: util.Ptr(content)})
+	}
+
+	return messages, nil
+}
+
+// chooseLayout returns the layout to render the responses with. Columns are used when they are
+// requested, or by default in a terminal, as long as there is room for them, and panes otherwise.
+func (h *compareCommandHandler) chooseLayout(requested string, modelCount int) string {
+	if requested == layoutPanes || columnWidth(h.cfg.TerminalWidth, modelCount) < minColumnWidth {
+		return layoutPanes
+	}
+	if requested == layoutColumns || h.cfg.IsTerminalOutput {
+		return layoutColumns
+	}
+	return layoutPanes
+}
+
+func columnWidth(terminalWidth, columns int) int {
+	return (terminalWidth - columnGap*(columns-1)) / columns
+}
+
+// modelStream collects the streamed response of a single model so that it can be
+// rendered while other models are still responding.
+type modelStream struct {
+	mu     sync.Mutex
+	chunks []string
+	result ModelResult
+	done   bool
+	notify chan struct{}
+}
+
+func newModelStream(model string) *modelStream {
+	return &modelStream{
+		result: ModelResult{Model: model},
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (s *modelStream) update(fn func(s *modelStream)) {
+	s.mu.Lock()
+	fn(s)
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the chunks received since the given offset, and whether the stream is complete.
+func (s *modelStream) next(offset int) ([]string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.chunks[offset:], s.done
+}
+
+// startRequests sends every request concurrently and returns a stream per request, in order.
+func (h *compareCommandHandler) startRequests(requests []azuremodels.ChatCompletionOptions) []*modelStream {
+	streams := make([]*modelStream, len(requests))
+	for i, req := range requests {
+		stream := newModelStream(req.Model)
+		streams[i] = stream
+		go h.runRequest(req, stream)
+	}
+	return streams
+}
+
+func (h *compareCommandHandler) runRequest(req azuremodels.ChatCompletionOptions, stream *modelStream) {
+	start := time.Now()
+	err := h.readCompletion(req, stream)
+
+	stream.update(func(s *modelStream) {
+		s.result.Content = strings.Join(s.chunks, "")
+		s.result.LatencyMs = time.Since(start).Milliseconds()
+		if err != nil {
+			s.result.Error = err.Error()
+		}
+		s.done = true
+	})
+}
+
+func (h *compareCommandHandler) readCompletion(req azuremodels.ChatCompletionOptions, stream *modelStream) error {
+	resp, err := h.client.GetChatCompletionStream(h.ctx, req, h.org)
+	if err != nil {
+		return err
+	}
+	defer resp.Reader.Close()
+
+	for {
+		completion, err := resp.Reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		stream.update(func(s *modelStream) {
+			if completion.Usage != nil {
+				s.result.Usage = completion.Usage
+			}
+			for _, choice := range completion.Choices {
+				if choice.FinishReason != "" {
+					s.result.FinishReason = choice.FinishReason
+				}
+				if choice.Delta != nil && choice.Delta.Content != nil {
+					s.chunks = append(s.chunks, *choice.Delta.Content)
+				} else if choice.Message != nil && choice.Message.Content != nil {
+					s.chunks = append(s.chunks, *choice.Message.Content)
+				}
+			}
+		})
+	}
+}
+
+func waitForResults(streams []*modelStream) []ModelResult {
+	results := make([]ModelResult, len(streams))
+	for i, stream := range streams {
+		for {
+			if _, done := stream.next(0); done {
+				break
+			}
+			<-stream.notify
+		}
+		stream.mu.Lock()
+		results[i] = stream.result
+		stream.mu.Unlock()
+	}
+	return results
+}
+
+// streamPanes prints each model's response in its own pane, in order. The pane of the first
+// unfinished model is streamed live while the other models continue responding in the background.
+func (h *compareCommandHandler) streamPanes(streams []*modelStream) []ModelResult {
+	results := make([]ModelResult, len(streams))
+	for i, stream := range streams {
+		h.writeRule(stream.result.Model)
+
+		offset := 0
+		for {
+			chunks, done := stream.next(offset)
+			for _, chunk := range chunks {
+				h.cfg.WriteToOut(chunk)
+			}
+			offset += len(chunks)
+			if done {
+				break
+			}
+			<-stream.notify
+		}
+
+		stream.mu.Lock()
+		results[i] = stream.result
+		stream.mu.Unlock()
+
+		if results[i].Error != "" {
+			h.cfg.WriteToOut(red("Error: " + results[i].Error))
+		}
+		h.cfg.WriteToOut("\n\n")
+	}
+	return results
+}
+
+func (h *compareCommandHandler) writeRule(title string) {
+	h.cfg.WriteToOut(lightGrayUnderline(title) + "\n")
+	if h.cfg.IsTerminalOutput {
+		h.cfg.WriteToOut(strings.Repeat("─", max(h.cfg.TerminalWidth, 1)) + "\n")
+	}
+}
+
+// renderColumns prints the responses side by side, wrapping each to its column width.
+func (h *compareCommandHandler) renderColumns(results []ModelResult) {
+	width := columnWidth(h.cfg.TerminalWidth, len(results))
+	gap := strings.Repeat(" ", columnGap)
+
+	columns := make([][]string, len(results))
+	height := 0
+	for i, result := range results {
+		content := result.Content
+		if result.Error != "" {
+			content = "Error: " + result.Error
+		}
+		lines := []string{truncate(result.Model, width), strings.Repeat("─", width)}
+		lines = append(lines, wrapText(strings.TrimSpace(content), width)...)
+		columns[i] = lines
+		height = max(height, len(lines))
+	}
+
+	for row := 0; row < height; row++ {
+		var sb strings.Builder
+		for i, lines := range columns {
+			cell := ""
+			if row < len(lines) {
+				cell = lines[row]
+			}
+			if i < len(columns)-1 {
+				cell += strings.Repeat(" ", width-len([]rune(cell))) + gap
+			}
+			sb.WriteString(cell)
+		}
+		h.cfg.WriteToOut(strings.TrimRight(sb.String(), " ") + "\n")
+	}
+	h.cfg.WriteToOut("\n")
+}
+
+// failedModels returns an error if any model failed to respond. The errors have already been shown
+// with the results, so the usage is not printed.
+func failedModels(cmd *cobra.Command, results []ModelResult) error {
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	cmd.SilenceUsage = true
+	return fmt.Errorf("%d of %d models failed to respond", failed, len(results))
+}
+
+// renderSummary prints the latency and token usage of each model.
+func (h *compareCommandHandler) renderSummary(results []ModelResult) error {
+	printer := h.cfg.NewTablePrinter()
+	printer.AddHeader([]string{"MODEL", "LATENCY", "PROMPT TOKENS", "COMPLETION TOKENS", "TOTAL TOKENS", "FINISH REASON"}, tableprinter.WithColor(lightGrayUnderline))
+	for _, result := range results {
+		printer.AddField(result.Model)
+		printer.AddField(fmt.Sprintf("%.2fs", float64(result.LatencyMs)/1000))
+		if result.Usage != nil {
+			printer.AddField(fmt.Sprintf("%d", result.Usage.PromptTokens))
+			printer.AddField(fmt.Sprintf("%d", result.Usage.CompletionTokens))
+			printer.AddField(fmt.Sprintf("%d", result.Usage.TotalTokens))
+		} else {
+			printer.AddField("-")
+			printer.AddField("-")
+			printer.AddField("-")
+		}
+		if result.Error != "" {
+			printer.AddField("error", tableprinter.WithColor(red))
+		} else {
+			printer.AddField(result.FinishReason)
+		}
+		printer.EndRow()
+	}
+	return printer.Render()
+}
+
+// wrapText wraps text at word boundaries so that no line is wider than width runes.
+func wrapText(text string, width int) []string {
+	if width <= 0 {
+		return []string{text}
+	}
+
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+
+		line := ""
+		for _, word := range words {
+			for len([]rune(word)) > width {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				runes := []rune(word)
+				lines = append(lines, string(runes[:width]))
+				word = string(runes[width:])
+			}
+			switch {
+			case line == "":
+				line = word
+			case len([]rune(line))+1+len([]rune(word)) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	if width <= 1 {
+		return string(runes[:width])
+	}
+	return string(runes[:width-1]) + "…"
+}
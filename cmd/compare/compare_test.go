@@ -0,0 +1,221 @@
+package compare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/github/gh-models/internal/azuremodels"
+	"github.com/github/gh-models/internal/sse"
+	"github.com/github/gh-models/pkg/command"
+	"github.com/github/gh-models/pkg/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(replies map[string]string) (*azuremodels.MockClient, *sync.Map) {
+	client := azuremodels.NewMockClient()
+	client.MockListModels = func(ctx context.Context) ([]*azuremodels.ModelSummary, error) {
+		return []*azuremodels.ModelSummary{
+			{ID: "openai/model-a", Name: "model-a", Publisher: "openai", Task: "chat-completion"},
+			{ID: "openai/model-b", Name: "model-b", Publisher: "openai", Task: "chat-completion"},
+		}, nil
+	}
+
+	requests := &sync.Map{}
+	client.MockGetChatCompletionStream = func(ctx context.Context, req azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+		requests.Store(req.Model, req)
+		reply := replies[req.Model]
+		return &azuremodels.ChatCompletionResponse{
+			Reader: sse.NewMockEventReader([]azuremodels.ChatCompletion{
+				{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: util.Ptr(reply)}, FinishReason: "stop"}}},
+				{Usage: &azuremodels.ChatCompletionUsage{PromptTokens: 3, CompletionTokens: len(reply), TotalTokens: 3 + len(reply)}},
+			}),
+		}, nil
+	}
+	return client, requests
+}
+
+func TestCompare(t *testing.T) {
+	replies := map[string]string{
+		"openai/model-a": "answer from a",
+		"openai/model-b": "answer from b",
+	}
+
+	t.Run("requires at least two models", func(t *testing.T) {
+		client, _ := newTestClient(replies)
+		out := new(bytes.Buffer)
+		cmd := NewCompareCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--model", "openai/model-a", "hello"})
+
+		err := cmd.Execute()
+		require.ErrorContains(t, err, "at least two models")
+	})
+
+	t.Run("json output includes every model in order", func(t *testing.T) {
+		client, requests := newTestClient(replies)
+		out := new(bytes.Buffer)
+		cmd := NewCompareCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--model", "openai/model-b", "--model", "openai/model-a", "--json", "hello"})
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+
+		var result ComparisonResult
+		require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+		require.Len(t, result.Results, 2)
+		require.Equal(t, "openai/model-b", result.Results[0].Model)
+		require.Equal(t, "answer from b", result.Results[0].Content)
+		require.Equal(t, "openai/model-a", result.Results[1].Model)
+		require.Equal(t, "answer from a", result.Results[1].Content)
+		require.Equal(t, 16, result.Results[1].Usage.TotalTokens)
+		require.Equal(t, "stop", result.Results[1].FinishReason)
+
+		value, ok := requests.Load("openai/model-a")
+		require.True(t, ok)
+		req := value.(azuremodels.ChatCompletionOptions)
+		require.Len(t, req.Messages, 1)
+		require.Equal(t, "hello", *req.Messages[0].Content)
+	})
+
+	t.Run("columns layout renders responses side by side", func(t *testing.T) {
+		client, _ := newTestClient(replies)
+		out := new(bytes.Buffer)
+		cmd := NewCompareCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--model", "openai/model-a", "--model", "openai/model-b", "--layout", "columns", "hello"})
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+
+		output := out.String()
+		require.Regexp(t, `openai/model-a\s+openai/model-b`, output)
+		require.Regexp(t, `answer from a\s+answer from b`, output)
+		require.Contains(t, output, "LATENCY")
+		require.Contains(t, output, "TOTAL TOKENS")
+	})
+
+	t.Run("columns layout falls back to panes without room for columns", func(t *testing.T) {
+		client, _ := newTestClient(replies)
+		out := new(bytes.Buffer)
+		// Off a terminal, the width is unknown
+		cmd := NewCompareCommand(command.NewConfig(out, out, client, false, -1))
+		cmd.SetArgs([]string{"--model", "openai/model-a", "--model", "openai/model-b", "--layout", "columns", "hello"})
+
+		require.NoError(t, cmd.Execute())
+		require.Contains(t, out.String(), "answer from a\n\n")
+		require.Contains(t, out.String(), "answer from b\n\n")
+	})
+
+	t.Run("fails when a model fails to respond", func(t *testing.T) {
+		client, _ := newTestClient(replies)
+		client.MockGetChatCompletionStream = func(ctx context.Context, req azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+			if req.Model == "openai/model-b" {
+				return nil, errors.New("unavailable")
+			}
+			return &azuremodels.ChatCompletionResponse{
+				Reader: sse.NewMockEventReader([]azuremodels.ChatCompletion{
+					{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: util.Ptr("answer from a")}, FinishReason: "stop"}}},
+				}),
+			}, nil
+		}
+		out := new(bytes.Buffer)
+		cmd := NewCompareCommand(command.NewConfig(out, out, client, false, 40))
+		cmd.SetArgs([]string{"--json", "--model", "openai/model-a", "--model", "openai/model-b", "hello"})
+
+		require.EqualError(t, cmd.Execute(), "1 of 2 models failed to respond")
+
+		var result ComparisonResult
+		require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+		require.Equal(t, "answer from a", result.Results[0].Content)
+		require.Equal(t, "unavailable", result.Results[1].Error)
+	})
+
+	t.Run("panes layout renders responses one after another", func(t *testing.T) {
+		client, _ := newTestClient(replies)
+		out := new(bytes.Buffer)
+		cmd := NewCompareCommand(command.NewConfig(out, out, client, false, 40))
+		cmd.SetArgs([]string{"--model", "openai/model-a", "--model", "openai/model-b", "hello"})
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+
+		output := out.String()
+		indexA := strings.Index(output, "answer from a")
+		indexB := strings.Index(output, "answer from b")
+		require.NotEqual(t, -1, indexA)
+		require.Greater(t, indexB, indexA)
+	})
+
+	t.Run("templates prompt files", func(t *testing.T) {
+		const yamlBody = `
+name: Greeting
+model: openai/model-a
+modelParameters:
+  temperature: 0.3
+messages:
+  - role: system
+    content: You are friendly.
+  - role: user
+    content: "Greet {{name}}"
+`
+		promptFile := filepath.Join(t.TempDir(), "test.prompt.yml")
+		require.NoError(t, os.WriteFile(promptFile, []byte(yamlBody), 0644))
+
+		client, requests := newTestClient(replies)
+		out := new(bytes.Buffer)
+		cmd := NewCompareCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--model", "openai/model-a", "--model", "openai/model-b", "--file", promptFile, "--var", "name=Alice", "--json"})
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+
+		value, ok := requests.Load("openai/model-b")
+		require.True(t, ok)
+		req := value.(azuremodels.ChatCompletionOptions)
+		require.Len(t, req.Messages, 2)
+		require.Equal(t, "You are friendly.", *req.Messages[0].Content)
+		require.Equal(t, "Greet Alice", *req.Messages[1].Content)
+		require.Equal(t, 0.3, *req.Temperature)
+	})
+
+	t.Run("applies the variables of prompt files", func(t *testing.T) {
+		const yamlBody = `
+name: Greeting
+variables:
+  - name: greeting
+    default: Hello
+  - name: name
+    required: true
+messages:
+  - role: user
+    content: "{{greeting}}, {{name}}"
+`
+		promptFile := filepath.Join(t.TempDir(), "test.prompt.yml")
+		require.NoError(t, os.WriteFile(promptFile, []byte(yamlBody), 0644))
+
+		client, requests := newTestClient(replies)
+		out := new(bytes.Buffer)
+		cmd := NewCompareCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--model", "openai/model-a", "--model", "openai/model-b", "--file", promptFile, "--var", "name=Alice", "--json"})
+		require.NoError(t, cmd.Execute())
+
+		value, ok := requests.Load("openai/model-a")
+		require.True(t, ok)
+		require.Equal(t, "Hello, Alice", *value.(azuremodels.ChatCompletionOptions).Messages[0].Content)
+
+		cmd = NewCompareCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--model", "openai/model-a", "--model", "openai/model-b", "--file", promptFile, "--json"})
+		require.ErrorContains(t, cmd.Execute(), "missing required variable 'name'")
+	})
+}
+
+func TestWrapText(t *testing.T) {
+	require.Equal(t, []string{"the quick", "brown fox"}, wrapText("the quick brown fox", 10))
+	require.Equal(t, []string{"abcde", "fgh"}, wrapText("abcdefgh", 5))
+	require.Equal(t, []string{"one", "", "two"}, wrapText("one\n\ntwo", 10))
+}
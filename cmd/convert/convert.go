@@ -0,0 +1,279 @@
+// Package convert provides a `gh models convert` command to convert prompt files to and from other formats.
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/github/gh-models/pkg/command"
+	"github.com/github/gh-models/pkg/prompt"
+	"github.com/github/gh-models/pkg/util"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// format reads and writes prompts in the format of another tool, by way of a prompt file
+type format struct {
+	name  string
+	read  func(c *conversion, filePath string) (*prompt.File, error)
+	write func(c *conversion, pf *prompt.File) ([]byte, error)
+}
+
+var formats = []format{
+	{name: "prompt.yml", read: readPromptFile, write: writePromptFile},
+	{name: "prompty", read: readPrompty, write: writePrompty},
+	{name: "promptfoo", read: readPromptfoo, write: writePromptfoo},
+	{name: "openai-evals", read: readOpenAIEvals, write: writeOpenAIEvals},
+}
+
+// conversion collects the warnings about what could not be converted
+type conversion struct {
+	warnings []string
+}
+
+// warnf adds a warning, unless the same warning was already added
+func (c *conversion) warnf(format string, args ...interface{}) {
+	warning := fmt.Sprintf(format, args...)
+	if !slices.Contains(c.warnings, warning) {
+		c.warnings = append(c.warnings, warning)
+	}
+}
+
+// NewConvertCommand returns a new command to convert prompt files to and from other formats
+func NewConvertCommand(cfg *command.Config) *cobra.Command {
+	var names []string
+	for _, f := range formats {
+		names = append(names, f.name)
+	}
+
+	cmd := &cobra.Command{
+		Use:   "convert <file>",
+		Short: "Convert prompts to and from other formats",
+		Long: heredoc.Docf(`
+			Converts prompts between .prompt.yml files and the formats of other tools:
+
+			- %[1]sprompty%[1]s: a Prompty file, with the model and inputs in its front matter and the
+			  messages in its body.
+			- %[1]spromptfoo%[1]s: a promptfoo configuration, with prompts, providers and tests.
+			- %[1]sopenai-evals%[1]s: an OpenAI Evals samples file, in JSONL with an %[1]sinput%[1]s and an
+			  %[1]sideal%[1]s answer for each sample.
+
+			Messages, model parameters, variables and test data are converted, along with the
+			evaluators that map onto prompt file evaluators. Anything that has no equivalent in the
+			target format is dropped with a warning.
+
+			The result is printed, or written to the file given with %[1]s--output%[1]s.
+		`, "`"),
+		Example: heredoc.Doc(`
+			gh models convert --from prompty --to prompt.yml chat.prompty
+			gh models convert --from prompt.yml --to promptfoo my_prompt.prompt.yml -o promptfooconfig.yaml
+		`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, err := lookupFormat(cmd, "from", names)
+			if err != nil {
+				return err
+			}
+			to, err := lookupFormat(cmd, "to", names)
+			if err != nil {
+				return err
+			}
+			outputPath, err := cmd.Flags().GetString("output")
+			if err != nil {
+				return err
+			}
+
+			c := &conversion{}
+			pf, err := from.read(c, args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read %s file: %w", from.name, err)
+			}
+			data, err := to.write(c, pf)
+			if err != nil {
+				return fmt.Errorf("failed to convert to %s: %w", to.name, err)
+			}
+
+			for _, warning := range c.warnings {
+				util.WriteToOut(cfg.ErrOut, "warning: "+warning+"\n")
+			}
+
+			if outputPath == "" {
+				cfg.WriteToOut(string(data))
+				return nil
+			}
+			if err := os.WriteFile(outputPath, data, 0644); err != nil {
+				return fmt.Errorf("failed to write output file: %w", err)
+			}
+			util.WriteToOut(cfg.ErrOut, fmt.Sprintf("Wrote %s\n", outputPath))
+			return nil
+		},
+	}
+
+	cmd.Flags().String("from", "", fmt.Sprintf("The format to convert from: %s", strings.Join(names, ", ")))
+	cmd.Flags().String("to", "", fmt.Sprintf("The format to convert to: %s", strings.Join(names, ", ")))
+	cmd.Flags().StringP("output", "o", "", "Write the result to this file instead of printing it")
+	return cmd
+}
+
+func lookupFormat(cmd *cobra.Command, flag string, names []string) (format, error) {
+	name, err := cmd.Flags().GetString(flag)
+	if err != nil {
+		return format{}, err
+	}
+	if name == "" {
+		return format{}, fmt.Errorf("--%s is required", flag)
+	}
+	i := slices.Index(names, name)
+	if i < 0 {
+		return format{}, fmt.Errorf("invalid format '%s': must be one of %s", name, strings.Join(names, ", "))
+	}
+	return formats[i], nil
+}
+
+func readPromptFile(c *conversion, filePath string) (*prompt.File, error) {
+	return prompt.LoadFromFile(filePath)
+}
+
+func writePromptFile(c *conversion, pf *prompt.File) ([]byte, error) {
+	return encodeYAML(pf)
+}
+
+func encodeYAML(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// chatMessage is a message in the JSON format of the OpenAI chat API
+type chatMessage struct {
+	Role    string `json:"role" yaml:"role"`
+	Content string `json:"content" yaml:"content"`
+}
+
+func toChatMessages(messages []prompt.Message) []chatMessage {
+	result := make([]chatMessage, 0, len(messages))
+	for _, m := range messages {
+		result = append(result, chatMessage{Role: m.Role, Content: m.Content})
+	}
+	return result
+}
+
+// openAIParameters are the model parameters in the format of the OpenAI API, which both Prompty
+// and promptfoo use
+type openAIParameters struct {
+	MaxTokens      *int                   `yaml:"max_tokens,omitempty"`
+	Temperature    *float64               `yaml:"temperature,omitempty"`
+	TopP           *float64               `yaml:"top_p,omitempty"`
+	ResponseFormat map[string]interface{} `yaml:"response_format,omitempty"`
+}
+
+// toOpenAIParameters returns the model parameters and response format of a prompt file, or nil if it has none
+func toOpenAIParameters(pf *prompt.File) *openAIParameters {
+	params := openAIParameters{
+		MaxTokens:   pf.ModelParameters.MaxTokens,
+		Temperature: pf.ModelParameters.Temperature,
+		TopP:        pf.ModelParameters.TopP,
+	}
+	if pf.ResponseFormat != nil {
+		params.ResponseFormat = map[string]interface{}{"type": *pf.ResponseFormat}
+		if *pf.ResponseFormat == "json_schema" && pf.JsonSchema != nil {
+			params.ResponseFormat["json_schema"] = pf.JsonSchema.Parsed
+		}
+	}
+	if params.MaxTokens == nil && params.Temperature == nil && params.TopP == nil && params.ResponseFormat == nil {
+		return nil
+	}
+	return &params
+}
+
+// readOpenAIParameters sets the model parameters and response format of a prompt file from parameters
+// in the format of the OpenAI API. where is the location of the parameters, for warnings.
+func readOpenAIParameters(c *conversion, pf *prompt.File, params map[string]interface{}, where string) {
+	for _, key := range sortedKeys(params) {
+		value := params[key]
+		switch key {
+		case "max_tokens":
+			if n, ok := value.(int); ok {
+				pf.ModelParameters.MaxTokens = util.Ptr(n)
+				continue
+			}
+		case "temperature", "top_p":
+			var n float64
+			switch v := value.(type) {
+			case int:
+				n = float64(v)
+			case float64:
+				n = v
+			default:
+				c.warnf("%s.%s is dropped, since it is not a number", where, key)
+				continue
+			}
+			if key == "temperature" {
+				pf.ModelParameters.Temperature = util.Ptr(n)
+			} else {
+				pf.ModelParameters.TopP = util.Ptr(n)
+			}
+			continue
+		case "response_format":
+			if readResponseFormat(pf, value) {
+				continue
+			}
+		}
+		c.warnf("%s.%s is dropped", where, key)
+	}
+}
+
+func readResponseFormat(pf *prompt.File, value interface{}) bool {
+	format, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	switch format["type"] {
+	case "text", "json_object":
+		pf.ResponseFormat = util.Ptr(format["type"].(string))
+		return true
+	case "json_schema":
+		schema, ok := format["json_schema"].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		raw, err := json.Marshal(schema)
+		if err != nil {
+			return false
+		}
+		pf.ResponseFormat = util.Ptr("json_schema")
+		pf.JsonSchema = &prompt.JsonSchema{Raw: string(raw), Parsed: schema}
+		return true
+	}
+	return false
+}
+
+// checkTemplates warns about messages whose templates use syntax that prompt files do not support
+func checkTemplates(c *conversion, messages []prompt.Message, syntax string) {
+	for i, m := range messages {
+		if _, err := prompt.TemplateVariables(m.Content); err != nil || strings.Contains(m.Content, "{%") {
+			c.warnf("messages[%d] uses %s syntax that prompt files do not support; review it by hand", i, syntax)
+		}
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,343 @@
+package convert
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/github/gh-models/internal/azuremodels"
+	"github.com/github/gh-models/pkg/command"
+	"github.com/stretchr/testify/require"
+)
+
+func runConvert(t *testing.T, args ...string) (string, string, error) {
+	t.Helper()
+	out := new(bytes.Buffer)
+	errOut := new(bytes.Buffer)
+	cmd := NewConvertCommand(command.NewConfig(out, errOut, azuremodels.NewMockClient(), true, 100))
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), errOut.String(), err
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+const promptFile = `name: Greeter
+description: Greets people
+model: openai/gpt-4o
+modelParameters:
+  maxTokens: 100
+  temperature: 0.5
+variables:
+  - name: name
+    type: string
+    description: Who to greet
+messages:
+  - role: system
+    content: You greet people.
+  - role: user
+    content: Say hello to {{name}}.
+testData:
+  - name: Mona
+    expected: Hello Mona
+  - name: Hubot
+    expected: Hello Hubot
+evaluators:
+  - name: greets
+    string:
+      contains: "{{expected}}"
+  - name: punctuated
+    string:
+      endsWith: "!"
+  - name: judge
+    llm:
+      modelId: openai/gpt-4o
+      prompt: "{{completion}}"
+      choices:
+        - choice: good
+          score: 1
+`
+
+func TestConvertFromPrompty(t *testing.T) {
+	path := writeFile(t, "chat.prompty", `---
+name: Basic Chat
+authors:
+  - Mona
+model:
+  api: chat
+  configuration:
+    type: azure_openai
+    azure_deployment: gpt-4o
+  parameters:
+    max_tokens: 128
+    stop: ["\n"]
+    response_format:
+      type: json_object
+inputs:
+  firstName:
+    type: string
+    description: The first name
+    sample: Jane
+  question: What is JSON?
+---
+system:
+You help {{firstName}} with JSON.
+
+user:
+{{question}}
+`)
+
+	out, errOut, err := runConvert(t, "--from", "prompty", "--to", "prompt.yml", path)
+	require.NoError(t, err)
+	require.Equal(t, "warning: authors is dropped\nwarning: model.parameters.stop is dropped\n", errOut)
+	require.Equal(t, `name: Basic Chat
+description: ""
+model: gpt-4o
+modelParameters:
+  maxTokens: 128
+responseFormat: json_object
+variables:
+  - name: firstName
+    type: string
+    description: The first name
+  - name: question
+messages:
+  - role: system
+    content: You help {{firstName}} with JSON.
+  - role: user
+    content: '{{question}}'
+testData:
+  - firstName: Jane
+    question: What is JSON?
+`, out)
+}
+
+func TestConvertToPrompty(t *testing.T) {
+	path := writeFile(t, "greeter.prompt.yml", promptFile)
+
+	out, errOut, err := runConvert(t, "--from", "prompt.yml", "--to", "prompty", path)
+	require.NoError(t, err)
+	require.Equal(t, `warning: the testData rows after the first are dropped, since a Prompty file has a single sample
+warning: evaluator 'greets' is dropped, since Prompty files have no evaluators
+warning: evaluator 'punctuated' is dropped, since Prompty files have no evaluators
+warning: evaluator 'judge' is dropped, since Prompty files have no evaluators
+`, errOut)
+	require.Equal(t, `---
+name: Greeter
+description: Greets people
+model:
+  api: chat
+  configuration:
+    name: openai/gpt-4o
+    type: openai
+  parameters:
+    max_tokens: 100
+    temperature: 0.5
+inputs:
+  name:
+    type: string
+    description: Who to greet
+sample:
+  expected: Hello Mona
+  name: Mona
+---
+system:
+You greet people.
+
+user:
+Say hello to {{name}}.
+`, out)
+}
+
+func TestConvertFromPromptfoo(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "chat.json"), []byte(`[
+  {"role": "system", "content": "Translate to {{language}}."},
+  {"role": "user", "content": "{{input}}"}
+]`), 0644))
+	path := filepath.Join(dir, "promptfooconfig.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`description: Translator
+prompts:
+  - file://chat.json
+  - "Other prompt"
+providers:
+  - id: openai:chat:gpt-4o-mini
+    config:
+      temperature: 0
+defaultTest:
+  vars:
+    language: French
+  assert:
+    - type: llm-rubric
+      value: is a translation
+    - type: is-json
+tests:
+  - vars:
+      input: Hello
+    assert:
+      - type: contains
+        value: Bonjour
+  - vars:
+      input: Goodbye
+      language: German
+    assert:
+      - type: contains
+        value: Wiedersehen
+        weight: 2
+`), 0644))
+
+	out, errOut, err := runConvert(t, "--from", "promptfoo", "--to", "prompt.yml", path)
+	require.NoError(t, err)
+	require.Equal(t, `warning: the prompts after the first are dropped, since a prompt file has a single prompt
+warning: the weight option of assertions is dropped
+`, errOut)
+	require.Equal(t, `name: Translator
+description: ""
+model: openai/gpt-4o-mini
+modelParameters:
+  temperature: 0
+messages:
+  - role: system
+    content: Translate to {{language}}.
+  - role: user
+    content: '{{input}}'
+testData:
+  - expected: Bonjour
+    input: Hello
+    language: French
+  - expected: Wiedersehen
+    input: Goodbye
+    language: German
+evaluators:
+  - name: llm-rubric
+    llm:
+      modelId: openai/gpt-4o
+      prompt: |-
+        Does the response meet the rubric? Reply with "yes" or "no".
+
+        Rubric: is a translation
+
+        Response: {{completion}}
+      choices:
+        - choice: "yes"
+          score: 1
+        - choice: "no"
+          score: 0
+  - name: is-json
+    json: {}
+  - name: contains
+    string:
+      contains: '{{expected}}'
+`, out)
+}
+
+func TestConvertToPromptfoo(t *testing.T) {
+	path := writeFile(t, "greeter.prompt.yml", promptFile)
+
+	out, errOut, err := runConvert(t, "--from", "prompt.yml", "--to", "promptfoo", path)
+	require.NoError(t, err)
+	require.Equal(t, `warning: description is dropped, since the name is used as the promptfoo description
+warning: evaluator 'judge' is dropped, since LLM evaluators with scored choices have no promptfoo equivalent
+`, errOut)
+	require.Equal(t, `description: Greeter
+prompts:
+  - |-
+    [
+      {
+        "role": "system",
+        "content": "You greet people."
+      },
+      {
+        "role": "user",
+        "content": "Say hello to {{name}}."
+      }
+    ]
+providers:
+  - id: github:openai/gpt-4o
+    config:
+      max_tokens: 100
+      temperature: 0.5
+defaultTest:
+  assert:
+    - type: contains
+      value: '{{expected}}'
+    - type: regex
+      value: '!$'
+tests:
+  - vars:
+      expected: Hello Mona
+      name: Mona
+  - vars:
+      expected: Hello Hubot
+      name: Hubot
+`, out)
+}
+
+func TestConvertFromOpenAIEvals(t *testing.T) {
+	path := writeFile(t, "arithmetic.jsonl", `{"input": [{"role": "system", "content": "Answer briefly."}, {"role": "user", "content": "2+2?"}], "ideal": "4"}
+{"input": [{"role": "system", "content": "Answer briefly."}, {"role": "user", "content": "3+3?"}], "ideal": ["6", "six"]}
+`)
+
+	out, errOut, err := runConvert(t, "--from", "openai-evals", "--to", "prompt.yml", path)
+	require.NoError(t, err)
+	require.Equal(t, "warning: only the first of several ideal answers is kept\n", errOut)
+	require.Equal(t, `name: arithmetic
+description: ""
+model: ""
+messages:
+  - role: system
+    content: Answer briefly.
+  - role: user
+    content: '{{input}}'
+testData:
+  - expected: "4"
+    input: 2+2?
+  - expected: "6"
+    input: 3+3?
+evaluators:
+  - name: match
+    string:
+      startsWith: '{{expected}}'
+`, out)
+
+	t.Run("requires the same roles in every sample", func(t *testing.T) {
+		path := writeFile(t, "mixed.jsonl", `{"input": "2+2?"}
+{"input": [{"role": "system", "content": "Answer briefly."}, {"role": "user", "content": "3+3?"}]}
+`)
+		_, _, err := runConvert(t, "--from", "openai-evals", "--to", "prompt.yml", path)
+		require.EqualError(t, err, "failed to read openai-evals file: every sample must have messages with the same roles to be converted to a single prompt")
+	})
+}
+
+func TestConvertToOpenAIEvals(t *testing.T) {
+	path := writeFile(t, "greeter.prompt.yml", promptFile)
+	output := filepath.Join(t.TempDir(), "samples.jsonl")
+
+	out, errOut, err := runConvert(t, "--from", "prompt.yml", "--to", "openai-evals", path, "-o", output)
+	require.NoError(t, err)
+	require.Empty(t, out)
+	require.Contains(t, errOut, "warning: the model and its parameters are dropped, since samples files do not set them\n")
+	require.Contains(t, errOut, "Wrote "+output+"\n")
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, `{"input":[{"role":"system","content":"You greet people."},{"role":"user","content":"Say hello to Mona."}],"ideal":"Hello Mona"}
+{"input":[{"role":"system","content":"You greet people."},{"role":"user","content":"Say hello to Hubot."}],"ideal":"Hello Hubot"}
+`, string(data))
+}
+
+func TestConvertFormats(t *testing.T) {
+	path := writeFile(t, "greeter.prompt.yml", promptFile)
+
+	_, _, err := runConvert(t, "--to", "prompty", path)
+	require.EqualError(t, err, "--from is required")
+
+	_, _, err = runConvert(t, "--from", "prompt.yml", "--to", "langchain", path)
+	require.EqualError(t, err, "invalid format 'langchain': must be one of prompt.yml, prompty, promptfoo, openai-evals")
+}
@@ -0,0 +1,194 @@
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/github/gh-models/pkg/prompt"
+)
+
+// openAIEvalsSample is a line of an OpenAI Evals samples file
+type openAIEvalsSample struct {
+	Input []chatMessage `json:"input"`
+	Ideal interface{}   `json:"ideal,omitempty"`
+}
+
+// readOpenAIEvals reads a samples file. Messages that are the same in every sample are kept as they
+// are, and the others become template variables with a testData column for their content. The ideal
+// answers become the expected column, which a startsWith evaluator compares to, like the Match class
+// of OpenAI Evals.
+func readOpenAIEvals(c *conversion, filePath string) (*prompt.File, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []openAIEvalsSample
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(text), &raw); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		for key := range raw {
+			if key != "input" && key != "ideal" {
+				c.warnf("the %s field of samples is dropped", key)
+			}
+		}
+
+		var sample openAIEvalsSample
+		var input string
+		if err := json.Unmarshal(raw["input"], &input); err == nil {
+			// Completion samples have a string input
+			sample.Input = []chatMessage{{Role: "user", Content: input}}
+		} else if err := json.Unmarshal(raw["input"], &sample.Input); err != nil {
+			return nil, fmt.Errorf("line %d: input must be a string or a list of messages", line)
+		}
+		if ideal, ok := raw["ideal"]; ok {
+			if err := json.Unmarshal(ideal, &sample.Ideal); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, errors.New("the file has no samples")
+	}
+
+	for _, sample := range samples[1:] {
+		if !sameRoles(sample.Input, samples[0].Input) {
+			return nil, errors.New("every sample must have messages with the same roles to be converted to a single prompt")
+		}
+	}
+
+	// Find the messages that differ between samples, which become variables
+	var varying []int
+	for i, m := range samples[0].Input {
+		for _, sample := range samples[1:] {
+			if sample.Input[i].Content != m.Content {
+				varying = append(varying, i)
+				break
+			}
+		}
+	}
+	if len(samples) == 1 && len(samples[0].Input) > 0 {
+		varying = []int{len(samples[0].Input) - 1}
+	}
+	variables := make(map[int]string)
+	for n, i := range varying {
+		variables[i] = "input"
+		if len(varying) > 1 {
+			variables[i] = fmt.Sprintf("input_%d", n+1)
+		}
+	}
+
+	pf := &prompt.File{Name: strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))}
+	for i, m := range samples[0].Input {
+		content := m.Content
+		if name, ok := variables[i]; ok {
+			content = "{{" + name + "}}"
+		}
+		pf.Messages = append(pf.Messages, prompt.Message{Role: m.Role, Content: content})
+	}
+
+	hasIdeal := false
+	for _, sample := range samples {
+		row := prompt.TestDataItem{}
+		for i, name := range variables {
+			row[name] = sample.Input[i].Content
+		}
+		switch ideal := sample.Ideal.(type) {
+		case nil:
+		case []interface{}:
+			if len(ideal) > 1 {
+				c.warnf("only the first of several ideal answers is kept")
+			}
+			if len(ideal) > 0 {
+				row["expected"] = fmt.Sprint(ideal[0])
+				hasIdeal = true
+			}
+		default:
+			row["expected"] = fmt.Sprint(ideal)
+			hasIdeal = true
+		}
+		pf.TestData = append(pf.TestData, row)
+	}
+
+	if hasIdeal {
+		pf.Evaluators = []prompt.Evaluator{{Name: "match", String: &prompt.StringEvaluator{StartsWith: "{{expected}}"}}}
+	}
+	return pf, nil
+}
+
+func sameRoles(a, b []chatMessage) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Role != b[i].Role {
+			return false
+		}
+	}
+	return true
+}
+
+// writeOpenAIEvals writes a sample for each testData row, with the messages rendered for the row and
+// its expected value as the ideal answer
+func writeOpenAIEvals(c *conversion, pf *prompt.File) ([]byte, error) {
+	if pf.Model != "" || pf.ModelParameters != (prompt.ModelParameters{}) {
+		c.warnf("the model and its parameters are dropped, since samples files do not set them")
+	}
+	if pf.ResponseFormat != nil {
+		c.warnf("responseFormat is dropped, since samples files do not set it")
+	}
+	for _, evaluator := range pf.Evaluators {
+		c.warnf("evaluator '%s' is dropped; choose an eval class, such as evals.elsuite.basic.match:Match, when registering the eval", evaluator.Name)
+	}
+
+	rows := pf.TestData
+	if len(rows) == 0 {
+		rows = []prompt.TestDataItem{{}}
+	}
+
+	var buf bytes.Buffer
+	for i, row := range rows {
+		values, err := pf.ResolveVariables(row)
+		if err != nil {
+			return nil, fmt.Errorf("testData row %d: %w", i+1, err)
+		}
+
+		var sample openAIEvalsSample
+		for _, m := range pf.Messages {
+			content, err := prompt.TemplateString(m.Content, values, prompt.WithStrictVariables())
+			if err != nil {
+				return nil, fmt.Errorf("testData row %d: %w", i+1, err)
+			}
+			sample.Input = append(sample.Input, chatMessage{Role: m.Role, Content: content})
+		}
+		if expected, ok := row["expected"]; ok {
+			sample.Ideal = expected
+		}
+
+		line, err := json.Marshal(sample)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteString("\n")
+	}
+	return buf.Bytes(), nil
+}
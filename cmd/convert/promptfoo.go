@@ -0,0 +1,477 @@
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/github/gh-models/pkg/prompt"
+	"gopkg.in/yaml.v3"
+)
+
+// rubricPrompt is the prompt of the LLM evaluator that an llm-rubric assertion converts to
+const rubricPrompt = `Does the response meet the rubric? Reply with "yes" or "no".
+
+Rubric: %s
+
+Response: {{completion}}`
+
+// rubricModel is the model that judges llm-rubric assertions
+const rubricModel = "openai/gpt-4o"
+
+// promptfooConfig is a promptfoo configuration file
+type promptfooConfig struct {
+	Description string              `yaml:"description,omitempty"`
+	Prompts     []string            `yaml:"prompts"`
+	Providers   []promptfooProvider `yaml:"providers"`
+	DefaultTest *promptfooTest      `yaml:"defaultTest,omitempty"`
+	Tests       []promptfooTest     `yaml:"tests,omitempty"`
+}
+
+type promptfooProvider struct {
+	ID     string            `yaml:"id"`
+	Config *openAIParameters `yaml:"config,omitempty"`
+}
+
+type promptfooTest struct {
+	Vars   map[string]interface{} `yaml:"vars,omitempty"`
+	Assert []promptfooAssertion   `yaml:"assert,omitempty"`
+}
+
+type promptfooAssertion struct {
+	Type  string `yaml:"type"`
+	Value string `yaml:"value,omitempty"`
+}
+
+func readPromptfoo(c *conversion, filePath string) (*prompt.File, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("the configuration must be a mapping")
+	}
+
+	pf := &prompt.File{}
+	var defaultTest, tests *yaml.Node
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i].Value, root.Content[i+1]
+		var err error
+		switch key {
+		case "description":
+			err = value.Decode(&pf.Name)
+		case "prompts":
+			err = readPromptfooPrompts(c, pf, value, filepath.Dir(filePath))
+		case "providers":
+			err = readPromptfooProviders(c, pf, value)
+		case "defaultTest":
+			defaultTest = value
+		case "tests":
+			tests = value
+		default:
+			c.warnf("%s is dropped", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+
+	if err := readPromptfooTests(c, pf, defaultTest, tests); err != nil {
+		return nil, err
+	}
+	return pf, nil
+}
+
+// listItems returns the items of a sequence, or the node itself when a single item is written without a sequence
+func listItems(node *yaml.Node) []*yaml.Node {
+	if node.Kind == yaml.SequenceNode {
+		return node.Content
+	}
+	return []*yaml.Node{node}
+}
+
+func readPromptfooPrompts(c *conversion, pf *prompt.File, node *yaml.Node, dir string) error {
+	items := listItems(node)
+	if len(items) == 0 {
+		return nil
+	}
+	if len(items) > 1 {
+		c.warnf("the prompts after the first are dropped, since a prompt file has a single prompt")
+	}
+
+	var raw string
+	switch items[0].Kind {
+	case yaml.ScalarNode:
+		raw = items[0].Value
+	case yaml.MappingNode:
+		var p struct {
+			ID  string `yaml:"id"`
+			Raw string `yaml:"raw"`
+		}
+		if err := items[0].Decode(&p); err != nil {
+			return err
+		}
+		raw = p.Raw
+		if raw == "" {
+			raw = p.ID
+		}
+	default:
+		return errors.New("a prompt must be a string or a mapping")
+	}
+
+	ext := ""
+	if path, ok := strings.CutPrefix(raw, "file://"); ok {
+		data, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			return fmt.Errorf("failed to read prompt: %w", err)
+		}
+		raw = string(data)
+		ext = strings.ToLower(filepath.Ext(path))
+	}
+
+	// Chat prompts are a JSON or YAML list of messages
+	var messages []chatMessage
+	if ext == ".json" || ext == ".yaml" || ext == ".yml" || strings.HasPrefix(strings.TrimSpace(raw), "[") {
+		if err := yaml.Unmarshal([]byte(raw), &messages); err != nil && ext != "" {
+			return fmt.Errorf("invalid chat prompt: %w", err)
+		}
+	}
+	if len(messages) == 0 {
+		messages = []chatMessage{{Role: "user", Content: strings.TrimSpace(raw)}}
+	}
+	for _, m := range messages {
+		pf.Messages = append(pf.Messages, prompt.Message{Role: m.Role, Content: m.Content})
+	}
+	checkTemplates(c, pf.Messages, "Nunjucks")
+	return nil
+}
+
+func readPromptfooProviders(c *conversion, pf *prompt.File, node *yaml.Node) error {
+	items := listItems(node)
+	if len(items) == 0 {
+		return nil
+	}
+	if len(items) > 1 {
+		c.warnf("the providers after the first are dropped, since a prompt file has a single model")
+	}
+
+	var provider struct {
+		ID     string                 `yaml:"id"`
+		Config map[string]interface{} `yaml:"config"`
+	}
+	if items[0].Kind == yaml.ScalarNode {
+		provider.ID = items[0].Value
+	} else if err := items[0].Decode(&provider); err != nil {
+		return err
+	}
+
+	switch {
+	case strings.HasPrefix(provider.ID, "github:"):
+		pf.Model = strings.TrimPrefix(provider.ID, "github:")
+	case strings.HasPrefix(provider.ID, "openai:"):
+		pf.Model = "openai/" + provider.ID[strings.LastIndex(provider.ID, ":")+1:]
+	default:
+		pf.Model = provider.ID
+		c.warnf("provider '%s' is not a GitHub Models provider; set the model by hand", provider.ID)
+	}
+	readOpenAIParameters(c, pf, provider.Config, "providers[0].config")
+	return nil
+}
+
+// readPromptfooTests converts the tests to testData rows and evaluators. The assertions of defaultTest
+// become evaluators as they are. The assertions of each test become an evaluator for each type of
+// assertion, which compares to a testData column that holds the values of the tests: expected when
+// the tests use a single type of assertion, and expected_<type> otherwise.
+func readPromptfooTests(c *conversion, pf *prompt.File, defaultTest, tests *yaml.Node) error {
+	var base promptfooTestNode
+	if defaultTest != nil {
+		if err := base.decode(c, defaultTest, "defaultTest"); err != nil {
+			return err
+		}
+		for _, a := range base.assert {
+			evaluator, ok := assertionEvaluator(a.Type, a.Value)
+			if !ok {
+				c.warnf("'%s' assertions are dropped, since they cannot be converted to an evaluator", a.Type)
+				continue
+			}
+			addEvaluator(pf, evaluator)
+		}
+	}
+
+	if tests == nil {
+		return nil
+	}
+	if tests.Kind == yaml.ScalarNode {
+		c.warnf("tests loaded from '%s' are dropped; convert them by hand", tests.Value)
+		return nil
+	}
+
+	var cases []promptfooTestNode
+	var types []string
+	usesExpected := false
+	for i, item := range listItems(tests) {
+		var test promptfooTestNode
+		if err := test.decode(c, item, fmt.Sprintf("tests[%d]", i)); err != nil {
+			return err
+		}
+		if _, ok := test.vars["expected"]; ok {
+			usesExpected = true
+		}
+		values := make(map[string]string)
+		for _, a := range test.assert {
+			if !slices.Contains(assertionTypes, a.Type) {
+				c.warnf("'%s' assertions are dropped, since they cannot be converted to an evaluator", a.Type)
+				continue
+			}
+			if _, ok := values[a.Type]; ok {
+				c.warnf("only the first '%s' assertion of each test is kept", a.Type)
+				continue
+			}
+			values[a.Type] = a.Value
+			if !slices.Contains(types, a.Type) {
+				types = append(types, a.Type)
+			}
+		}
+		test.values = values
+		cases = append(cases, test)
+	}
+
+	columns := make(map[string]string)
+	for _, t := range types {
+		if len(types) == 1 && !usesExpected {
+			columns[t] = "expected"
+		} else {
+			columns[t] = "expected_" + strings.ReplaceAll(t, "-", "_")
+		}
+		if t == "similar" && columns[t] != "expected" {
+			c.warnf("'similar' assertions are dropped, since they can only be converted when the tests have no other assertions")
+			delete(columns, t)
+			continue
+		}
+		evaluator, _ := assertionEvaluator(t, "{{"+columns[t]+"}}")
+		addEvaluator(pf, evaluator)
+	}
+
+	for _, test := range cases {
+		row := prompt.TestDataItem{}
+		for name, value := range base.vars {
+			row[name] = value
+		}
+		for name, value := range test.vars {
+			row[name] = value
+		}
+		for t, value := range test.values {
+			if column, ok := columns[t]; ok {
+				row[column] = value
+			}
+		}
+		pf.TestData = append(pf.TestData, row)
+	}
+	return nil
+}
+
+// promptfooTestNode is a test read from a promptfoo configuration
+type promptfooTestNode struct {
+	vars   map[string]interface{}
+	assert []promptfooAssertion
+	// values are the values of the assertions of the test, by type
+	values map[string]string
+}
+
+func (t *promptfooTestNode) decode(c *conversion, node *yaml.Node, where string) error {
+	var test map[string]interface{}
+	if err := node.Decode(&test); err != nil {
+		return fmt.Errorf("invalid %s: %w", where, err)
+	}
+
+	for _, key := range sortedKeys(test) {
+		switch key {
+		case "vars":
+			vars, ok := test[key].(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("invalid %s: vars must be a mapping", where)
+			}
+			t.vars = vars
+		case "assert":
+			assertions, ok := test[key].([]interface{})
+			if !ok {
+				return fmt.Errorf("invalid %s: assert must be a list", where)
+			}
+			for _, item := range assertions {
+				assertion, ok := item.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("invalid %s: each assertion must be a mapping", where)
+				}
+				typ, _ := assertion["type"].(string)
+				value, ok := assertion["value"].(string)
+				if !ok && assertion["value"] != nil {
+					c.warnf("'%s' assertions without a string value are dropped", typ)
+					continue
+				}
+				for _, option := range sortedKeys(assertion) {
+					if option != "type" && option != "value" {
+						c.warnf("the %s option of assertions is dropped", option)
+					}
+				}
+				t.assert = append(t.assert, promptfooAssertion{Type: typ, Value: value})
+			}
+		default:
+			c.warnf("the %s option of tests is dropped", key)
+		}
+	}
+	return nil
+}
+
+// assertionTypes are the types of promptfoo assertions that can be converted to evaluators
+var assertionTypes = []string{"equals", "contains", "not-contains", "starts-with", "regex", "similar", "llm-rubric"}
+
+// assertionEvaluator returns the evaluator that an assertion converts to, given the template of its value
+func assertionEvaluator(typ, value string) (prompt.Evaluator, bool) {
+	switch typ {
+	case "equals":
+		return prompt.Evaluator{Name: typ, String: &prompt.StringEvaluator{Equals: value}}, true
+	case "contains":
+		return prompt.Evaluator{Name: typ, String: &prompt.StringEvaluator{Contains: value}}, true
+	case "not-contains":
+		return prompt.Evaluator{Name: typ, String: &prompt.StringEvaluator{NotContains: value}}, true
+	case "starts-with":
+		return prompt.Evaluator{Name: typ, String: &prompt.StringEvaluator{StartsWith: value}}, true
+	case "regex":
+		return prompt.Evaluator{Name: typ, String: &prompt.StringEvaluator{Regex: value}}, true
+	case "is-json":
+		return prompt.Evaluator{Name: typ, JSON: &prompt.JSONEvaluator{}}, true
+	case "similar":
+		if value == "{{expected}}" {
+			return prompt.Evaluator{Name: "similarity", Uses: "github/similarity"}, true
+		}
+	case "llm-rubric":
+		return prompt.Evaluator{Name: typ, LLM: &prompt.LLMEvaluator{
+			ModelID: rubricModel,
+			Prompt:  fmt.Sprintf(rubricPrompt, value),
+			Choices: []prompt.Choice{{Choice: "yes", Score: 1}, {Choice: "no", Score: 0}},
+		}}, true
+	}
+	return prompt.Evaluator{}, false
+}
+
+// addEvaluator adds an evaluator, numbering its name if another evaluator has the same name
+func addEvaluator(pf *prompt.File, evaluator prompt.Evaluator) {
+	name := evaluator.Name
+	for n := 2; slices.ContainsFunc(pf.Evaluators, func(e prompt.Evaluator) bool { return e.Name == evaluator.Name }); n++ {
+		evaluator.Name = fmt.Sprintf("%s-%d", name, n)
+	}
+	pf.Evaluators = append(pf.Evaluators, evaluator)
+}
+
+// templateTag matches the template tags in a string
+var templateTag = regexp.MustCompile(`\{\{[^}]*\}\}`)
+
+func writePromptfoo(c *conversion, pf *prompt.File) ([]byte, error) {
+	messages, err := json.MarshalIndent(toChatMessages(pf.Messages), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	config := promptfooConfig{
+		Description: pf.Name,
+		Prompts:     []string{string(messages)},
+		Providers:   []promptfooProvider{{ID: "github:" + pf.Model, Config: toOpenAIParameters(pf)}},
+	}
+	if pf.Description != "" {
+		if config.Description == "" {
+			config.Description = pf.Description
+		} else {
+			c.warnf("description is dropped, since the name is used as the promptfoo description")
+		}
+	}
+
+	var assertions []promptfooAssertion
+	for _, evaluator := range pf.Evaluators {
+		assertions = append(assertions, evaluatorAssertions(c, evaluator)...)
+	}
+	if len(assertions) > 0 {
+		config.DefaultTest = &promptfooTest{Assert: assertions}
+	}
+
+	for _, row := range pf.TestData {
+		config.Tests = append(config.Tests, promptfooTest{Vars: row})
+	}
+	return encodeYAML(config)
+}
+
+// evaluatorAssertions returns the assertions an evaluator converts to. promptfoo renders the values
+// of assertions with the variables of the test, so templates are kept as they are.
+func evaluatorAssertions(c *conversion, evaluator prompt.Evaluator) []promptfooAssertion {
+	switch {
+	case evaluator.String != nil && evaluator.String.Mode == prompt.StringModeAny:
+		c.warnf("evaluator '%s' is dropped, since promptfoo assertions must all pass", evaluator.Name)
+	case evaluator.String != nil:
+		var assertions []promptfooAssertion
+		if evaluator.String.Equals != "" {
+			assertions = append(assertions, promptfooAssertion{Type: "equals", Value: evaluator.String.Equals})
+		}
+		if evaluator.String.Contains != "" {
+			assertions = append(assertions, promptfooAssertion{Type: "contains", Value: evaluator.String.Contains})
+		}
+		if evaluator.String.StartsWith != "" {
+			assertions = append(assertions, promptfooAssertion{Type: "starts-with", Value: evaluator.String.StartsWith})
+		}
+		if endsWith := evaluator.String.EndsWith; endsWith != "" {
+			if templateTag.MatchString(endsWith) {
+				c.warnf("the endsWith check of evaluator '%s' is dropped, since it uses template variables", evaluator.Name)
+			} else {
+				assertions = append(assertions, promptfooAssertion{Type: "regex", Value: regexp.QuoteMeta(endsWith) + "$"})
+			}
+		}
+		if evaluator.String.NotContains != "" {
+			assertions = append(assertions, promptfooAssertion{Type: "not-contains", Value: evaluator.String.NotContains})
+		}
+		// Every assertion must pass, so containsAll converts to one assertion per string
+		for _, value := range evaluator.String.ContainsAll {
+			assertions = append(assertions, promptfooAssertion{Type: "contains", Value: value})
+		}
+		if evaluator.String.Regex != "" {
+			assertions = append(assertions, promptfooAssertion{Type: "regex", Value: evaluator.String.Regex})
+		}
+		for _, dropped := range []struct {
+			set    bool
+			option string
+		}{
+			{len(evaluator.String.ContainsAny) > 0, "containsAny"},
+			{evaluator.String.MinLength != nil, "minLength"},
+			{evaluator.String.MaxLength != nil, "maxLength"},
+			{evaluator.String.MinLines != nil, "minLines"},
+			{evaluator.String.MaxLines != nil, "maxLines"},
+			{evaluator.String.CaseSensitive != nil, "caseSensitive"},
+			{evaluator.String.NormalizeWhitespace, "normalizeWhitespace"},
+		} {
+			if dropped.set {
+				c.warnf("the %s option of evaluator '%s' is dropped", dropped.option, evaluator.Name)
+			}
+		}
+		return assertions
+	case evaluator.JSON != nil:
+		if *evaluator.JSON != (prompt.JSONEvaluator{}) {
+			c.warnf("evaluator '%s' is dropped, since promptfoo has no equivalent of its JSON checks", evaluator.Name)
+			return nil
+		}
+		return []promptfooAssertion{{Type: "is-json"}}
+	case evaluator.Uses == "github/similarity":
+		return []promptfooAssertion{{Type: "similar", Value: "{{expected}}"}}
+	case evaluator.Uses != "":
+		c.warnf("evaluator '%s' is dropped, since promptfoo has no equivalent of %s", evaluator.Name, evaluator.Uses)
+	default:
+		c.warnf("evaluator '%s' is dropped, since LLM evaluators with scored choices have no promptfoo equivalent", evaluator.Name)
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/github/gh-models/pkg/prompt"
+	"gopkg.in/yaml.v3"
+)
+
+// promptyRole matches the lines that start a message in the body of a Prompty file
+var promptyRole = regexp.MustCompile(`(?im)^[ \t]*#?[ \t]*(system|user|assistant)[ \t]*:[ \t]*$`)
+
+// promptyModelKeys are the keys of model.configuration that name the model, in order of preference
+var promptyModelKeys = []string{"name", "azure_deployment", "model"}
+
+// promptyConnectionKeys are the keys of model.configuration that say where the model is hosted,
+// which gh models decides itself, so they are dropped without a warning
+var promptyConnectionKeys = []string{"type", "api_key", "api_version", "azure_endpoint", "base_url", "endpoint", "organization"}
+
+// promptyHeader is the front matter of a Prompty file
+type promptyHeader struct {
+	Name        string                 `yaml:"name,omitempty"`
+	Description string                 `yaml:"description,omitempty"`
+	Model       promptyModel           `yaml:"model"`
+	Inputs      *yaml.Node             `yaml:"inputs,omitempty"`
+	Sample      map[string]interface{} `yaml:"sample,omitempty"`
+}
+
+type promptyModel struct {
+	API           string            `yaml:"api"`
+	Configuration map[string]string `yaml:"configuration,omitempty"`
+	Parameters    *openAIParameters `yaml:"parameters,omitempty"`
+}
+
+type promptyInput struct {
+	Type        string      `yaml:"type,omitempty"`
+	Description string      `yaml:"description,omitempty"`
+	Default     interface{} `yaml:"default,omitempty"`
+	Sample      interface{} `yaml:"sample,omitempty"`
+}
+
+func readPrompty(c *conversion, filePath string) (*prompt.File, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	frontMatter, body, err := splitFrontMatter(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(frontMatter), &doc); err != nil {
+		return nil, fmt.Errorf("invalid front matter: %w", err)
+	}
+
+	pf := &prompt.File{}
+	sample := prompt.TestDataItem{}
+	if len(doc.Content) > 0 {
+		root := doc.Content[0]
+		if root.Kind != yaml.MappingNode {
+			return nil, errors.New("the front matter must be a mapping")
+		}
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			key, value := root.Content[i].Value, root.Content[i+1]
+			var err error
+			switch key {
+			case "name":
+				err = value.Decode(&pf.Name)
+			case "description":
+				err = value.Decode(&pf.Description)
+			case "model":
+				err = readPromptyModel(c, pf, value)
+			case "inputs":
+				err = readPromptyInputs(c, pf, value, sample)
+			case "sample":
+				if value.Kind != yaml.MappingNode {
+					c.warnf("sample is dropped, since only inline samples can be converted")
+					continue
+				}
+				var values map[string]interface{}
+				err = value.Decode(&values)
+				for name, v := range values {
+					sample[name] = v
+				}
+			default:
+				c.warnf("%s is dropped", key)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", key, err)
+			}
+		}
+	}
+
+	pf.Messages = readPromptyMessages(body)
+	checkTemplates(c, pf.Messages, "Jinja")
+	if len(sample) > 0 {
+		pf.TestData = []prompt.TestDataItem{sample}
+	}
+	return pf, nil
+}
+
+// splitFrontMatter returns the YAML between the --- lines at the start of a file, and the rest of the file
+func splitFrontMatter(data string) (string, string, error) {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	if !strings.HasPrefix(data, "---\n") {
+		return "", "", errors.New("missing front matter")
+	}
+	rest := data[len("---\n"):]
+	for offset := 0; ; {
+		end := strings.Index(rest[offset:], "---")
+		if end < 0 {
+			return "", "", errors.New("the front matter is not closed with ---")
+		}
+		end += offset
+		after := rest[end+len("---"):]
+		if (end == 0 || rest[end-1] == '\n') && (after == "" || after[0] == '\n') {
+			return rest[:end], strings.TrimPrefix(after, "\n"), nil
+		}
+		offset = end + len("---")
+	}
+}
+
+func readPromptyModel(c *conversion, pf *prompt.File, node *yaml.Node) error {
+	var model map[string]interface{}
+	if err := node.Decode(&model); err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(model) {
+		switch key {
+		case "api":
+			if api, _ := model[key].(string); api != "chat" {
+				c.warnf("model.api '%v' is not supported, so the body is read as chat messages", model[key])
+			}
+		case "configuration":
+			configuration, ok := model[key].(map[string]interface{})
+			if !ok {
+				return errors.New("model.configuration must be a mapping")
+			}
+			for _, modelKey := range promptyModelKeys {
+				if name, ok := configuration[modelKey].(string); ok && pf.Model == "" {
+					pf.Model = name
+				}
+			}
+			for _, configurationKey := range sortedKeys(configuration) {
+				if !slices.Contains(promptyModelKeys, configurationKey) && !slices.Contains(promptyConnectionKeys, configurationKey) {
+					c.warnf("model.configuration.%s is dropped", configurationKey)
+				}
+			}
+		case "parameters":
+			parameters, ok := model[key].(map[string]interface{})
+			if !ok {
+				return errors.New("model.parameters must be a mapping")
+			}
+			readOpenAIParameters(c, pf, parameters, "model.parameters")
+		default:
+			c.warnf("model.%s is dropped", key)
+		}
+	}
+	return nil
+}
+
+// readPromptyInputs declares a variable for each input, and adds the sample values of the inputs to sample
+func readPromptyInputs(c *conversion, pf *prompt.File, node *yaml.Node, sample prompt.TestDataItem) error {
+	if node.Kind != yaml.MappingNode {
+		return errors.New("inputs must be a mapping")
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name, value := node.Content[i].Value, node.Content[i+1]
+		variable := prompt.Variable{Name: name}
+
+		// An input that is not a mapping is a shorthand for its sample value
+		if value.Kind != yaml.MappingNode {
+			var v interface{}
+			if err := value.Decode(&v); err != nil {
+				return err
+			}
+			sample[name] = v
+			pf.Variables = append(pf.Variables, variable)
+			continue
+		}
+
+		var input promptyInput
+		if err := value.Decode(&input); err != nil {
+			return err
+		}
+		switch {
+		case input.Type == "list":
+			variable.Type = "array"
+		case slices.Contains(prompt.VariableTypes, input.Type):
+			variable.Type = input.Type
+		case input.Type != "":
+			c.warnf("the type '%s' of input '%s' is dropped", input.Type, name)
+		}
+		variable.Description = input.Description
+		variable.Default = input.Default
+		if input.Sample != nil {
+			sample[name] = input.Sample
+		}
+		pf.Variables = append(pf.Variables, variable)
+	}
+	return nil
+}
+
+// readPromptyMessages splits the body of a Prompty file into messages at its role lines. Text before
+// the first role line is a system message.
+func readPromptyMessages(body string) []prompt.Message {
+	var messages []prompt.Message
+	add := func(role, content string) {
+		if content = strings.TrimSpace(content); content != "" {
+			messages = append(messages, prompt.Message{Role: role, Content: content})
+		}
+	}
+
+	locations := promptyRole.FindAllStringSubmatchIndex(body, -1)
+	if len(locations) == 0 {
+		add("system", body)
+		return messages
+	}
+	add("system", body[:locations[0][0]])
+	for i, location := range locations {
+		end := len(body)
+		if i+1 < len(locations) {
+			end = locations[i+1][0]
+		}
+		add(strings.ToLower(body[location[2]:location[3]]), body[location[1]:end])
+	}
+	return messages
+}
+
+func writePrompty(c *conversion, pf *prompt.File) ([]byte, error) {
+	header := promptyHeader{
+		Name:        pf.Name,
+		Description: pf.Description,
+		Model:       promptyModel{API: "chat", Parameters: toOpenAIParameters(pf)},
+	}
+	if pf.Model != "" {
+		header.Model.Configuration = map[string]string{"type": "openai", "name": pf.Model}
+	}
+
+	if len(pf.Variables) > 0 {
+		header.Inputs = &yaml.Node{Kind: yaml.MappingNode}
+		for _, variable := range pf.Variables {
+			var value yaml.Node
+			if err := value.Encode(promptyInput{Type: variable.Type, Description: variable.Description, Default: variable.Default}); err != nil {
+				return nil, err
+			}
+			header.Inputs.Content = append(header.Inputs.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: variable.Name}, &value)
+		}
+	}
+
+	if len(pf.TestData) > 0 {
+		header.Sample = pf.TestData[0]
+		if len(pf.TestData) > 1 {
+			c.warnf("the testData rows after the first are dropped, since a Prompty file has a single sample")
+		}
+	}
+	for _, evaluator := range pf.Evaluators {
+		c.warnf("evaluator '%s' is dropped, since Prompty files have no evaluators", evaluator.Name)
+	}
+
+	frontMatter, err := encodeYAML(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	sb.Write(frontMatter)
+	sb.WriteString("---\n")
+	for i, m := range pf.Messages {
+		if strings.Contains(m.Content, "{{#") || strings.Contains(m.Content, "{{^") {
+			c.warnf("messages[%d] uses Mustache sections, which Jinja does not support; review it by hand", i)
+		}
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(m.Role + ":\n" + strings.TrimSpace(m.Content) + "\n")
+	}
+	return []byte(sb.String()), nil
+}
@@ -0,0 +1,314 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/github/gh-models/cmd/eval"
+	"github.com/github/gh-models/pkg/prompt"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+	changeChanged = "changed"
+)
+
+// Result is the difference between two prompt files
+type Result struct {
+	Old        string          `json:"old"`
+	New        string          `json:"new"`
+	Fields     []FieldChange   `json:"fields"`
+	Variables  []ItemChange    `json:"variables"`
+	Messages   []MessageChange `json:"messages"`
+	Evaluators []ItemChange    `json:"evaluators"`
+	TestData   TestDataChange  `json:"testData"`
+	Eval       *EvalComparison `json:"eval,omitempty"`
+}
+
+// FieldChange is a setting with a different value in the new file. Values that are not set are empty.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// ItemChange is a variable or evaluator that was added, removed or changed, matched by name
+type ItemChange struct {
+	Name   string `json:"name"`
+	Change string `json:"change"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+}
+
+// MessageChange is a message that was added, removed or changed, matched by position
+type MessageChange struct {
+	Index   int    `json:"index"`
+	Change  string `json:"change"`
+	Role    string `json:"role"`
+	OldRole string `json:"oldRole,omitempty"`
+	Old     string `json:"old,omitempty"`
+	New     string `json:"new,omitempty"`
+}
+
+// TestDataChange is the test data rows that were added and removed. The order of the rows is ignored.
+type TestDataChange struct {
+	Added     []prompt.TestDataItem `json:"added"`
+	Removed   []prompt.TestDataItem `json:"removed"`
+	Unchanged int                   `json:"unchanged"`
+}
+
+// EvalComparison is the results of evaluating both files
+type EvalComparison struct {
+	Old        eval.Summary  `json:"old"`
+	New        eval.Summary  `json:"new"`
+	Evaluators []ScoreChange `json:"evaluators"`
+}
+
+// ScoreChange is the mean score of an evaluator in each file, or nil when a file does not have the evaluator
+type ScoreChange struct {
+	Name string   `json:"name"`
+	Old  *float64 `json:"old"`
+	New  *float64 `json:"new"`
+}
+
+// Empty reports whether the files have no differences
+func (r *Result) Empty() bool {
+	return len(r.Fields) == 0 && len(r.Variables) == 0 && len(r.Messages) == 0 && len(r.Evaluators) == 0 &&
+		len(r.TestData.Added) == 0 && len(r.TestData.Removed) == 0
+}
+
+// compareFiles returns the differences between two prompt files
+func compareFiles(oldFile, newFile *prompt.File) (*Result, error) {
+	result := &Result{
+		Fields:     []FieldChange{},
+		Variables:  []ItemChange{},
+		Messages:   []MessageChange{},
+		Evaluators: []ItemChange{},
+		TestData:   TestDataChange{Added: []prompt.TestDataItem{}, Removed: []prompt.TestDataItem{}},
+	}
+
+	addField := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			result.Fields = append(result.Fields, FieldChange{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+	addField("name", oldFile.Name, newFile.Name)
+	addField("description", strings.TrimSpace(oldFile.Description), strings.TrimSpace(newFile.Description))
+	addField("model", oldFile.Model, newFile.Model)
+	addField("models", strings.Join(oldFile.Models, ", "), strings.Join(newFile.Models, ", "))
+	addField("modelParameters.maxTokens", formatPointer(oldFile.ModelParameters.MaxTokens), formatPointer(newFile.ModelParameters.MaxTokens))
+	addField("modelParameters.temperature", formatPointer(oldFile.ModelParameters.Temperature), formatPointer(newFile.ModelParameters.Temperature))
+	addField("modelParameters.topP", formatPointer(oldFile.ModelParameters.TopP), formatPointer(newFile.ModelParameters.TopP))
+	addField("responseFormat", formatPointer(oldFile.ResponseFormat), formatPointer(newFile.ResponseFormat))
+	oldSchema, err := schemaJSON(oldFile)
+	if err != nil {
+		return nil, err
+	}
+	newSchema, err := schemaJSON(newFile)
+	if err != nil {
+		return nil, err
+	}
+	addField("jsonSchema", oldSchema, newSchema)
+
+	result.Variables, err = compareNamed(oldFile.Variables, newFile.Variables, func(v prompt.Variable) string { return v.Name })
+	if err != nil {
+		return nil, err
+	}
+	result.Evaluators, err = compareNamed(oldFile.Evaluators, newFile.Evaluators, func(e prompt.Evaluator) string { return e.Name })
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < max(len(oldFile.Messages), len(newFile.Messages)); i++ {
+		switch {
+		case i >= len(oldFile.Messages):
+			m := newFile.Messages[i]
+			result.Messages = append(result.Messages, MessageChange{Index: i, Change: changeAdded, Role: m.Role, New: m.Content})
+		case i >= len(newFile.Messages):
+			m := oldFile.Messages[i]
+			result.Messages = append(result.Messages, MessageChange{Index: i, Change: changeRemoved, Role: m.Role, Old: m.Content})
+		default:
+			oldMessage, newMessage := oldFile.Messages[i], newFile.Messages[i]
+			if oldMessage.Role == newMessage.Role && slices.Equal(strings.Fields(oldMessage.Content), strings.Fields(newMessage.Content)) {
+				continue
+			}
+			change := MessageChange{Index: i, Change: changeChanged, Role: newMessage.Role, Old: oldMessage.Content, New: newMessage.Content}
+			if oldMessage.Role != newMessage.Role {
+				change.OldRole = oldMessage.Role
+			}
+			result.Messages = append(result.Messages, change)
+		}
+	}
+
+	result.TestData, err = compareTestData(oldFile.TestData, newFile.TestData)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func formatPointer[T any](value *T) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(*value)
+}
+
+func schemaJSON(pf *prompt.File) (string, error) {
+	if pf.JsonSchema == nil {
+		return "", nil
+	}
+	// Marshaling the parsed schema sorts its keys, so changes to the formatting are ignored
+	data, err := json.Marshal(pf.JsonSchema.Parsed)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal jsonSchema: %w", err)
+	}
+	return string(data), nil
+}
+
+// compareNamed matches the items of two lists by name, and returns the items that were added,
+// removed or changed, with the changed items as YAML
+func compareNamed[T any](oldItems, newItems []T, name func(T) string) ([]ItemChange, error) {
+	changes := []ItemChange{}
+	for _, oldItem := range oldItems {
+		i := slices.IndexFunc(newItems, func(item T) bool { return name(item) == name(oldItem) })
+		if i < 0 {
+			changes = append(changes, ItemChange{Name: name(oldItem), Change: changeRemoved})
+			continue
+		}
+		if reflect.DeepEqual(oldItem, newItems[i]) {
+			continue
+		}
+		oldYAML, err := formatYAML(oldItem)
+		if err != nil {
+			return nil, err
+		}
+		newYAML, err := formatYAML(newItems[i])
+		if err != nil {
+			return nil, err
+		}
+		if oldYAML != newYAML {
+			changes = append(changes, ItemChange{Name: name(oldItem), Change: changeChanged, Old: oldYAML, New: newYAML})
+		}
+	}
+	for _, newItem := range newItems {
+		if !slices.ContainsFunc(oldItems, func(item T) bool { return name(item) == name(newItem) }) {
+			changes = append(changes, ItemChange{Name: name(newItem), Change: changeAdded})
+		}
+	}
+	return changes, nil
+}
+
+func formatYAML(value interface{}) (string, error) {
+	var sb strings.Builder
+	encoder := yaml.NewEncoder(&sb)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return "", fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// compareTestData matches rows with the same values, wherever they are in the lists
+func compareTestData(oldRows, newRows []prompt.TestDataItem) (TestDataChange, error) {
+	change := TestDataChange{Added: []prompt.TestDataItem{}, Removed: []prompt.TestDataItem{}}
+
+	remaining := make(map[string]int)
+	for _, row := range oldRows {
+		key, err := rowKey(row)
+		if err != nil {
+			return change, err
+		}
+		remaining[key]++
+	}
+
+	for _, row := range newRows {
+		key, err := rowKey(row)
+		if err != nil {
+			return change, err
+		}
+		if remaining[key] > 0 {
+			remaining[key]--
+			change.Unchanged++
+			continue
+		}
+		change.Added = append(change.Added, row)
+	}
+
+	for _, row := range oldRows {
+		key, _ := rowKey(row)
+		if remaining[key] > 0 {
+			remaining[key]--
+			change.Removed = append(change.Removed, row)
+		}
+	}
+	return change, nil
+}
+
+// rowKey returns the row as JSON, which has its keys sorted
+func rowKey(row prompt.TestDataItem) (string, error) {
+	data, err := json.Marshal(row)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal testData row: %w", err)
+	}
+	return string(data), nil
+}
+
+// compareEvaluations returns the pass rates of both evaluations and the mean score of each evaluator
+func compareEvaluations(oldSummary, newSummary *eval.EvaluationSummary) *EvalComparison {
+	comparison := &EvalComparison{Old: oldSummary.Summary, New: newSummary.Summary, Evaluators: []ScoreChange{}}
+
+	var names []string
+	for _, summary := range []*eval.EvaluationSummary{oldSummary, newSummary} {
+		for _, result := range summary.TestResults {
+			for _, evaluation := range result.EvaluationResults {
+				if !slices.Contains(names, evaluation.EvaluatorName) {
+					names = append(names, evaluation.EvaluatorName)
+				}
+			}
+		}
+	}
+
+	for _, name := range names {
+		comparison.Evaluators = append(comparison.Evaluators, ScoreChange{
+			Name: name,
+			Old:  meanScore(oldSummary, name),
+			New:  meanScore(newSummary, name),
+		})
+	}
+	return comparison
+}
+
+func meanScore(summary *eval.EvaluationSummary, name string) *float64 {
+	total, count := 0.0, 0
+	for _, result := range summary.TestResults {
+		for _, evaluation := range result.EvaluationResults {
+			if evaluation.EvaluatorName == name {
+				total += evaluation.Score
+				count++
+			}
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	mean := total / float64(count)
+	return &mean
+}
+
+// formatScore formats a score, or a dash when there is none
+func formatScore(score *float64) string {
+	if score == nil {
+		return "-"
+	}
+	return strconv.FormatFloat(*score, 'f', 2, 64)
+}
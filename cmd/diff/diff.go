@@ -0,0 +1,301 @@
+// Package diff provides a `gh models diff` command to compare prompt files.
+package diff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/github/gh-models/cmd/eval"
+	"github.com/github/gh-models/pkg/command"
+	"github.com/github/gh-models/pkg/prompt"
+	"github.com/github/gh-models/pkg/util"
+	"github.com/mgutz/ansi"
+	"github.com/spf13/cobra"
+)
+
+var (
+	red   = ansi.ColorFunc("red")
+	green = ansi.ColorFunc("green")
+)
+
+// NewDiffCommand returns a new command to compare prompt files
+func NewDiffCommand(cfg *command.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <old-file> <new-file>",
+		Short: "Compare two prompt files",
+		Long: heredoc.Docf(`
+			Compares two prompt files by what they do rather than by their YAML, so that changes to
+			whitespace, key order and the order of test data rows are ignored.
+
+			The model, model parameters and response format are compared, along with the variables and
+			evaluators by name and the messages by position, with the words that changed in each message
+			marked as %[1]s[-removed-]%[1]s and %[1]s{+added+}%[1]s. Test data rows are reported as added or removed.
+
+			Use %[1]s--rev%[1]s to compare a prompt file with the same file at a git revision. The files it extends,
+			includes or reads test data from are read at the same revision. Use %[1]s--eval%[1]s to run the evaluations
+			of both files, and compare their pass rates and the mean score of each evaluator. The evaluations take
+			the %[1]s--var%[1]s, %[1]s--model%[1]s, %[1]s--concurrency%[1]s and %[1]s--allow-exec%[1]s flags of %[1]sgh models eval%[1]s, and
+			evaluator commands of a file at a revision run in a copy of the repository at that revision.
+		`, "`"),
+		Example: heredoc.Doc(`
+			gh models diff old.prompt.yml new.prompt.yml
+			gh models diff --rev HEAD~1 my_prompt.prompt.yml
+			gh models diff --rev main --eval my_prompt.prompt.yml
+			gh models diff --rev main --eval --allow-exec --var tone=formal my_prompt.prompt.yml
+		`),
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rev, err := cmd.Flags().GetString("rev")
+			if err != nil {
+				return err
+			}
+			runEval, err := cmd.Flags().GetBool("eval")
+			if err != nil {
+				return err
+			}
+			jsonOutput, err := cmd.Flags().GetBool("json")
+			if err != nil {
+				return err
+			}
+
+			var oldFile, newFile *prompt.File
+			var oldLabel, newLabel, oldDir, newDir string
+			switch {
+			case rev != "" && len(args) == 1:
+				oldLabel, newLabel = args[0]+"@"+rev, args[0]
+				var cleanup func()
+				oldFile, oldDir, cleanup, err = loadRevision(args[0], rev)
+				if err != nil {
+					return fmt.Errorf("failed to load prompt file at %s: %w", rev, err)
+				}
+				// Keep the files of the revision until the evaluations that may read them are done
+				defer cleanup()
+				newDir = filepath.Dir(args[0])
+				newFile, err = prompt.LoadFromFile(args[0])
+			case rev == "" && len(args) == 2:
+				oldLabel, newLabel = args[0], args[1]
+				oldDir, newDir = filepath.Dir(args[0]), filepath.Dir(args[1])
+				oldFile, err = prompt.LoadFromFile(args[0])
+				if err != nil {
+					return fmt.Errorf("failed to load prompt file: %w", err)
+				}
+				newFile, err = prompt.LoadFromFile(args[1])
+			default:
+				return errors.New("pass two prompt files, or one prompt file with --rev")
+			}
+			if err != nil {
+				return fmt.Errorf("failed to load prompt file: %w", err)
+			}
+
+			result, err := compareFiles(oldFile, newFile)
+			if err != nil {
+				return err
+			}
+			result.Old, result.New = oldLabel, newLabel
+
+			if runEval {
+				opts, err := evaluateOptions(cmd)
+				if err != nil {
+					return err
+				}
+				opts.PromptDir = oldDir
+				oldSummary, err := eval.Evaluate(cmd.Context(), cfg, oldFile, opts)
+				if err != nil {
+					return fmt.Errorf("failed to evaluate %s: %w", oldLabel, err)
+				}
+				opts.PromptDir = newDir
+				newSummary, err := eval.Evaluate(cmd.Context(), cfg, newFile, opts)
+				if err != nil {
+					return fmt.Errorf("failed to evaluate %s: %w", newLabel, err)
+				}
+				result.Eval = compareEvaluations(oldSummary, newSummary)
+			}
+
+			if jsonOutput {
+				data, err := json.MarshalIndent(result, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				cfg.WriteToOut(string(data) + "\n")
+				return nil
+			}
+
+			cfg.WriteToOut(formatResult(result, cfg.IsTerminalOutput))
+			return nil
+		},
+	}
+
+	cmd.Flags().String("rev", "", "Compare the prompt file with the same file at this git revision")
+	cmd.Flags().Bool("eval", false, "Run the evaluations of both files and compare their scores")
+	cmd.Flags().Bool("json", false, "Output the differences in JSON format")
+	cmd.Flags().StringArray("var", []string{}, "Template variables for every test case with --eval (can be used multiple times: --var name=value)")
+	cmd.Flags().Bool("strict-vars", false, "Fail a test case with --eval if the prompt file references a template variable that is not set")
+	cmd.Flags().String("model", "", "Model to evaluate both files with instead of their own models, with --eval")
+	cmd.Flags().Int("concurrency", 1, "Maximum number of test cases to run at the same time with --eval")
+	cmd.Flags().Bool("allow-exec", false, "Allow evaluators to run the commands set with uses, with --eval")
+	return cmd
+}
+
+// evaluateOptions returns the options of the evaluations from the flags
+func evaluateOptions(cmd *cobra.Command) (eval.EvaluateOptions, error) {
+	templateVars, err := util.ParseTemplateVariables(cmd.Flags())
+	if err != nil {
+		return eval.EvaluateOptions{}, err
+	}
+	concurrency, err := cmd.Flags().GetInt("concurrency")
+	if err != nil {
+		return eval.EvaluateOptions{}, err
+	}
+	if concurrency < 1 {
+		return eval.EvaluateOptions{}, errors.New("--concurrency must be at least 1")
+	}
+	strictVars, _ := cmd.Flags().GetBool("strict-vars")
+	model, _ := cmd.Flags().GetString("model")
+	allowExec, _ := cmd.Flags().GetBool("allow-exec")
+	return eval.EvaluateOptions{
+		TemplateVars: templateVars,
+		StrictVars:   strictVars,
+		Model:        model,
+		Concurrency:  concurrency,
+		AllowExec:    allowExec,
+	}, nil
+}
+
+// formatResult renders the differences for people to read, with colors when color is set
+func formatResult(result *Result, color bool) string {
+	var sb strings.Builder
+	removed, added := func(s string) string { return s }, func(s string) string { return s }
+	var removedWords, addedWords func(string) string
+	if color {
+		removed, added = red, green
+		removedWords, addedWords = red, green
+	}
+
+	if result.Empty() {
+		sb.WriteString(fmt.Sprintf("No differences between %s and %s\n", result.Old, result.New))
+	} else {
+		sb.WriteString(fmt.Sprintf("Comparing %s with %s\n", result.Old, result.New))
+	}
+
+	if len(result.Fields) > 0 {
+		sb.WriteString("\n")
+		for _, field := range result.Fields {
+			sb.WriteString(fmt.Sprintf("%s: %s → %s\n", field.Field, formatValue(field.Old), formatValue(field.New)))
+		}
+	}
+
+	writeItems := func(title string, changes []ItemChange) {
+		if len(changes) == 0 {
+			return
+		}
+		sb.WriteString("\n" + title + ":\n")
+		for _, change := range changes {
+			switch change.Change {
+			case changeAdded:
+				sb.WriteString(added("  + "+change.Name) + "\n")
+			case changeRemoved:
+				sb.WriteString(removed("  - "+change.Name) + "\n")
+			default:
+				sb.WriteString("  ~ " + change.Name + "\n")
+				sb.WriteString(indent(formatWordDiff(diffWords(change.Old, change.New), removedWords, addedWords), "      "))
+			}
+		}
+	}
+	writeItems("variables", result.Variables)
+
+	if len(result.Messages) > 0 {
+		sb.WriteString("\nmessages:\n")
+		for _, m := range result.Messages {
+			switch m.Change {
+			case changeAdded:
+				sb.WriteString(added(fmt.Sprintf("  + messages[%d] (%s)", m.Index, m.Role)) + "\n")
+				sb.WriteString(indent(m.New, "      "))
+			case changeRemoved:
+				sb.WriteString(removed(fmt.Sprintf("  - messages[%d] (%s)", m.Index, m.Role)) + "\n")
+			default:
+				role := m.Role
+				if m.OldRole != "" {
+					role = m.OldRole + " → " + m.Role
+				}
+				sb.WriteString(fmt.Sprintf("  ~ messages[%d] (%s)\n", m.Index, role))
+				sb.WriteString(indent(formatWordDiff(diffWords(m.Old, m.New), removedWords, addedWords), "      "))
+			}
+		}
+	}
+
+	writeItems("evaluators", result.Evaluators)
+
+	if testData := result.TestData; len(testData.Added) > 0 || len(testData.Removed) > 0 {
+		sb.WriteString(fmt.Sprintf("\ntestData: %d added, %d removed, %d unchanged\n", len(testData.Added), len(testData.Removed), testData.Unchanged))
+		for _, row := range testData.Added {
+			key, _ := rowKey(row)
+			sb.WriteString(added("  + "+key) + "\n")
+		}
+		for _, row := range testData.Removed {
+			key, _ := rowKey(row)
+			sb.WriteString(removed("  - "+key) + "\n")
+		}
+	}
+
+	if result.Eval != nil {
+		sb.WriteString("\n" + formatEvalComparison(result.Eval))
+	}
+	return sb.String()
+}
+
+func formatEvalComparison(comparison *EvalComparison) string {
+	rows := [][]string{
+		{"evaluation", "old", "new", "change"},
+		{
+			"pass rate",
+			fmt.Sprintf("%.1f%%", comparison.Old.PassRate),
+			fmt.Sprintf("%.1f%%", comparison.New.PassRate),
+			fmt.Sprintf("%+.1f", comparison.New.PassRate-comparison.Old.PassRate),
+		},
+	}
+	for _, score := range comparison.Evaluators {
+		change := "-"
+		if score.Old != nil && score.New != nil {
+			change = fmt.Sprintf("%+.2f", *score.New-*score.Old)
+		}
+		rows = append(rows, []string{score.Name, formatScore(score.Old), formatScore(score.New), change})
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+
+	var sb strings.Builder
+	for _, row := range rows {
+		var line string
+		for i, cell := range row {
+			line += fmt.Sprintf("%-*s   ", widths[i], cell)
+		}
+		sb.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	return sb.String()
+}
+
+// formatValue formats the value of a field, which is empty when it is not set
+func formatValue(value string) string {
+	if value == "" {
+		return "(not set)"
+	}
+	return value
+}
+
+// indent indents every line of a text, and ends it with a newline
+func indent(text, prefix string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(prefix+line, " ")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,265 @@
+package diff
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/github/gh-models/internal/azuremodels"
+	"github.com/github/gh-models/internal/sse"
+	"github.com/github/gh-models/pkg/command"
+	"github.com/github/gh-models/pkg/util"
+	"github.com/stretchr/testify/require"
+)
+
+const oldPromptFile = `name: Greeter
+model: openai/gpt-4o
+modelParameters:
+  temperature: 0.2
+messages:
+  - role: system
+    content: You are a helpful assistant.
+  - role: user
+    content: Say hello to {{name}}.
+testData:
+  - name: Mona
+  - name: Hubot
+evaluators:
+  - name: greets
+    string:
+      contains: Hello
+  - name: polite
+    string:
+      contains: please
+`
+
+// newPromptFile changes oldPromptFile, and reformats and reorders parts of it that did not change
+const newPromptFile = `model: openai/gpt-4o-mini
+name: Greeter
+modelParameters: {temperature: 0.5}
+messages:
+  - role: system
+    content: |
+      You are a friendly
+      assistant.
+  - role: user
+    content: "Say hello to {{name}}."
+  - role: user
+    content: Be brief.
+testData:
+  - name: Hubot
+  - name: Octocat
+evaluators:
+  - name: greets
+    string:
+      contains: Hi
+`
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old.prompt.yml")
+	newPath := filepath.Join(dir, "new.prompt.yml")
+	require.NoError(t, os.WriteFile(oldPath, []byte(oldPromptFile), 0644))
+	require.NoError(t, os.WriteFile(newPath, []byte(newPromptFile), 0644))
+
+	t.Run("compares two files", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		cmd := NewDiffCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), false, 100))
+		cmd.SetArgs([]string{oldPath, newPath})
+
+		require.NoError(t, cmd.Execute())
+		require.Equal(t, `Comparing `+oldPath+` with `+newPath+`
+
+model: openai/gpt-4o → openai/gpt-4o-mini
+modelParameters.temperature: 0.2 → 0.5
+
+messages:
+  ~ messages[0] (system)
+      You are a [-helpful-]{+friendly+}
+      assistant.
+  + messages[2] (user)
+      Be brief.
+
+evaluators:
+  ~ greets
+      name: greets
+      string:
+        contains: [-Hello-]{+Hi+}
+  - polite
+
+testData: 1 added, 1 removed, 1 unchanged
+  + {"name":"Octocat"}
+  - {"name":"Mona"}
+`, out.String())
+	})
+
+	t.Run("reports files without differences", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		cmd := NewDiffCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), false, 100))
+		cmd.SetArgs([]string{oldPath, oldPath})
+
+		require.NoError(t, cmd.Execute())
+		require.Equal(t, "No differences between "+oldPath+" and "+oldPath+"\n", out.String())
+	})
+
+	t.Run("--json", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		cmd := NewDiffCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), false, 100))
+		cmd.SetArgs([]string{"--json", oldPath, newPath})
+		require.NoError(t, cmd.Execute())
+
+		var result Result
+		require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+		require.Equal(t, []FieldChange{
+			{Field: "model", Old: "openai/gpt-4o", New: "openai/gpt-4o-mini"},
+			{Field: "modelParameters.temperature", Old: "0.2", New: "0.5"},
+		}, result.Fields)
+		require.Len(t, result.Messages, 2)
+		require.Equal(t, MessageChange{Index: 2, Change: "added", Role: "user", New: "Be brief."}, result.Messages[1])
+		require.Equal(t, []ItemChange{
+			{Name: "greets", Change: "changed", Old: "name: greets\nstring:\n  contains: Hello", New: "name: greets\nstring:\n  contains: Hi"},
+			{Name: "polite", Change: "removed"},
+		}, result.Evaluators)
+		require.Equal(t, 1, result.TestData.Unchanged)
+		require.Nil(t, result.Eval)
+	})
+
+	t.Run("--eval compares scores", func(t *testing.T) {
+		client := azuremodels.NewMockClient()
+		client.MockGetChatCompletionStream = func(ctx context.Context, req azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+			return &azuremodels.ChatCompletionResponse{
+				Reader: sse.NewMockEventReader([]azuremodels.ChatCompletion{
+					{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: util.Ptr("Hi there, please")}}}},
+				}),
+			}, nil
+		}
+		out := new(bytes.Buffer)
+		cmd := NewDiffCommand(command.NewConfig(out, out, client, false, 100))
+		cmd.SetArgs([]string{"--eval", oldPath, newPath})
+
+		require.NoError(t, cmd.Execute())
+		require.True(t, strings.HasSuffix(out.String(), `
+evaluation   old    new      change
+pass rate    0.0%   100.0%   +100.0
+greets       0.00   1.00     +1.00
+polite       1.00   -        -
+`), out.String())
+	})
+
+	t.Run("requires two files or --rev", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		cmd := NewDiffCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), false, 100))
+		cmd.SetArgs([]string{oldPath})
+
+		require.EqualError(t, cmd.Execute(), "pass two prompt files, or one prompt file with --rev")
+	})
+}
+
+func TestDiffRevision(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+
+	promptDir := filepath.Join(dir, "prompts")
+	require.NoError(t, os.MkdirAll(promptDir, 0755))
+	promptPath := filepath.Join(promptDir, "greeter.prompt.yml")
+	require.NoError(t, os.WriteFile(promptPath, []byte("extends: base.yml\n"+strings.Replace(oldPromptFile, "model: openai/gpt-4o\n", "", 1)), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(promptDir, "base.yml"), []byte("model: openai/gpt-4o\n"), 0644))
+	run("init", "-q")
+	run("add", "-A")
+	run("commit", "-q", "-m", "Add greeter")
+
+	// Change the model in the file that is extended, which is read at the revision too
+	require.NoError(t, os.WriteFile(filepath.Join(promptDir, "base.yml"), []byte("model: openai/gpt-4.1\n"), 0644))
+
+	out := new(bytes.Buffer)
+	cmd := NewDiffCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), false, 100))
+	cmd.SetArgs([]string{"--rev", "HEAD", promptPath})
+
+	require.NoError(t, cmd.Execute())
+	require.Equal(t, "Comparing "+promptPath+"@HEAD with "+promptPath+"\n\nmodel: openai/gpt-4o → openai/gpt-4.1\n", out.String())
+
+	t.Run("files that do not exist at the revision", func(t *testing.T) {
+		newPath := filepath.Join(promptDir, "new.prompt.yml")
+		require.NoError(t, os.WriteFile(newPath, []byte(oldPromptFile), 0644))
+
+		cmd := NewDiffCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), false, 100))
+		cmd.SetArgs([]string{"--rev", "HEAD", newPath})
+
+		require.EqualError(t, cmd.Execute(), "failed to load prompt file at HEAD: "+newPath+" does not exist at HEAD")
+	})
+
+	t.Run("reads extended files from other directories at the revision", func(t *testing.T) {
+		sharedPath := filepath.Join(dir, "shared", "base.prompt.yml")
+		require.NoError(t, os.MkdirAll(filepath.Dir(sharedPath), 0755))
+		require.NoError(t, os.WriteFile(sharedPath, []byte("model: openai/gpt-4o\n"), 0644))
+		siblingPath := filepath.Join(promptDir, "sibling.prompt.yml")
+		require.NoError(t, os.WriteFile(siblingPath, []byte("extends: ../shared/base.prompt.yml\nmessages:\n  - role: user\n    content: hi\n"), 0644))
+		run("add", "-A")
+		run("commit", "-q", "-m", "Add sibling")
+		require.NoError(t, os.WriteFile(sharedPath, []byte("model: openai/gpt-4.1\n"), 0644))
+
+		out := new(bytes.Buffer)
+		cmd := NewDiffCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), false, 100))
+		cmd.SetArgs([]string{"--rev", "HEAD", siblingPath})
+
+		require.NoError(t, cmd.Execute())
+		require.Equal(t, "Comparing "+siblingPath+"@HEAD with "+siblingPath+"\n\nmodel: openai/gpt-4o → openai/gpt-4.1\n", out.String())
+	})
+
+	t.Run("--eval runs the evaluator commands of the revision", func(t *testing.T) {
+		evalPath := filepath.Join(promptDir, "check.prompt.yml")
+		require.NoError(t, os.WriteFile(evalPath, []byte(`model: openai/gpt-4o
+messages:
+  - role: user
+    content: Greet {{name}} in a {{tone}} tone.
+testData:
+  - name: Mona
+evaluators:
+  - name: check
+    uses: ./check.sh
+`), 0644))
+		writeCheck := func(passed bool) {
+			script := fmt.Sprintf("#!/bin/sh\ncat > /dev/null\necho '{\"passed\": %t}'\n", passed)
+			require.NoError(t, os.WriteFile(filepath.Join(promptDir, "check.sh"), []byte(script), 0755))
+		}
+		writeCheck(true)
+		run("add", "-A")
+		run("commit", "-q", "-m", "Add check")
+		writeCheck(false)
+
+		client := azuremodels.NewMockClient()
+		client.MockGetChatCompletionStream = func(ctx context.Context, req azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+			require.Equal(t, "Greet Mona in a formal tone.", *req.Messages[0].Content)
+			return &azuremodels.ChatCompletionResponse{
+				Reader: sse.NewMockEventReader([]azuremodels.ChatCompletion{
+					{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: util.Ptr("Hello, Mona")}}}},
+				}),
+			}, nil
+		}
+		out := new(bytes.Buffer)
+		cmd := NewDiffCommand(command.NewConfig(out, out, client, false, 100))
+		cmd.SetArgs([]string{"--rev", "HEAD", "--eval", "--allow-exec", "--var", "tone=formal", evalPath})
+
+		require.NoError(t, cmd.Execute())
+		require.True(t, strings.HasSuffix(out.String(), `
+evaluation   old      new    change
+pass rate    100.0%   0.0%   -100.0
+check        1.00     0.00   -1.00
+`), out.String())
+	})
+}
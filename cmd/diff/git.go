@@ -0,0 +1,121 @@
+package diff
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/github/gh-models/pkg/prompt"
+)
+
+// loadRevision loads a prompt file as it was at a git revision. The repository is extracted from the
+// revision, so that the files the prompt file extends, includes or reads test data from are read at
+// the same revision, wherever they are in the repository. It returns the directory of the extracted
+// prompt file, and a function that removes the extracted files, which the caller calls once it is
+// done with the file, since evaluators may read from it.
+func loadRevision(filePath, rev string) (_ *prompt.File, dir string, cleanup func(), err error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	// The path of the file's directory in the repository, such as prompts/, which is empty at the root
+	prefix, err := git(filepath.Dir(absPath), "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, "", nil, err
+	}
+	root, err := git(filepath.Dir(absPath), "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	// Run at the root of the repository, so that git archive includes every file, with paths relative to it
+	archive, err := git(strings.TrimSpace(string(root)), "archive", "--format=tar", rev)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	tempDir, err := os.MkdirTemp("", "gh-models-diff-")
+	if err != nil {
+		return nil, "", nil, err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	if err := extractTar(bytes.NewReader(archive), tempDir); err != nil {
+		return nil, "", nil, fmt.Errorf("failed to extract %s: %w", rev, err)
+	}
+
+	revPath := filepath.Join(tempDir, filepath.FromSlash(strings.TrimSpace(string(prefix))), filepath.Base(absPath))
+	if _, err := os.Stat(revPath); err != nil {
+		return nil, "", nil, fmt.Errorf("%s does not exist at %s", filePath, rev)
+	}
+	pf, err := prompt.LoadFromFile(revPath)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return pf, filepath.Dir(revPath), func() { os.RemoveAll(tempDir) }, nil
+}
+
+// git runs a git command in a directory and returns its output
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], message)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
+
+// extractTar writes the files of a tar archive to a directory
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(path, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path in archive: %s", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			// Keep the executable bit, so that evaluator commands in the revision can run
+			if err := os.WriteFile(path, data, header.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		}
+	}
+}
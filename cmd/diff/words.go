@@ -0,0 +1,164 @@
+package diff
+
+import (
+	"strings"
+	"unicode"
+)
+
+// word is a word of a text and the whitespace that follows it
+type word struct {
+	text  string
+	space string
+}
+
+// splitWords splits a text into words. Whitespace before the first word is kept as a word without text.
+func splitWords(s string) []word {
+	var words []word
+	start := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsSpace(r) })
+	if start < 0 {
+		if s == "" {
+			return nil
+		}
+		return []word{{space: s}}
+	}
+	if start > 0 {
+		words = append(words, word{space: s[:start]})
+	}
+
+	rest := s[start:]
+	for rest != "" {
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			end = len(rest)
+		}
+		next := strings.IndexFunc(rest[end:], func(r rune) bool { return !unicode.IsSpace(r) })
+		if next < 0 {
+			next = len(rest) - end
+		}
+		words = append(words, word{text: rest[:end], space: rest[end : end+next]})
+		rest = rest[end+next:]
+	}
+	return words
+}
+
+// editKind says whether words are kept, removed or added
+type editKind int
+
+const (
+	editKeep editKind = iota
+	editRemove
+	editAdd
+)
+
+// wordEdit is a run of words that are kept, removed or added
+type wordEdit struct {
+	kind  editKind
+	words []word
+}
+
+// diffWords returns the edits that turn the words of a into the words of b. Words are compared without
+// the whitespace around them, so changes to whitespace alone are not reported.
+func diffWords(a, b string) []wordEdit {
+	old, updated := splitWords(a), splitWords(b)
+
+	// Skip the words at the start and end that are the same, which is most of the text for small changes
+	prefix := 0
+	for prefix < len(old) && prefix < len(updated) && old[prefix].text == updated[prefix].text {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(updated)-prefix &&
+		old[len(old)-1-suffix].text == updated[len(updated)-1-suffix].text {
+		suffix++
+	}
+
+	var edits []wordEdit
+	add := func(kind editKind, w word) {
+		if len(edits) > 0 && edits[len(edits)-1].kind == kind {
+			edits[len(edits)-1].words = append(edits[len(edits)-1].words, w)
+			return
+		}
+		edits = append(edits, wordEdit{kind: kind, words: []word{w}})
+	}
+
+	for _, w := range updated[:prefix] {
+		add(editKeep, w)
+	}
+
+	// Find the longest common subsequence of the words in between
+	x, y := old[prefix:len(old)-suffix], updated[prefix:len(updated)-suffix]
+	lengths := make([][]int32, len(x)+1)
+	for i := range lengths {
+		lengths[i] = make([]int32, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i].text == y[j].text {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i].text == y[j].text:
+			add(editKeep, y[j])
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lengths[i+1][j] >= lengths[i][j+1]):
+			add(editRemove, x[i])
+			i++
+		default:
+			add(editAdd, y[j])
+			j++
+		}
+	}
+
+	for _, w := range updated[len(updated)-suffix:] {
+		add(editKeep, w)
+	}
+	return edits
+}
+
+// formatWordDiff renders edits in the style of git diff --word-diff, marking removed words with [-...-]
+// and added words with {+...+}, or with the given colors when they are set
+func formatWordDiff(edits []wordEdit, removed, added func(string) string) string {
+	var sb strings.Builder
+	for i, edit := range edits {
+		var text strings.Builder
+		for _, w := range edit.words {
+			text.WriteString(w.text + w.space)
+		}
+		if edit.kind == editKeep {
+			sb.WriteString(text.String())
+			continue
+		}
+
+		// Whitespace after the run goes outside the markers
+		changed := strings.TrimRightFunc(text.String(), unicode.IsSpace)
+		space := text.String()[len(changed):]
+		if changed == "" {
+			sb.WriteString(space)
+			continue
+		}
+		switch {
+		case edit.kind == editRemove && removed != nil:
+			changed = removed(changed)
+		case edit.kind == editRemove:
+			changed = "[-" + changed + "-]"
+		case added != nil:
+			changed = added(changed)
+		default:
+			changed = "{+" + changed + "+}"
+		}
+		// Removed words are followed by the added words that replace them, without whitespace between
+		if edit.kind == editRemove && i+1 < len(edits) && edits[i+1].kind == editAdd {
+			space = ""
+		}
+		sb.WriteString(changed + space)
+	}
+	return sb.String()
+}
@@ -0,0 +1,29 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWordDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old      string
+		new      string
+		expected string
+	}{
+		{"unchanged", "You are helpful.", "You are helpful.", "You are helpful."},
+		{"whitespace only", "You are\nhelpful.", "  You   are helpful.", "  You   are helpful."},
+		{"replaced word", "You are a helpful assistant.", "You are a friendly assistant.", "You are a [-helpful-]{+friendly+} assistant."},
+		{"added words", "Answer.", "Answer in one sentence.", "[-Answer.-]{+Answer in one sentence.+}"},
+		{"removed words", "Be very brief.", "Be brief.", "Be [-very-] brief."},
+		{"empty", "", "Hello there", "{+Hello there+}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, formatWordDiff(diffWords(tt.old, tt.new), nil, nil))
+		})
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/MakeNowJust/heredoc"
@@ -49,6 +52,13 @@ type TestResult struct {
 	EvaluationResults []EvaluationResult     `json:"evaluationResults"`
 }
 
+// MatrixSummary represents the results of evaluating a prompt with several models
+type MatrixSummary struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Models      []EvaluationSummary `json:"models"`
+}
+
 // EvaluationResult represents the result of a single evaluator
 type EvaluationResult struct {
 	EvaluatorName string  `json:"evaluatorName"`
@@ -59,6 +69,9 @@ type EvaluationResult struct {
 
 var FailedTests = errors.New("❌ Some tests failed.")
 
+// schemaEvaluatorName is the name of the implicit evaluator that checks responses against the JSON schema
+const schemaEvaluatorName = "json-schema"
+
 // NewEvalCommand returns a new command to evaluate prompts against models
 func NewEvalCommand(cfg *command.Config) *cobra.Command {
 	cmd := &cobra.Command{
@@ -92,11 +105,40 @@ func NewEvalCommand(cfg *command.Config) *cobra.Command {
 			This command will automatically retry on rate limiting errors, waiting for the specified
 			duration before retrying the request.
 
+			When the prompt file uses %[1]sresponseFormat: json_schema%[1]s, every response is validated against
+			the schema and reported as a %[1]sjson-schema%[1]s evaluation. Use %[1]s--schema-retries%[1]s to send the
+			validation errors back to the model and ask it to fix its response.
+
+			Variables set with %[1]s--var%[1]s or %[1]s--var-file%[1]s are available to every test case, and values from
+			the test data take precedence over them. Use %[1]s--allow-env%[1]s to replace %[1]s${env:NAME}%[1]s references
+			in the prompt file with the values of environment variables. With %[1]s--strict-vars%[1]s, a test case fails
+			if its messages or evaluators reference a variable that is not set.
+
+			Use %[1]s--model%[1]s to evaluate the prompt with a different model than the one in the prompt file. Pass it
+			several times, or list the models under %[1]smodels%[1]s in the prompt file, to run the same tests with each
+			model and compare their pass rates and the mean score of each evaluator. The JSON output then has
+			the results of each model under %[1]smodels%[1]s.
+
+			Use %[1]s--concurrency%[1]s to run several test cases, and the evaluators of each test case, at the same
+			time. Results are still reported in the order of the test data, and when the model is rate limited,
+			every request waits before it is retried.
+
+			An evaluator can run a command with %[1]suses%[1]s, such as %[1]s./evaluators/check.sh%[1]s or
+			%[1]sgo run ./evaluators/check%[1]s. Since prompt files can come from anywhere, commands only run with
+			%[1]s--allow-exec%[1]s. The command runs in the directory of the prompt file and reads a JSON object with
+			%[1]sevaluator%[1]s, %[1]stestCase%[1]s, %[1]sresponse%[1]s and %[1]sconfig%[1]s, the evaluator's %[1]swith%[1]s mapping,
+			from stdin. It writes a JSON object with %[1]sscore%[1]s, between 0 and 1, %[1]spassed%[1]s and %[1]sdetails%[1]s to
+			stdout. Commands that fail, or run longer than %[1]s--plugin-timeout%[1]s, stop the evaluation.
+
 			See https://docs.github.com/github-models/use-github-models/storing-prompts-in-github-repositories#supported-file-format for more information.
-		`),
+		`, "`"),
 		Example: heredoc.Doc(`
 			gh models eval my_prompt.prompt.yml
 			gh models eval --org my-org my_prompt.prompt.yml
+			gh models eval --var-file vars.yml --var context=@docs/context.md my_prompt.prompt.yml
+			gh models eval --concurrency 4 my_prompt.prompt.yml
+			gh models eval --model openai/gpt-4o-mini --model openai/gpt-4.1 my_prompt.prompt.yml
+			gh models eval --allow-exec --plugin-timeout 1m my_prompt.prompt.yml
 		`),
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -111,19 +153,67 @@ func NewEvalCommand(cfg *command.Config) *cobra.Command {
 			// Get the org flag
 			org, _ := cmd.Flags().GetString("org")
 
+			schemaRetries, err := cmd.Flags().GetInt("schema-retries")
+			if err != nil {
+				return err
+			}
+			if schemaRetries < 0 {
+				return errors.New("--schema-retries must not be negative")
+			}
+
+			concurrency, err := cmd.Flags().GetInt("concurrency")
+			if err != nil {
+				return err
+			}
+			if concurrency < 1 {
+				return errors.New("--concurrency must be at least 1")
+			}
+
+			pluginTimeout, err := cmd.Flags().GetDuration("plugin-timeout")
+			if err != nil {
+				return err
+			}
+			if pluginTimeout <= 0 {
+				return errors.New("--plugin-timeout must be positive")
+			}
+
+			// Parse template variables shared by every test case
+			templateVars, err := util.ParseTemplateVariables(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			allowEnv, _ := cmd.Flags().GetBool("allow-env")
+			strictVars, _ := cmd.Flags().GetBool("strict-vars")
+			allowExec, _ := cmd.Flags().GetBool("allow-exec")
+
 			// Load the evaluation prompt file
-			evalFile, err := loadEvaluationPromptFile(promptFilePath)
+			evalFile, err := loadEvaluationPromptFile(promptFilePath, allowEnv)
 			if err != nil {
 				return fmt.Errorf("failed to load prompt file: %w", err)
 			}
 
+			// Models on the command line take precedence over the models in the prompt file
+			models, _ := cmd.Flags().GetStringArray("model")
+			if len(models) == 0 {
+				models = evalFile.Models
+			}
+
 			// Run evaluation
 			handler := &evalCommandHandler{
-				cfg:        cfg,
-				client:     cfg.Client,
-				evalFile:   evalFile,
-				jsonOutput: jsonOutput,
-				org:        org,
+				cfg:           cfg,
+				client:        cfg.Client,
+				evalFile:      evalFile,
+				jsonOutput:    jsonOutput,
+				org:           org,
+				schemaRetries: schemaRetries,
+				templateVars:  templateVars,
+				strictVars:    strictVars,
+				concurrency:   concurrency,
+				models:        models,
+				allowExec:     allowExec,
+				pluginTimeout: pluginTimeout,
+				promptDir:     filepath.Dir(promptFilePath),
 			}
 
 			err = handler.runEvaluation(cmd.Context())
@@ -139,6 +229,15 @@ func NewEvalCommand(cfg *command.Config) *cobra.Command {
 
 	cmd.Flags().Bool("json", false, "Output results in JSON format")
 	cmd.Flags().String("org", "", "Organization to attribute usage to (omitting will attribute usage to the current actor")
+	cmd.Flags().Int("schema-retries", 0, "Number of times to ask the model to fix a response that does not match the JSON schema")
+	cmd.Flags().StringArray("var", []string{}, "Template variables for every test case (can be used multiple times: --var name=value, --var name=@file or --var name=- for stdin)")
+	cmd.Flags().StringArray("var-file", []string{}, "Load template variables from a YAML, JSON or .env file (can be used multiple times)")
+	cmd.Flags().Bool("allow-env", false, "Replace ${env:NAME} references in the prompt file with the values of environment variables")
+	cmd.Flags().Bool("strict-vars", false, "Fail a test case if the prompt file references a template variable that is not set")
+	cmd.Flags().StringArray("model", []string{}, "Model to evaluate the prompt with instead of the prompt file's model (can be used multiple times to compare models)")
+	cmd.Flags().Int("concurrency", 1, "Maximum number of test cases to run at the same time")
+	cmd.Flags().Bool("allow-exec", false, "Allow evaluators to run the commands set with uses")
+	cmd.Flags().Duration("plugin-timeout", defaultPluginTimeout, "Maximum time an evaluator command can run for each test case")
 	return cmd
 }
 
@@ -148,10 +247,71 @@ type evalCommandHandler struct {
 	evalFile   *prompt.File
 	jsonOutput bool
 	org        string
+	// schemaRetries is the number of times to ask the model to fix a response that does not match the JSON schema
+	schemaRetries int
+	// templateVars are the variables set on the command line, used as defaults for every test case
+	templateVars map[string]string
+	// strictVars reports undefined template variables as errors instead of leaving them in place
+	strictVars bool
+	// models replace the prompt file's model, and the prompt is evaluated with each of them when there are several
+	models []string
+	// concurrency is the maximum number of test cases, and of model requests, to run at the same time
+	concurrency int
+	// requests limits the model requests in flight when running concurrently
+	requests chan struct{}
+	// rateLimit makes every request wait when any of them is rate limited
+	rateLimit rateLimitGate
+	// outputMu serializes the output of test cases that run at the same time
+	outputMu sync.Mutex
+	// allowExec allows evaluators to run commands, which run in promptDir for at most pluginTimeout
+	allowExec     bool
+	pluginTimeout time.Duration
+	promptDir     string
 }
 
-func loadEvaluationPromptFile(filePath string) (*prompt.File, error) {
-	evalFile, err := prompt.LoadFromFile(filePath)
+// rateLimitGate holds back every request until a rate limit has passed
+type rateLimitGate struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// wait blocks until the gate is open
+func (g *rateLimitGate) wait(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		g.mu.Lock()
+		delay := time.Until(g.until)
+		g.mu.Unlock()
+		if delay <= 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// closeFor closes the gate for a duration, unless it is already closed for longer
+func (g *rateLimitGate) closeFor(d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if until := time.Now().Add(d); until.After(g.until) {
+		g.until = until
+	}
+}
+
+func loadEvaluationPromptFile(filePath string, allowEnv bool) (*prompt.File, error) {
+	var opts []prompt.LoadOption
+	if allowEnv {
+		opts = append(opts, prompt.WithEnvInterpolation())
+	}
+
+	evalFile, err := prompt.LoadFromFile(filePath, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load prompt file: %w", err)
 	}
@@ -159,7 +319,63 @@ func loadEvaluationPromptFile(filePath string) (*prompt.File, error) {
 	return evalFile, nil
 }
 
+// EvaluateOptions are the options of Evaluate, which match the flags of the eval command
+type EvaluateOptions struct {
+	// TemplateVars are used as defaults for every test case
+	TemplateVars map[string]string
+	// StrictVars reports undefined template variables as errors instead of leaving them in place
+	StrictVars bool
+	// Model replaces the prompt file's model when it is set
+	Model string
+	// Concurrency is the maximum number of test cases to run at the same time, 1 when it is not set
+	Concurrency int
+	// AllowExec allows evaluators to run commands, which run in PromptDir for at most PluginTimeout
+	AllowExec     bool
+	PluginTimeout time.Duration
+	PromptDir     string
+}
+
+// Evaluate runs the test cases of a prompt file and returns the results, without printing them
+func Evaluate(ctx context.Context, cfg *command.Config, evalFile *prompt.File, opts EvaluateOptions) (*EvaluationSummary, error) {
+	if opts.Model != "" {
+		evalFile = withModel(evalFile, opts.Model)
+	}
+	h := &evalCommandHandler{
+		cfg:           cfg,
+		client:        cfg.Client,
+		evalFile:      evalFile,
+		jsonOutput:    true,
+		templateVars:  opts.TemplateVars,
+		strictVars:    opts.StrictVars,
+		concurrency:   max(opts.Concurrency, 1),
+		allowExec:     opts.AllowExec,
+		pluginTimeout: opts.PluginTimeout,
+		promptDir:     opts.PromptDir,
+	}
+	if err := h.checkTestCases(); err != nil {
+		return nil, err
+	}
+	if err := h.checkPlugins(); err != nil {
+		return nil, err
+	}
+	return h.evaluate(ctx)
+}
+
 func (h *evalCommandHandler) runEvaluation(ctx context.Context) error {
+	if err := h.checkTestCases(); err != nil {
+		return err
+	}
+	if err := h.checkPlugins(); err != nil {
+		return err
+	}
+
+	if len(h.models) > 1 {
+		return h.runMatrix(ctx)
+	}
+	if len(h.models) == 1 {
+		h.evalFile = withModel(h.evalFile, h.models[0])
+	}
+
 	// Print header info only for human-readable output
 	if !h.jsonOutput {
 		h.cfg.WriteToOut(fmt.Sprintf("Running evaluation: %s\n", h.evalFile.Name))
@@ -169,21 +385,102 @@ func (h *evalCommandHandler) runEvaluation(ctx context.Context) error {
 		h.cfg.WriteToOut("\n")
 	}
 
-	var testResults []TestResult
-	passedTests := 0
-	totalTests := len(h.evalFile.TestData)
+	summary, err := h.evaluate(ctx)
+	if err != nil {
+		return err
+	}
 
-	for i, testCase := range h.evalFile.TestData {
+	if h.jsonOutput {
+		// Output JSON format
+		jsonData, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+
+		h.cfg.WriteToOut(string(jsonData) + "\n")
+	} else {
+		// Output human-readable format summary
+		h.printSummary(summary.Summary.PassedTests, summary.Summary.TotalTests, summary.Summary.PassRate)
+	}
+
+	if summary.Summary.FailedTests > 0 {
+		return FailedTests
+	}
+
+	return nil
+}
+
+// runMatrix evaluates the prompt with each model, and compares their results
+func (h *evalCommandHandler) runMatrix(ctx context.Context) error {
+	evalFile := h.evalFile
+	defer func() { h.evalFile = evalFile }()
+
+	if !h.jsonOutput {
+		h.cfg.WriteToOut(fmt.Sprintf("Running evaluation: %s\n", evalFile.Name))
+		h.cfg.WriteToOut(fmt.Sprintf("Description: %s\n", evalFile.Description))
+		h.cfg.WriteToOut(fmt.Sprintf("Models: %s\n", strings.Join(h.models, ", ")))
+		h.cfg.WriteToOut(fmt.Sprintf("Test cases: %d\n", len(evalFile.TestData)))
+		h.cfg.WriteToOut("\n")
+	}
+
+	matrix := &MatrixSummary{Name: evalFile.Name, Description: evalFile.Description}
+	failed := false
+	for _, model := range h.models {
 		if !h.jsonOutput {
-			h.cfg.WriteToOut("-------------------------\n")
-			h.cfg.WriteToOut(fmt.Sprintf("Running test case %d/%d...\n", i+1, totalTests))
+			h.cfg.WriteToOut("=========================\n")
+			h.cfg.WriteToOut(fmt.Sprintf("Model: %s\n", model))
+			h.cfg.WriteToOut("\n")
 		}
 
-		result, err := h.runTestCase(ctx, testCase)
+		h.evalFile = withModel(evalFile, model)
+		summary, err := h.evaluate(ctx)
 		if err != nil {
-			return fmt.Errorf("test case %d failed: %w", i+1, err)
+			return fmt.Errorf("model %s: %w", model, err)
 		}
+		matrix.Models = append(matrix.Models, *summary)
+		failed = failed || summary.Summary.FailedTests > 0
+	}
 
+	if h.jsonOutput {
+		jsonData, err := json.MarshalIndent(matrix, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		h.cfg.WriteToOut(string(jsonData) + "\n")
+	} else {
+		h.printMatrixSummary(matrix)
+	}
+
+	if failed {
+		return FailedTests
+	}
+	return nil
+}
+
+// withModel returns a copy of the prompt file that uses another model
+func withModel(evalFile *prompt.File, model string) *prompt.File {
+	modelFile := *evalFile
+	modelFile.Model = model
+	return &modelFile
+}
+
+// checkTestCases checks the variables of every test case before calling the model
+func (h *evalCommandHandler) checkTestCases() error {
+	for i, testCase := range h.evalFile.TestData {
+		if _, err := h.testCaseData(testCase); err != nil {
+			return fmt.Errorf("test case %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// evaluate runs every test case, printing the result of each one for human-readable output
+func (h *evalCommandHandler) evaluate(ctx context.Context) (*EvaluationSummary, error) {
+	var testResults []TestResult
+	passedTests := 0
+	totalTests := len(h.evalFile.TestData)
+
+	addResult := func(result TestResult) {
 		testResults = append(testResults, result)
 
 		// Check if all evaluators passed
@@ -204,43 +501,123 @@ func (h *evalCommandHandler) runEvaluation(ctx context.Context) error {
 		}
 	}
 
+	if h.concurrency > 1 {
+		h.requests = make(chan struct{}, h.concurrency)
+		err := h.runTestCasesConcurrently(ctx, func(i int, result TestResult) {
+			h.printTestCaseHeader(i, totalTests)
+			addResult(result)
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for i, testCase := range h.evalFile.TestData {
+			h.printTestCaseHeader(i, totalTests)
+
+			result, err := h.runTestCase(ctx, testCase)
+			if err != nil {
+				return nil, fmt.Errorf("test case %d failed: %w", i+1, err)
+			}
+			addResult(result)
+		}
+	}
+
 	// Calculate pass rate
 	passRate := 100.0
 	if totalTests > 0 {
 		passRate = float64(passedTests) / float64(totalTests) * 100
 	}
 
-	if h.jsonOutput {
-		// Output JSON format
-		summary := EvaluationSummary{
-			Name:        h.evalFile.Name,
-			Description: h.evalFile.Description,
-			Model:       h.evalFile.Model,
-			TestResults: testResults,
-			Summary: Summary{
-				TotalTests:  totalTests,
-				PassedTests: passedTests,
-				FailedTests: totalTests - passedTests,
-				PassRate:    passRate,
-			},
-		}
+	return &EvaluationSummary{
+		Name:        h.evalFile.Name,
+		Description: h.evalFile.Description,
+		Model:       h.evalFile.Model,
+		TestResults: testResults,
+		Summary: Summary{
+			TotalTests:  totalTests,
+			PassedTests: passedTests,
+			FailedTests: totalTests - passedTests,
+			PassRate:    passRate,
+		},
+	}, nil
+}
 
-		jsonData, err := json.MarshalIndent(summary, "", "  ")
-		if err != nil {
-			return fmt.Errorf("failed to marshal JSON: %w", err)
+// runTestCasesConcurrently runs up to concurrency test cases at the same time, and reports their
+// results in the order of the test data as soon as they are available. When a test case fails,
+// the test cases that are still running are canceled and its error is returned.
+func (h *evalCommandHandler) runTestCasesConcurrently(ctx context.Context, report func(int, TestResult)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type indexedResult struct {
+		index  int
+		result TestResult
+		err    error
+	}
+
+	testData := h.evalFile.TestData
+	results := make(chan indexedResult)
+	go func() {
+		sem := make(chan struct{}, h.concurrency)
+		for i, testCase := range testData {
+			sem <- struct{}{}
+			go func(i int, testCase map[string]interface{}) {
+				defer func() { <-sem }()
+				result, err := h.runTestCase(ctx, testCase)
+				results <- indexedResult{index: i, result: result, err: err}
+			}(i, testCase)
+		}
+	}()
+
+	pending := make(map[int]TestResult)
+	next := 0
+	var firstErr error
+	for range testData {
+		r := <-results
+		// Keep receiving results after an error so that no worker is left blocked
+		if firstErr != nil {
+			continue
+		}
+		if r.err != nil {
+			firstErr = fmt.Errorf("test case %d failed: %w", r.index+1, r.err)
+			cancel()
+			continue
 		}
 
-		h.cfg.WriteToOut(string(jsonData) + "\n")
-	} else {
-		// Output human-readable format summary
-		h.printSummary(passedTests, totalTests, passRate)
+		pending[r.index] = r.result
+		h.outputMu.Lock()
+		for {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			report(next, result)
+			next++
+		}
+		h.outputMu.Unlock()
 	}
 
-	if totalTests-passedTests > 0 {
-		return FailedTests
+	return firstErr
+}
+
+func (h *evalCommandHandler) printTestCaseHeader(i, totalTests int) {
+	if h.jsonOutput {
+		return
 	}
+	h.cfg.WriteToOut("-------------------------\n")
+	h.cfg.WriteToOut(fmt.Sprintf("Running test case %d/%d...\n", i+1, totalTests))
+}
 
-	return nil
+// printProgress writes a progress message for human-readable output, without interleaving it
+// with the output of test cases that run at the same time
+func (h *evalCommandHandler) printProgress(message string) {
+	if h.jsonOutput {
+		return
+	}
+	h.outputMu.Lock()
+	defer h.outputMu.Unlock()
+	h.cfg.WriteToOut(message)
 }
 
 func (h *evalCommandHandler) printTestResult(result TestResult, testPassed bool) {
@@ -314,7 +691,89 @@ func (h *evalCommandHandler) printSummary(passedTests, totalTests int, passRate
 	}
 }
 
+// printMatrixSummary prints the pass rate of each model, and the mean score of each evaluator
+func (h *evalCommandHandler) printMatrixSummary(matrix *MatrixSummary) {
+	h.cfg.WriteToOut("Evaluation Summary:\n")
+
+	header := []string{"EVALUATION"}
+	for _, summary := range matrix.Models {
+		header = append(header, summary.Model)
+	}
+	table := h.cfg.NewTablePrinter()
+	table.AddHeader(header, tableprinter.WithColor(lightGrayUnderline))
+
+	allPassed := true
+	table.AddField("Passed")
+	for _, summary := range matrix.Models {
+		s := summary.Summary
+		color := green
+		if s.FailedTests > 0 {
+			color, allPassed = red, false
+		}
+		table.AddField(fmt.Sprintf("%d/%d (%.2f%%)", s.PassedTests, s.TotalTests, s.PassRate), tableprinter.WithColor(color))
+	}
+	table.EndRow()
+
+	for _, name := range evaluatorNames(matrix.Models) {
+		table.AddField(name)
+		for _, summary := range matrix.Models {
+			if score, ok := meanScore(summary, name); ok {
+				table.AddField(fmt.Sprintf("%.2f", score))
+			} else {
+				table.AddField("-")
+			}
+		}
+		table.EndRow()
+	}
+
+	if err := table.Render(); err != nil {
+		return
+	}
+
+	if allPassed {
+		h.cfg.WriteToOut("🎉 All tests passed!\n")
+	}
+}
+
+// evaluatorNames returns the names of the evaluators in the results, in the order they first appear
+func evaluatorNames(summaries []EvaluationSummary) []string {
+	var names []string
+	for _, summary := range summaries {
+		for _, result := range summary.TestResults {
+			for _, evaluation := range result.EvaluationResults {
+				if !slices.Contains(names, evaluation.EvaluatorName) {
+					names = append(names, evaluation.EvaluatorName)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// meanScore returns the mean score of an evaluator over the test cases it ran for
+func meanScore(summary EvaluationSummary, name string) (float64, bool) {
+	total, count := 0.0, 0
+	for _, result := range summary.TestResults {
+		for _, evaluation := range result.EvaluationResults {
+			if evaluation.EvaluatorName == name {
+				total += evaluation.Score
+				count++
+			}
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return total / float64(count), true
+}
+
 func (h *evalCommandHandler) runTestCase(ctx context.Context, testCase map[string]interface{}) (TestResult, error) {
+	row := testCase
+	testCase, err := h.testCaseData(testCase)
+	if err != nil {
+		return TestResult{}, err
+	}
+
 	// Template the messages with test case data
 	messages, err := h.templateMessages(testCase)
 	if err != nil {
@@ -327,19 +786,50 @@ func (h *evalCommandHandler) runTestCase(ctx context.Context, testCase map[strin
 		return TestResult{}, fmt.Errorf("failed to call model: %w", err)
 	}
 
+	// Validate structured responses against the JSON schema before running evaluators
+	var schemaResult *EvaluationResult
+	if h.evalFile.ResponseSchema() != nil {
+		var violations []prompt.SchemaViolation
+		response, violations, err = h.repairResponse(ctx, messages, response)
+		if err != nil {
+			return TestResult{}, fmt.Errorf("failed to call model: %w", err)
+		}
+		schemaResult = newSchemaEvaluationResult(violations)
+	}
+
 	// Run evaluators
 	evalResults, err := h.runEvaluators(ctx, testCase, response)
 	if err != nil {
 		return TestResult{}, fmt.Errorf("failed to run evaluators: %w", err)
 	}
 
+	if schemaResult != nil {
+		evalResults = append([]EvaluationResult{*schemaResult}, evalResults...)
+	}
+
 	return TestResult{
-		TestCase:          testCase,
+		TestCase:          row,
 		ModelResponse:     response,
 		EvaluationResults: evalResults,
 	}, nil
 }
 
+// testCaseData returns the test case with the command line variables added as defaults, checked
+// against the variables declared by the prompt file
+func (h *evalCommandHandler) testCaseData(testCase map[string]interface{}) (map[string]interface{}, error) {
+	data := testCase
+	if len(h.templateVars) > 0 {
+		data = make(map[string]interface{}, len(h.templateVars)+len(testCase))
+		for key, value := range h.templateVars {
+			data[key] = value
+		}
+		for key, value := range testCase {
+			data[key] = value
+		}
+	}
+	return h.evalFile.ResolveVariables(data)
+}
+
 func (h *evalCommandHandler) templateMessages(testCase map[string]interface{}) ([]azuremodels.ChatMessage, error) {
 	var messages []azuremodels.ChatMessage
 
@@ -364,6 +854,9 @@ func (h *evalCommandHandler) templateMessages(testCase map[string]interface{}) (
 }
 
 func (h *evalCommandHandler) templateString(templateStr string, data map[string]interface{}) (string, error) {
+	if h.strictVars {
+		return prompt.TemplateString(templateStr, data, prompt.WithStrictVariables())
+	}
 	return prompt.TemplateString(templateStr, data)
 }
 
@@ -372,55 +865,70 @@ func (h *evalCommandHandler) callModelWithRetry(ctx context.Context, req azuremo
 	const maxRetries = 3
 
 	for attempt := 0; attempt <= maxRetries; attempt++ {
-		resp, err := h.client.GetChatCompletionStream(ctx, req, h.org)
+		// Wait for any rate limit that another request ran into
+		if err := h.rateLimit.wait(ctx); err != nil {
+			return "", err
+		}
+
+		content, err := h.requestCompletion(ctx, req)
 		if err != nil {
 			var rateLimitErr *azuremodels.RateLimitError
 			if errors.As(err, &rateLimitErr) {
 				if attempt < maxRetries {
-					if !h.jsonOutput {
-						h.cfg.WriteToOut(fmt.Sprintf("    Rate limited, waiting %v before retry (attempt %d/%d)...\n",
-							rateLimitErr.RetryAfter, attempt+1, maxRetries+1))
-					}
-
-					// Wait for the specified duration
-					select {
-					case <-ctx.Done():
-						return "", ctx.Err()
-					case <-time.After(rateLimitErr.RetryAfter):
-						continue
-					}
+					h.printProgress(fmt.Sprintf("    Rate limited, waiting %v before retry (attempt %d/%d)...\n",
+						rateLimitErr.RetryAfter, attempt+1, maxRetries+1))
+					h.rateLimit.closeFor(rateLimitErr.RetryAfter)
+					continue
 				}
 				return "", fmt.Errorf("rate limit exceeded after %d attempts: %w", attempt+1, err)
 			}
 			// For non-rate-limit errors, return immediately
 			return "", err
 		}
+		return content, nil
+	}
 
-		var content strings.Builder
-		for {
-			completion, err := resp.Reader.Read()
-			if err != nil {
-				if errors.Is(err, context.Canceled) || strings.Contains(err.Error(), "EOF") {
-					break
-				}
-				return "", err
-			}
+	// This should never be reached, but just in case
+	return "", errors.New("unexpected error calling model")
+}
 
-			for _, choice := range completion.Choices {
-				if choice.Delta != nil && choice.Delta.Content != nil {
-					content.WriteString(*choice.Delta.Content)
-				}
-				if choice.Message != nil && choice.Message.Content != nil {
-					content.WriteString(*choice.Message.Content)
-				}
+// requestCompletion sends a single request and reads the whole response
+func (h *evalCommandHandler) requestCompletion(ctx context.Context, req azuremodels.ChatCompletionOptions) (string, error) {
+	if h.requests != nil {
+		select {
+		case h.requests <- struct{}{}:
+			defer func() { <-h.requests }()
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	resp, err := h.client.GetChatCompletionStream(ctx, req, h.org)
+	if err != nil {
+		return "", err
+	}
+
+	var content strings.Builder
+	for {
+		completion, err := resp.Reader.Read()
+		if err != nil {
+			if errors.Is(err, context.Canceled) || strings.Contains(err.Error(), "EOF") {
+				break
 			}
+			return "", err
 		}
 
-		return strings.TrimSpace(content.String()), nil
+		for _, choice := range completion.Choices {
+			if choice.Delta != nil && choice.Delta.Content != nil {
+				content.WriteString(*choice.Delta.Content)
+			}
+			if choice.Message != nil && choice.Message.Content != nil {
+				content.WriteString(*choice.Message.Content)
+			}
+		}
 	}
 
-	// This should never be reached, but just in case
-	return "", errors.New("unexpected error calling model")
+	return strings.TrimSpace(content.String()), nil
 }
 
 func (h *evalCommandHandler) callModel(ctx context.Context, messages []azuremodels.ChatMessage) (string, error) {
@@ -428,7 +936,58 @@ func (h *evalCommandHandler) callModel(ctx context.Context, messages []azuremode
 	return h.callModelWithRetry(ctx, req)
 }
 
+// repairResponse validates the response against the prompt file's JSON schema and asks the model
+// to fix it up to schemaRetries times. It returns the last response and its remaining violations.
+func (h *evalCommandHandler) repairResponse(ctx context.Context, messages []azuremodels.ChatMessage, response string) (string, []prompt.SchemaViolation, error) {
+	for attempt := 0; ; attempt++ {
+		violations := h.evalFile.ValidateResponse(response)
+		if len(violations) == 0 || attempt >= h.schemaRetries {
+			return response, violations, nil
+		}
+
+		h.printProgress(fmt.Sprintf("    Response does not match the JSON schema, asking the model to fix it (attempt %d/%d)...\n",
+			attempt+1, h.schemaRetries))
+
+		messages = append(messages[:len(messages):len(messages)],
+			azuremodels.ChatMessage{Role: azuremodels.ChatMessageRoleAssistant, Content: util.Ptr(response)},
+			azuremodels.ChatMessage{Role: azuremodels.ChatMessageRoleUser, Content: util.Ptr(prompt.SchemaRepairPrompt(violations))},
+		)
+
+		var err error
+		response, err = h.callModel(ctx, messages)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+}
+
+func newSchemaEvaluationResult(violations []prompt.SchemaViolation) *EvaluationResult {
+	if len(violations) == 0 {
+		return &EvaluationResult{
+			EvaluatorName: schemaEvaluatorName,
+			Score:         1.0,
+			Passed:        true,
+			Details:       "Response matches the JSON schema",
+		}
+	}
+
+	details := make([]string, len(violations))
+	for i, v := range violations {
+		details[i] = v.String()
+	}
+	return &EvaluationResult{
+		EvaluatorName: schemaEvaluatorName,
+		Score:         0.0,
+		Passed:        false,
+		Details:       strings.Join(details, "; "),
+	}
+}
+
 func (h *evalCommandHandler) runEvaluators(ctx context.Context, testCase map[string]interface{}, response string) ([]EvaluationResult, error) {
+	if h.concurrency > 1 && len(h.evalFile.Evaluators) > 1 {
+		return h.runEvaluatorsConcurrently(ctx, testCase, response)
+	}
+
 	var results []EvaluationResult
 
 	for _, evaluator := range h.evalFile.Evaluators {
@@ -442,12 +1001,40 @@ func (h *evalCommandHandler) runEvaluators(ctx context.Context, testCase map[str
 	return results, nil
 }
 
+// runEvaluatorsConcurrently runs every evaluator at the same time, and returns their results in order
+func (h *evalCommandHandler) runEvaluatorsConcurrently(ctx context.Context, testCase map[string]interface{}, response string) ([]EvaluationResult, error) {
+	evaluators := h.evalFile.Evaluators
+	results := make([]EvaluationResult, len(evaluators))
+	errs := make([]error, len(evaluators))
+
+	var wg sync.WaitGroup
+	for i, evaluator := range evaluators {
+		wg.Add(1)
+		go func(i int, evaluator prompt.Evaluator) {
+			defer wg.Done()
+			results[i], errs[i] = h.runSingleEvaluator(ctx, evaluator, testCase, response)
+		}(i, evaluator)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("evaluator %s failed: %w", evaluators[i].Name, err)
+		}
+	}
+	return results, nil
+}
+
 func (h *evalCommandHandler) runSingleEvaluator(ctx context.Context, evaluator prompt.Evaluator, testCase map[string]interface{}, response string) (EvaluationResult, error) {
 	switch {
 	case evaluator.String != nil:
 		return h.runStringEvaluator(evaluator.Name, *evaluator.String, testCase, response)
 	case evaluator.LLM != nil:
 		return h.runLLMEvaluator(ctx, evaluator.Name, *evaluator.LLM, testCase, response)
+	case evaluator.JSON != nil:
+		return h.runJSONEvaluator(evaluator.Name, *evaluator.JSON, testCase, response)
+	case evaluator.Uses != "" && prompt.IsCommandPlugin(evaluator.Uses):
+		return h.runCommandPlugin(ctx, evaluator, testCase, response)
 	case evaluator.Uses != "":
 		return h.runPluginEvaluator(ctx, evaluator.Name, evaluator.Uses, testCase, response)
 	default:
@@ -455,56 +1042,6 @@ func (h *evalCommandHandler) runSingleEvaluator(ctx context.Context, evaluator p
 	}
 }
 
-func (h *evalCommandHandler) runStringEvaluator(name string, eval prompt.StringEvaluator, testCase map[string]interface{}, response string) (EvaluationResult, error) {
-	var passed bool
-	var details string
-
-	switch {
-	case eval.Equals != "":
-		equals, err := h.templateString(eval.Equals, testCase)
-		if err != nil {
-			return EvaluationResult{}, fmt.Errorf("failed to template message content: %w", err)
-		}
-		passed = response == equals
-		details = fmt.Sprintf("Expected exact match: '%s'", equals)
-	case eval.Contains != "":
-		contains, err := h.templateString(eval.Contains, testCase)
-		if err != nil {
-			return EvaluationResult{}, fmt.Errorf("failed to template message content: %w", err)
-		}
-		passed = strings.Contains(strings.ToLower(response), strings.ToLower(contains))
-		details = fmt.Sprintf("Expected to contain: '%s'", contains)
-	case eval.StartsWith != "":
-		startsWith, err := h.templateString(eval.StartsWith, testCase)
-		if err != nil {
-			return EvaluationResult{}, fmt.Errorf("failed to template message content: %w", err)
-		}
-		passed = strings.HasPrefix(strings.ToLower(response), strings.ToLower(startsWith))
-		details = fmt.Sprintf("Expected to start with: '%s'", startsWith)
-	case eval.EndsWith != "":
-		endsWith, err := h.templateString(eval.EndsWith, testCase)
-		if err != nil {
-			return EvaluationResult{}, fmt.Errorf("failed to template message content: %w", err)
-		}
-		passed = strings.HasSuffix(strings.ToLower(response), strings.ToLower(endsWith))
-		details = fmt.Sprintf("Expected to end with: '%s'", endsWith)
-	default:
-		return EvaluationResult{}, errors.New("no string evaluation criteria specified")
-	}
-
-	score := 0.0
-	if passed {
-		score = 1.0
-	}
-
-	return EvaluationResult{
-		EvaluatorName: name,
-		Score:         score,
-		Passed:        passed,
-		Details:       details,
-	}, nil
-}
-
 func (h *evalCommandHandler) runLLMEvaluator(ctx context.Context, name string, eval prompt.LLMEvaluator, testCase map[string]interface{}, response string) (EvaluationResult, error) {
 	evalData := make(map[string]interface{})
 	for k, v := range testCase {
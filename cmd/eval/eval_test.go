@@ -4,14 +4,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/github/gh-models/internal/azuremodels"
 	"github.com/github/gh-models/internal/sse"
 	"github.com/github/gh-models/pkg/command"
 	"github.com/github/gh-models/pkg/prompt"
+	"github.com/github/gh-models/pkg/util"
 	"github.com/stretchr/testify/require"
 )
 
@@ -140,6 +145,79 @@ evaluators:
 				expected:  false,
 				variables: map[string]interface{}{"expected": "goodbye"},
 			},
+			{
+				name:      "contains is case insensitive by default",
+				evaluator: prompt.StringEvaluator{Contains: "WORLD"},
+				response:  "hello world",
+				expected:  true,
+			},
+			{
+				name:      "caseSensitive applies to contains",
+				evaluator: prompt.StringEvaluator{Contains: "WORLD", CaseSensitive: util.Ptr(true)},
+				response:  "hello world",
+				expected:  false,
+			},
+			{
+				name:      "caseSensitive false applies to equals",
+				evaluator: prompt.StringEvaluator{Equals: "Hello World", CaseSensitive: util.Ptr(false)},
+				response:  "hello world",
+				expected:  true,
+			},
+			{
+				name:      "normalizeWhitespace",
+				evaluator: prompt.StringEvaluator{Equals: "hello world", NormalizeWhitespace: true},
+				response:  "hello \n\t world",
+				expected:  true,
+			},
+			{
+				name:      "not contains",
+				evaluator: prompt.StringEvaluator{NotContains: "sorry"},
+				response:  "Sorry, I cannot help",
+				expected:  false,
+			},
+			{
+				name:      "contains any",
+				evaluator: prompt.StringEvaluator{ContainsAny: []string{"yes", "{{expected}}"}},
+				response:  "Indeed",
+				expected:  true,
+				variables: map[string]interface{}{"expected": "indeed"},
+			},
+			{
+				name:      "contains all",
+				evaluator: prompt.StringEvaluator{ContainsAll: []string{"apples", "pears"}},
+				response:  "apples and oranges",
+				expected:  false,
+			},
+			{
+				name:      "regex",
+				evaluator: prompt.StringEvaluator{Regex: `^\d{3}-\d{4}$`},
+				response:  "555-1234",
+				expected:  true,
+			},
+			{
+				name:      "length bounds",
+				evaluator: prompt.StringEvaluator{MinLength: util.Ptr(3), MaxLength: util.Ptr(5)},
+				response:  "héllo",
+				expected:  true,
+			},
+			{
+				name:      "line count",
+				evaluator: prompt.StringEvaluator{MaxLines: util.Ptr(2)},
+				response:  "one\ntwo\nthree",
+				expected:  false,
+			},
+			{
+				name:      "all criteria must pass by default",
+				evaluator: prompt.StringEvaluator{StartsWith: "hello", EndsWith: "there"},
+				response:  "hello world",
+				expected:  false,
+			},
+			{
+				name:      "any criterion passes with mode any",
+				evaluator: prompt.StringEvaluator{StartsWith: "hello", EndsWith: "there", Mode: prompt.StringModeAny},
+				response:  "hello world",
+				expected:  true,
+			},
 		}
 
 		for _, tt := range tests {
@@ -156,6 +234,94 @@ evaluators:
 		}
 	})
 
+	t.Run("string evaluator reports each criterion", func(t *testing.T) {
+		handler := &evalCommandHandler{}
+		evaluator := prompt.StringEvaluator{Contains: "hello", MaxLines: util.Ptr(1), NotContains: "sorry"}
+
+		result, err := handler.runStringEvaluator("test", evaluator, nil, "hello\nsorry")
+		require.NoError(t, err)
+		require.False(t, result.Passed)
+		require.Equal(t, "✓ Expected to contain: 'hello'; ✗ Expected not to contain: 'sorry'; ✗ Expected at most 1 lines, got 2", result.Details)
+
+		_, err = handler.runStringEvaluator("test", prompt.StringEvaluator{Regex: "("}, nil, "hello")
+		require.ErrorContains(t, err, "invalid regex '('")
+	})
+
+	t.Run("json evaluator works correctly", func(t *testing.T) {
+		handler := &evalCommandHandler{}
+		schema := &prompt.JsonSchema{Parsed: map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"status"},
+		}}
+
+		tests := []struct {
+			name      string
+			evaluator prompt.JSONEvaluator
+			response  string
+			expected  bool
+			details   string
+			variables map[string]interface{}
+		}{
+			{
+				name:     "valid JSON",
+				response: "```json\n{\"status\": \"ok\"}\n```",
+				expected: true,
+				details:  "Response is valid JSON",
+			},
+			{
+				name:     "invalid JSON",
+				response: "status: ok",
+				expected: false,
+				details:  "Response is not valid JSON: invalid character 's' looking for beginning of value",
+			},
+			{
+				name:      "schema",
+				evaluator: prompt.JSONEvaluator{Schema: schema},
+				response:  `{"state": "ok"}`,
+				expected:  false,
+				details:   "$: missing required property \"status\"",
+			},
+			{
+				name:      "equals ignores key order and whitespace",
+				evaluator: prompt.JSONEvaluator{Equals: "{{expected}}"},
+				response:  `{"b": [1, 2], "a": "x"}`,
+				expected:  true,
+				details:   `Expected $ to equal: '{"a":"x","b":[1,2]}'`,
+				variables: map[string]interface{}{"expected": map[string]interface{}{"a": "x", "b": []interface{}{1, 2}}},
+			},
+			{
+				name:      "equals a string at a path",
+				evaluator: prompt.JSONEvaluator{Path: "$.items[0].name", Equals: "apple"},
+				response:  `{"items": [{"name": "pear"}]}`,
+				expected:  false,
+				details:   "Expected $.items[0].name to equal: 'apple', got: 'pear'",
+			},
+			{
+				name:      "missing path",
+				evaluator: prompt.JSONEvaluator{Path: "$.items[1]", Contains: "pear"},
+				response:  `{"items": [{"name": "pear"}]}`,
+				expected:  false,
+				details:   "No value at $.items[1]",
+			},
+			{
+				name:      "contains and regex",
+				evaluator: prompt.JSONEvaluator{Path: "$.items[*].name", Contains: "PEAR", Regex: `^\["\w+","\w+"\]$`},
+				response:  `{"items": [{"name": "apple"}, {"name": "pear"}]}`,
+				expected:  true,
+				details:   `✓ Expected $.items[*].name to contain: 'PEAR'; ✓ Expected $.items[*].name to match regex: '^\["\w+","\w+"\]$'`,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				result, err := handler.runJSONEvaluator("test", tt.evaluator, tt.variables, tt.response)
+				require.NoError(t, err)
+				require.Equal(t, tt.expected, result.Passed)
+				require.Equal(t, tt.details, result.Details)
+			})
+		}
+	})
+
 	t.Run("plugin evaluator works with github/similarity", func(t *testing.T) {
 		out := new(bytes.Buffer)
 		client := azuremodels.NewMockClient()
@@ -631,4 +797,478 @@ evaluators:
 		require.Contains(t, output, "✓ PASSED")
 		require.Contains(t, output, "🎉 All tests passed!")
 	})
+
+	t.Run("eval validates responses against the JSON schema", func(t *testing.T) {
+		const yamlBody = `
+name: JSON Schema Evaluation
+model: openai/gpt-4o
+responseFormat: json_schema
+jsonSchema: '{"name": "response_schema", "schema": {"type": "object", "properties": {"message": {"type": "string"}}, "required": ["message"], "additionalProperties": false}}'
+testData:
+  - input: "hello"
+messages:
+  - role: user
+    content: "Respond to: {{input}}"
+evaluators:
+  - name: contains-hello
+    string:
+      contains: "hello"
+`
+
+		promptFile := filepath.Join(t.TempDir(), "test.prompt.yml")
+		require.NoError(t, os.WriteFile(promptFile, []byte(yamlBody), 0644))
+
+		newClient := func(replies ...string) (*azuremodels.MockClient, *int) {
+			client := azuremodels.NewMockClient()
+			calls := 0
+			client.MockGetChatCompletionStream = func(ctx context.Context, req azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+				reply := replies[calls]
+				calls++
+				return &azuremodels.ChatCompletionResponse{
+					Reader: sse.NewMockEventReader([]azuremodels.ChatCompletion{
+						{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: util.Ptr(reply)}}}},
+					}),
+				}, nil
+			}
+			return client, &calls
+		}
+
+		t.Run("reports violations", func(t *testing.T) {
+			client, calls := newClient(`{"message": "hello", "extra": true}`)
+			out := new(bytes.Buffer)
+			cmd := NewEvalCommand(command.NewConfig(out, out, client, true, 100))
+			cmd.SetArgs([]string{"--json", promptFile})
+
+			err := cmd.Execute()
+			require.ErrorIs(t, err, FailedTests)
+			require.Equal(t, 1, *calls)
+
+			var summary EvaluationSummary
+			require.NoError(t, json.Unmarshal(out.Bytes(), &summary))
+			results := summary.TestResults[0].EvaluationResults
+			require.Len(t, results, 2)
+			require.Equal(t, "json-schema", results[0].EvaluatorName)
+			require.False(t, results[0].Passed)
+			require.Equal(t, `$.extra: additional property "extra" is not allowed`, results[0].Details)
+			require.True(t, results[1].Passed)
+		})
+
+		t.Run("--schema-retries repairs the response", func(t *testing.T) {
+			client, calls := newClient(`hello`, `{"message": "hello"}`)
+			out := new(bytes.Buffer)
+			cmd := NewEvalCommand(command.NewConfig(out, out, client, true, 100))
+			cmd.SetArgs([]string{"--schema-retries", "1", promptFile})
+
+			err := cmd.Execute()
+			require.NoError(t, err, out.String())
+			require.Equal(t, 2, *calls)
+			require.Contains(t, out.String(), "asking the model to fix it (attempt 1/1)")
+			require.Contains(t, out.String(), "🎉 All tests passed!")
+		})
+	})
+
+	t.Run("eval uses --var and --var-file values as defaults for test cases", func(t *testing.T) {
+		const yamlBody = `
+name: Variables Evaluation
+model: openai/gpt-4o
+testData:
+  - input: "first"
+  - input: "second"
+    tone: "formal"
+messages:
+  - role: system
+    content: "Use a {{tone}} tone for {{team}}."
+  - role: user
+    content: "{{input}}"
+evaluators:
+  - name: mentions-team
+    string:
+      contains: "{{team}}"
+`
+
+		dir := t.TempDir()
+		promptFile := filepath.Join(dir, "test.prompt.yml")
+		require.NoError(t, os.WriteFile(promptFile, []byte(yamlBody), 0644))
+		varFile := filepath.Join(dir, "vars.yml")
+		require.NoError(t, os.WriteFile(varFile, []byte("team: docs\ntone: casual\n"), 0644))
+
+		client := azuremodels.NewMockClient()
+		var systemPrompts []string
+		client.MockGetChatCompletionStream = func(ctx context.Context, req azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+			systemPrompts = append(systemPrompts, *req.Messages[0].Content)
+			return &azuremodels.ChatCompletionResponse{
+				Reader: sse.NewMockEventReader([]azuremodels.ChatCompletion{
+					{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: util.Ptr("Hi from the support team")}}}},
+				}),
+			}, nil
+		}
+
+		out := new(bytes.Buffer)
+		cmd := NewEvalCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--json", "--var-file", varFile, "--var", "team=support", promptFile})
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+		require.Equal(t, []string{"Use a casual tone for support.", "Use a formal tone for support."}, systemPrompts)
+
+		var summary EvaluationSummary
+		require.NoError(t, json.Unmarshal(out.Bytes(), &summary))
+		require.Equal(t, 2, summary.Summary.PassedTests)
+		require.Equal(t, map[string]interface{}{"input": "first"}, summary.TestResults[0].TestCase)
+	})
+
+	t.Run("eval checks every test case against the declared variables", func(t *testing.T) {
+		const yamlBody = `
+name: Declared Variables
+model: openai/gpt-4o
+variables:
+  - name: input
+    required: true
+  - name: count
+    type: integer
+    default: 1
+testData:
+  - input: "first"
+  - count: 2
+messages:
+  - role: user
+    content: "{{input}} x{{count}}"
+`
+
+		promptFile := filepath.Join(t.TempDir(), "test.prompt.yml")
+		require.NoError(t, os.WriteFile(promptFile, []byte(yamlBody), 0644))
+
+		client := azuremodels.NewMockClient()
+		calls := 0
+		client.MockGetChatCompletionStream = func(ctx context.Context, req azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+			calls++
+			return nil, errors.New("unexpected call")
+		}
+
+		out := new(bytes.Buffer)
+		cmd := NewEvalCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{promptFile})
+
+		err := cmd.Execute()
+		require.EqualError(t, err, "test case 2: invalid template variables: missing required variable 'input'")
+		require.Zero(t, calls)
+	})
+
+	t.Run("--concurrency reports test cases in order", func(t *testing.T) {
+		const yamlBody = `
+name: Concurrent Test
+model: openai/gpt-4o
+testData:
+  - input: "first"
+  - input: "second"
+  - input: "third"
+messages:
+  - role: user
+    content: "{{input}}"
+evaluators:
+  - name: echoes
+    string:
+      contains: "{{input}}"
+  - name: short
+    string:
+      startsWith: "echo"
+`
+
+		promptFile := filepath.Join(t.TempDir(), "test.prompt.yml")
+		require.NoError(t, os.WriteFile(promptFile, []byte(yamlBody), 0644))
+
+		// The first test case is rate limited once, and the later test cases respond sooner, so
+		// the responses arrive out of order
+		delays := map[string]time.Duration{"first": 60 * time.Millisecond, "second": 30 * time.Millisecond}
+		var mu sync.Mutex
+		rateLimited := false
+		newClient := func() *azuremodels.MockClient {
+			rateLimited = false
+			client := azuremodels.NewMockClient()
+			client.MockGetChatCompletionStream = func(ctx context.Context, req azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+				input := *req.Messages[0].Content
+				mu.Lock()
+				limit := input == "first" && !rateLimited
+				rateLimited = rateLimited || limit
+				mu.Unlock()
+				if limit {
+					return nil, &azuremodels.RateLimitError{RetryAfter: 10 * time.Millisecond, Message: "slow down"}
+				}
+
+				time.Sleep(delays[input])
+				response := "echo " + input
+				return &azuremodels.ChatCompletionResponse{
+					Reader: sse.NewMockEventReader([]azuremodels.ChatCompletion{
+						{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: &response}}}},
+					}),
+				}, nil
+			}
+			return client
+		}
+
+		out := new(bytes.Buffer)
+		cmd := NewEvalCommand(command.NewConfig(out, out, newClient(), true, 100))
+		cmd.SetArgs([]string{"--concurrency", "3", promptFile})
+		require.NoError(t, cmd.Execute())
+
+		output := out.String()
+		require.Contains(t, output, "Rate limited, waiting 10ms before retry (attempt 1/4)...")
+		first := strings.Index(output, "Running test case 1/3...")
+		second := strings.Index(output, "Running test case 2/3...")
+		third := strings.Index(output, "Running test case 3/3...")
+		require.True(t, first >= 0 && first < second && second < third, output)
+		require.Contains(t, output, "Passed: 3/3 (100.00%)")
+
+		out.Reset()
+		cmd = NewEvalCommand(command.NewConfig(out, out, newClient(), true, 100))
+		cmd.SetArgs([]string{"--json", "--concurrency", "3", promptFile})
+		require.NoError(t, cmd.Execute())
+
+		var result EvaluationSummary
+		require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+		require.Len(t, result.TestResults, 3)
+		for i, input := range []string{"first", "second", "third"} {
+			require.Equal(t, "echo "+input, result.TestResults[i].ModelResponse)
+			require.Equal(t, "echoes", result.TestResults[i].EvaluationResults[0].EvaluatorName)
+			require.Equal(t, "short", result.TestResults[i].EvaluationResults[1].EvaluatorName)
+		}
+	})
+
+	t.Run("--concurrency stops at the first failed test case", func(t *testing.T) {
+		const yamlBody = `
+name: Concurrent Failure
+model: openai/gpt-4o
+testData:
+  - input: "first"
+  - input: "second"
+messages:
+  - role: user
+    content: "{{input}}"
+`
+
+		promptFile := filepath.Join(t.TempDir(), "test.prompt.yml")
+		require.NoError(t, os.WriteFile(promptFile, []byte(yamlBody), 0644))
+
+		client := azuremodels.NewMockClient()
+		client.MockGetChatCompletionStream = func(ctx context.Context, req azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+			if *req.Messages[0].Content == "second" {
+				return nil, errors.New("boom")
+			}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+
+		out := new(bytes.Buffer)
+		cmd := NewEvalCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--concurrency", "2", promptFile})
+		require.EqualError(t, cmd.Execute(), "test case 2 failed: failed to call model: boom")
+	})
+
+	t.Run("--model evaluates the prompt with each model", func(t *testing.T) {
+		const yamlBody = `
+name: Model Matrix
+model: openai/gpt-4o
+models:
+  - openai/gpt-4o-mini
+  - openai/gpt-4.1
+testData:
+  - input: "hello"
+  - input: "bye"
+messages:
+  - role: user
+    content: "{{input}}"
+evaluators:
+  - name: echoes
+    string:
+      contains: "{{input}}"
+`
+
+		promptFile := filepath.Join(t.TempDir(), "test.prompt.yml")
+		require.NoError(t, os.WriteFile(promptFile, []byte(yamlBody), 0644))
+
+		// The mini model only answers the first test case correctly
+		client := azuremodels.NewMockClient()
+		client.MockGetChatCompletionStream = func(ctx context.Context, req azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+			response := *req.Messages[0].Content
+			if req.Model == "openai/gpt-4o-mini" && response == "bye" {
+				response = "see you"
+			}
+			return &azuremodels.ChatCompletionResponse{
+				Reader: sse.NewMockEventReader([]azuremodels.ChatCompletion{
+					{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: &response}}}},
+				}),
+			}, nil
+		}
+
+		t.Run("from the prompt file", func(t *testing.T) {
+			out := new(bytes.Buffer)
+			cmd := NewEvalCommand(command.NewConfig(out, out, client, false, 100))
+			cmd.SetArgs([]string{promptFile})
+			require.ErrorIs(t, cmd.Execute(), FailedTests)
+
+			output := out.String()
+			require.Contains(t, output, "Models: openai/gpt-4o-mini, openai/gpt-4.1\n")
+			require.Contains(t, output, "Model: openai/gpt-4o-mini\n")
+			require.Contains(t, output, "Model: openai/gpt-4.1\n")
+			require.True(t, strings.HasSuffix(output, "Evaluation Summary:\nPassed\t1/2 (50.00%)\t2/2 (100.00%)\nechoes\t0.50\t1.00\n"), output)
+		})
+
+		t.Run("--json groups the results by model", func(t *testing.T) {
+			out := new(bytes.Buffer)
+			cmd := NewEvalCommand(command.NewConfig(out, out, client, false, 100))
+			cmd.SetArgs([]string{"--json", "--model", "openai/gpt-4.1", "--model", "openai/gpt-4o-mini", promptFile})
+			require.ErrorIs(t, cmd.Execute(), FailedTests)
+
+			var matrix MatrixSummary
+			require.NoError(t, json.Unmarshal(out.Bytes(), &matrix))
+			require.Equal(t, "Model Matrix", matrix.Name)
+			require.Len(t, matrix.Models, 2)
+			require.Equal(t, "openai/gpt-4.1", matrix.Models[0].Model)
+			require.Equal(t, 2, matrix.Models[0].Summary.PassedTests)
+			require.Equal(t, "openai/gpt-4o-mini", matrix.Models[1].Model)
+			require.Equal(t, 1, matrix.Models[1].Summary.PassedTests)
+			require.Equal(t, "see you", matrix.Models[1].TestResults[1].ModelResponse)
+		})
+
+		t.Run("a single --model replaces the prompt file's model", func(t *testing.T) {
+			out := new(bytes.Buffer)
+			cmd := NewEvalCommand(command.NewConfig(out, out, client, false, 100))
+			cmd.SetArgs([]string{"--json", "--model", "openai/gpt-4.1", promptFile})
+			require.NoError(t, cmd.Execute())
+
+			var summary EvaluationSummary
+			require.NoError(t, json.Unmarshal(out.Bytes(), &summary))
+			require.Equal(t, "openai/gpt-4.1", summary.Model)
+			require.Len(t, summary.TestResults, 2)
+		})
+	})
+
+	t.Run("evaluators run commands with --allow-exec", func(t *testing.T) {
+		const yamlBody = `
+name: Command Evaluator
+model: openai/gpt-4o
+testData:
+  - input: "hello"
+messages:
+  - role: user
+    content: "{{input}}"
+evaluators:
+  - name: check
+    uses: ./evaluators/check.sh --strict
+    with:
+      minWords: 3
+`
+
+		dir := t.TempDir()
+		promptFile := filepath.Join(dir, "test.prompt.yml")
+		require.NoError(t, os.WriteFile(promptFile, []byte(yamlBody), 0644))
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "evaluators"), 0755))
+		// The command saves its input in the working directory, which is the prompt file's directory
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "evaluators", "check.sh"), []byte(`#!/bin/sh
+cat > input.json
+echo '{"score": 0.5, "details": "checked with '"$1"'"}'
+`), 0755))
+
+		client := azuremodels.NewMockClient()
+		client.MockGetChatCompletionStream = func(ctx context.Context, req azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+			response := "hi there"
+			return &azuremodels.ChatCompletionResponse{
+				Reader: sse.NewMockEventReader([]azuremodels.ChatCompletion{
+					{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: &response}}}},
+				}),
+			}, nil
+		}
+
+		t.Run("without --allow-exec", func(t *testing.T) {
+			out := new(bytes.Buffer)
+			cmd := NewEvalCommand(command.NewConfig(out, out, client, true, 100))
+			cmd.SetArgs([]string{promptFile})
+			require.EqualError(t, cmd.Execute(), "evaluator 'check' runs the command './evaluators/check.sh --strict'; use --allow-exec to allow prompt files to run commands")
+		})
+
+		t.Run("with --allow-exec", func(t *testing.T) {
+			out := new(bytes.Buffer)
+			cmd := NewEvalCommand(command.NewConfig(out, out, client, true, 100))
+			cmd.SetArgs([]string{"--json", "--allow-exec", promptFile})
+			require.NoError(t, cmd.Execute())
+
+			var summary EvaluationSummary
+			require.NoError(t, json.Unmarshal(out.Bytes(), &summary))
+			require.Equal(t, []EvaluationResult{
+				{EvaluatorName: "check", Score: 0.5, Passed: true, Details: "checked with --strict"},
+			}, summary.TestResults[0].EvaluationResults)
+
+			input, err := os.ReadFile(filepath.Join(dir, "input.json"))
+			require.NoError(t, err)
+			require.JSONEq(t, `{
+				"evaluator": "check",
+				"testCase": {"input": "hello"},
+				"response": "hi there",
+				"config": {"minWords": 3}
+			}`, string(input))
+		})
+
+		t.Run("with a relative prompt file path", func(t *testing.T) {
+			wd, err := os.Getwd()
+			require.NoError(t, err)
+			require.NoError(t, os.Chdir(filepath.Dir(dir)))
+			t.Cleanup(func() { require.NoError(t, os.Chdir(wd)) })
+
+			out := new(bytes.Buffer)
+			cmd := NewEvalCommand(command.NewConfig(out, out, client, true, 100))
+			cmd.SetArgs([]string{"--json", "--allow-exec", filepath.Join(filepath.Base(dir), "test.prompt.yml")})
+			require.NoError(t, cmd.Execute())
+
+			var summary EvaluationSummary
+			require.NoError(t, json.Unmarshal(out.Bytes(), &summary))
+			require.Equal(t, "checked with --strict", summary.TestResults[0].EvaluationResults[0].Details)
+		})
+	})
+
+	t.Run("evaluator commands report failures", func(t *testing.T) {
+		handler := &evalCommandHandler{allowExec: true, pluginTimeout: 100 * time.Millisecond, promptDir: t.TempDir()}
+
+		tests := []struct {
+			uses     string
+			expected string
+		}{
+			{uses: `sh -c 'echo nope'`, expected: "evaluator 'check' wrote invalid JSON: invalid character 'o' in literal null (expecting 'u')"},
+			{uses: `sh -c 'echo {}'`, expected: "evaluator 'check' wrote neither a score nor passed"},
+			{uses: `sh -c 'echo "{\"score\": 2}"'`, expected: "evaluator 'check' wrote the score 2, which must be between 0 and 1"},
+			{uses: `sh -c 'echo broken >&2; exit 3'`, expected: "evaluator 'check' failed: exit status 3: broken"},
+			{uses: `sh -c 'exec sleep 5'`, expected: "evaluator 'check' timed out after 100ms"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.uses, func(t *testing.T) {
+				_, err := handler.runCommandPlugin(context.Background(), prompt.Evaluator{Name: "check", Uses: tt.uses}, nil, "response")
+				require.EqualError(t, err, tt.expected)
+			})
+		}
+	})
+
+	t.Run("--concurrency must be at least 1", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		cmd := NewEvalCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), true, 100))
+		cmd.SetArgs([]string{"--concurrency", "0", "test.prompt.yml"})
+		require.EqualError(t, cmd.Execute(), "--concurrency must be at least 1")
+	})
+}
+
+func TestRateLimitGate(t *testing.T) {
+	var gate rateLimitGate
+	require.NoError(t, gate.wait(context.Background()))
+
+	gate.closeFor(50 * time.Millisecond)
+	// A shorter rate limit does not open the gate sooner
+	gate.closeFor(time.Millisecond)
+
+	start := time.Now()
+	require.NoError(t, gate.wait(context.Background()))
+	require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	gate.closeFor(time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, gate.wait(ctx), context.Canceled)
 }
@@ -0,0 +1,130 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/github/gh-models/pkg/prompt"
+)
+
+// codeBlock matches a response that is a single Markdown code block, which models often wrap JSON in
+var codeBlock = regexp.MustCompile("(?s)^```[\\w-]*[ \\t]*\\n(.*?)\\n?```$")
+
+func (h *evalCommandHandler) runJSONEvaluator(name string, eval prompt.JSONEvaluator, testCase map[string]interface{}, response string) (EvaluationResult, error) {
+	path := "$"
+	var jsonPath *prompt.JSONPath
+	if eval.Path != "" {
+		var err error
+		jsonPath, err = prompt.ParseJSONPath(eval.Path)
+		if err != nil {
+			return EvaluationResult{}, err
+		}
+		path = eval.Path
+	}
+
+	var regex *regexp.Regexp
+	if eval.Regex != "" {
+		pattern, err := h.templateString(eval.Regex, testCase)
+		if err != nil {
+			return EvaluationResult{}, fmt.Errorf("failed to template message content: %w", err)
+		}
+		regex, err = regexp.Compile(pattern)
+		if err != nil {
+			return EvaluationResult{}, fmt.Errorf("invalid regex '%s': %w", pattern, err)
+		}
+	}
+
+	text := strings.TrimSpace(response)
+	if match := codeBlock.FindStringSubmatch(text); match != nil {
+		text = match[1]
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return EvaluationResult{
+			EvaluatorName: name,
+			Score:         0.0,
+			Passed:        false,
+			Details:       fmt.Sprintf("Response is not valid JSON: %v", err),
+		}, nil
+	}
+
+	var checks []criterionCheck
+	if eval.Schema != nil {
+		violations := prompt.ValidateJSONSchema(eval.Schema.Parsed, value)
+		check := criterionCheck{passed: len(violations) == 0, details: "Response matches the JSON schema"}
+		if len(violations) > 0 {
+			details := make([]string, len(violations))
+			for i, v := range violations {
+				details[i] = v.String()
+			}
+			check.details = strings.Join(details, "; ")
+		}
+		checks = append(checks, check)
+	}
+
+	selected := value
+	if jsonPath != nil {
+		var ok bool
+		if selected, ok = jsonPath.Select(value); !ok {
+			checks = append(checks, criterionCheck{passed: false, details: fmt.Sprintf("No value at %s", path)})
+			return newCriteriaResult(name, checks, false), nil
+		}
+	}
+	selectedText := jsonText(selected)
+
+	if eval.Equals != "" {
+		equals, err := h.templateString(eval.Equals, testCase)
+		if err != nil {
+			return EvaluationResult{}, fmt.Errorf("failed to template message content: %w", err)
+		}
+		// Compare as JSON when the expected value is JSON, so that key order and whitespace are ignored
+		var expected interface{}
+		passed := json.Unmarshal([]byte(equals), &expected) == nil && reflect.DeepEqual(expected, selected)
+		if s, ok := selected.(string); ok && s == equals {
+			passed = true
+		}
+		details := fmt.Sprintf("Expected %s to equal: '%s'", path, equals)
+		if !passed {
+			details += fmt.Sprintf(", got: '%s'", selectedText)
+		}
+		checks = append(checks, criterionCheck{passed: passed, details: details})
+	}
+
+	if eval.Contains != "" {
+		contains, err := h.templateString(eval.Contains, testCase)
+		if err != nil {
+			return EvaluationResult{}, fmt.Errorf("failed to template message content: %w", err)
+		}
+		checks = append(checks, criterionCheck{
+			passed:  strings.Contains(strings.ToLower(selectedText), strings.ToLower(contains)),
+			details: fmt.Sprintf("Expected %s to contain: '%s'", path, contains),
+		})
+	}
+
+	if regex != nil {
+		checks = append(checks, criterionCheck{
+			passed:  regex.MatchString(selectedText),
+			details: fmt.Sprintf("Expected %s to match regex: '%s'", path, regex.String()),
+		})
+	}
+
+	if len(checks) == 0 {
+		checks = append(checks, criterionCheck{passed: true, details: "Response is valid JSON"})
+	}
+	return newCriteriaResult(name, checks, false), nil
+}
+
+// jsonText returns a string value as it is, and any other value as compact JSON
+func jsonText(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
@@ -0,0 +1,122 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/github/gh-models/pkg/prompt"
+)
+
+// defaultPluginTimeout is how long an evaluator command can run before it is stopped
+const defaultPluginTimeout = 30 * time.Second
+
+// pluginInput is the JSON that an evaluator command reads from stdin
+type pluginInput struct {
+	Evaluator string                 `json:"evaluator"`
+	TestCase  map[string]interface{} `json:"testCase"`
+	Response  string                 `json:"response"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+// pluginOutput is the JSON that an evaluator command writes to stdout. A missing score is 1 when the
+// evaluation passed and 0 otherwise, and a missing passed is true when the score is above 0.
+type pluginOutput struct {
+	Score   *float64 `json:"score"`
+	Passed  *bool    `json:"passed"`
+	Details string   `json:"details"`
+}
+
+// checkPlugins returns an error for evaluators that run a command, unless commands are allowed
+func (h *evalCommandHandler) checkPlugins() error {
+	for _, evaluator := range h.evalFile.Evaluators {
+		if evaluator.Uses == "" || !prompt.IsCommandPlugin(evaluator.Uses) {
+			continue
+		}
+		if !h.allowExec {
+			return fmt.Errorf("evaluator '%s' runs the command '%s'; use --allow-exec to allow prompt files to run commands", evaluator.Name, evaluator.Uses)
+		}
+		if _, err := prompt.ParsePluginCommand(evaluator.Uses, h.promptDir); err != nil {
+			return fmt.Errorf("evaluator '%s': %w", evaluator.Name, err)
+		}
+	}
+	return nil
+}
+
+// runCommandPlugin runs the command of an evaluator in the directory of the prompt file. It writes the
+// test case, the response and the evaluator's with configuration to the command's stdin as JSON, and
+// reads the result from its stdout.
+func (h *evalCommandHandler) runCommandPlugin(ctx context.Context, evaluator prompt.Evaluator, testCase map[string]interface{}, response string) (EvaluationResult, error) {
+	if !h.allowExec {
+		return EvaluationResult{}, fmt.Errorf("evaluator '%s' runs a command, which needs --allow-exec", evaluator.Name)
+	}
+	args, err := prompt.ParsePluginCommand(evaluator.Uses, h.promptDir)
+	if err != nil {
+		return EvaluationResult{}, fmt.Errorf("evaluator '%s': %w", evaluator.Name, err)
+	}
+
+	input, err := json.Marshal(pluginInput{
+		Evaluator: evaluator.Name,
+		TestCase:  testCase,
+		Response:  response,
+		Config:    evaluator.With,
+	})
+	if err != nil {
+		return EvaluationResult{}, fmt.Errorf("failed to encode the input of evaluator '%s': %w", evaluator.Name, err)
+	}
+
+	timeout := h.pluginTimeout
+	if timeout <= 0 {
+		timeout = defaultPluginTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = h.promptDir
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Don't wait forever for processes the command started, which may keep its output open
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return EvaluationResult{}, fmt.Errorf("evaluator '%s' timed out after %s", evaluator.Name, timeout)
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return EvaluationResult{}, fmt.Errorf("evaluator '%s' failed: %w: %s", evaluator.Name, err, message)
+		}
+		return EvaluationResult{}, fmt.Errorf("evaluator '%s' failed: %w", evaluator.Name, err)
+	}
+
+	var output pluginOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return EvaluationResult{}, fmt.Errorf("evaluator '%s' wrote invalid JSON: %w", evaluator.Name, err)
+	}
+	if output.Score == nil && output.Passed == nil {
+		return EvaluationResult{}, fmt.Errorf("evaluator '%s' wrote neither a score nor passed", evaluator.Name)
+	}
+
+	result := EvaluationResult{EvaluatorName: evaluator.Name, Details: output.Details}
+	if output.Score != nil {
+		if *output.Score < 0 || *output.Score > 1 {
+			return EvaluationResult{}, fmt.Errorf("evaluator '%s' wrote the score %v, which must be between 0 and 1", evaluator.Name, *output.Score)
+		}
+		result.Score = *output.Score
+		result.Passed = result.Score > 0
+	}
+	if output.Passed != nil {
+		result.Passed = *output.Passed
+		if output.Score == nil && result.Passed {
+			result.Score = 1
+		}
+	}
+	return result, nil
+}
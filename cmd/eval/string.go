@@ -0,0 +1,204 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/github/gh-models/pkg/prompt"
+)
+
+// criterionCheck is the result of one criterion of a string or JSON evaluator
+type criterionCheck struct {
+	passed  bool
+	details string
+}
+
+func (h *evalCommandHandler) runStringEvaluator(name string, eval prompt.StringEvaluator, testCase map[string]interface{}, response string) (EvaluationResult, error) {
+	if eval.Mode != "" && eval.Mode != prompt.StringModeAll && eval.Mode != prompt.StringModeAny {
+		return EvaluationResult{}, fmt.Errorf("invalid mode '%s': must be %s or %s", eval.Mode, prompt.StringModeAll, prompt.StringModeAny)
+	}
+
+	text := response
+	if eval.NormalizeWhitespace {
+		text = normalizeWhitespace(text)
+	}
+
+	render := func(value string) (string, error) {
+		rendered, err := h.templateString(value, testCase)
+		if err != nil {
+			return "", fmt.Errorf("failed to template message content: %w", err)
+		}
+		if eval.NormalizeWhitespace {
+			rendered = normalizeWhitespace(rendered)
+		}
+		return rendered, nil
+	}
+	renderAll := func(values []string) ([]string, error) {
+		rendered := make([]string, len(values))
+		for i, value := range values {
+			var err error
+			if rendered[i], err = render(value); err != nil {
+				return nil, err
+			}
+		}
+		return rendered, nil
+	}
+
+	// fold lowercases a string for comparisons that are not case sensitive
+	fold := func(s string, caseSensitive bool) string {
+		if eval.CaseSensitive != nil {
+			caseSensitive = *eval.CaseSensitive
+		}
+		if caseSensitive {
+			return s
+		}
+		return strings.ToLower(s)
+	}
+
+	var checks []criterionCheck
+	compare := func(value string, caseSensitive bool, format string, test func(text, value string) bool) error {
+		if value == "" {
+			return nil
+		}
+		rendered, err := render(value)
+		if err != nil {
+			return err
+		}
+		checks = append(checks, criterionCheck{
+			passed:  test(fold(text, caseSensitive), fold(rendered, caseSensitive)),
+			details: fmt.Sprintf(format, rendered),
+		})
+		return nil
+	}
+
+	equals := func(text, value string) bool { return text == value }
+	notContains := func(text, value string) bool { return !strings.Contains(text, value) }
+	for _, err := range []error{
+		compare(eval.Equals, true, "Expected exact match: '%s'", equals),
+		compare(eval.Contains, false, "Expected to contain: '%s'", strings.Contains),
+		compare(eval.StartsWith, false, "Expected to start with: '%s'", strings.HasPrefix),
+		compare(eval.EndsWith, false, "Expected to end with: '%s'", strings.HasSuffix),
+		compare(eval.NotContains, false, "Expected not to contain: '%s'", notContains),
+	} {
+		if err != nil {
+			return EvaluationResult{}, err
+		}
+	}
+
+	for _, list := range []struct {
+		values []string
+		all    bool
+		format string
+	}{
+		{eval.ContainsAny, false, "Expected to contain any of: %s"},
+		{eval.ContainsAll, true, "Expected to contain all of: %s"},
+	} {
+		if len(list.values) == 0 {
+			continue
+		}
+		values, err := renderAll(list.values)
+		if err != nil {
+			return EvaluationResult{}, err
+		}
+		passed := list.all
+		quoted := make([]string, len(values))
+		for i, value := range values {
+			contains := strings.Contains(fold(text, false), fold(value, false))
+			if list.all {
+				passed = passed && contains
+			} else {
+				passed = passed || contains
+			}
+			quoted[i] = "'" + value + "'"
+		}
+		checks = append(checks, criterionCheck{passed: passed, details: fmt.Sprintf(list.format, strings.Join(quoted, ", "))})
+	}
+
+	if eval.Regex != "" {
+		pattern, err := h.templateString(eval.Regex, testCase)
+		if err != nil {
+			return EvaluationResult{}, fmt.Errorf("failed to template message content: %w", err)
+		}
+		expr := pattern
+		if eval.CaseSensitive != nil && !*eval.CaseSensitive {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return EvaluationResult{}, fmt.Errorf("invalid regex '%s': %w", pattern, err)
+		}
+		checks = append(checks, criterionCheck{passed: re.MatchString(text), details: fmt.Sprintf("Expected to match regex: '%s'", pattern)})
+	}
+
+	length := utf8.RuneCountInString(text)
+	if eval.MinLength != nil {
+		checks = append(checks, criterionCheck{passed: length >= *eval.MinLength, details: fmt.Sprintf("Expected at least %d characters, got %d", *eval.MinLength, length)})
+	}
+	if eval.MaxLength != nil {
+		checks = append(checks, criterionCheck{passed: length <= *eval.MaxLength, details: fmt.Sprintf("Expected at most %d characters, got %d", *eval.MaxLength, length)})
+	}
+
+	// Lines are counted before whitespace is normalized, since that joins them
+	lines := countLines(response)
+	if eval.MinLines != nil {
+		checks = append(checks, criterionCheck{passed: lines >= *eval.MinLines, details: fmt.Sprintf("Expected at least %d lines, got %d", *eval.MinLines, lines)})
+	}
+	if eval.MaxLines != nil {
+		checks = append(checks, criterionCheck{passed: lines <= *eval.MaxLines, details: fmt.Sprintf("Expected at most %d lines, got %d", *eval.MaxLines, lines)})
+	}
+
+	if len(checks) == 0 {
+		return EvaluationResult{}, errors.New("no string evaluation criteria specified")
+	}
+
+	return newCriteriaResult(name, checks, eval.Mode == prompt.StringModeAny), nil
+}
+
+// newCriteriaResult passes when all of the checks pass, or any of them in anyMode. The details
+// mark each check when there are several.
+func newCriteriaResult(name string, checks []criterionCheck, anyMode bool) EvaluationResult {
+	passed := !anyMode
+	details := make([]string, len(checks))
+	for i, check := range checks {
+		if anyMode {
+			passed = passed || check.passed
+		} else {
+			passed = passed && check.passed
+		}
+		mark := "✓"
+		if !check.passed {
+			mark = "✗"
+		}
+		details[i] = mark + " " + check.details
+	}
+
+	result := EvaluationResult{EvaluatorName: name, Passed: passed}
+	if passed {
+		result.Score = 1.0
+	}
+	switch {
+	case len(checks) == 1:
+		result.Details = checks[0].details
+	case anyMode:
+		result.Details = "Any of: " + strings.Join(details, "; ")
+	default:
+		result.Details = strings.Join(details, "; ")
+	}
+	return result
+}
+
+// normalizeWhitespace trims a string and replaces every run of whitespace in it with a single space
+func normalizeWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// countLines returns the number of lines of a response, which has none when it is empty
+func countLines(response string) int {
+	if response == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimRight(response, "\n"), "\n") + 1
+}
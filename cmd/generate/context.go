@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/github/gh-models/pkg/prompt"
@@ -14,11 +15,15 @@ func (h *generateCommandHandler) CreateContextFromPrompt() (*PromptPexContext, e
 
 	h.WriteStartBox("Prompt", h.promptFile)
 
-	prompt, err := prompt.LoadFromFile(h.promptFile)
+	prompt, err := prompt.LoadFromFile(h.promptFile, h.loadOptions()...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load prompt file: %w", err)
 	}
 
+	if err := h.checkTemplateVariables(prompt); err != nil {
+		return nil, err
+	}
+
 	// Compute the hash of the prompt (messages, model, model parameters)
 	promptHash, err := ComputePromptHash(prompt)
 	if err != nil {
@@ -130,3 +135,34 @@ func mergeContexts(existing *PromptPexContext, new *PromptPexContext) *PromptPex
 
 	return merged
 }
+
+// loadOptions returns the options used to load the prompt file
+func (h *generateCommandHandler) loadOptions() []prompt.LoadOption {
+	if h.allowEnv {
+		return []prompt.LoadOption{prompt.WithEnvInterpolation()}
+	}
+	return nil
+}
+
+// checkTemplateVariables checks the variables passed with --var against the variables declared by
+// the prompt file. The input variable is left out, since it is set by the generated tests.
+func (h *generateCommandHandler) checkTemplateVariables(pf *prompt.File) error {
+	declared := slices.DeleteFunc(slices.Clone(pf.Variables), func(v prompt.Variable) bool {
+		return v.Name == "input"
+	})
+
+	values := make(map[string]interface{}, len(h.templateVars))
+	for key, value := range h.templateVars {
+		values[key] = value
+	}
+	_, err := (&prompt.File{Variables: declared}).ResolveVariables(values)
+	return err
+}
+
+// templateOptions returns the options used to render the messages of the prompt file
+func (h *generateCommandHandler) templateOptions() []prompt.TemplateOption {
+	if h.strictVars {
+		return []prompt.TemplateOption{prompt.WithStrictVariables()}
+	}
+	return nil
+}
@@ -21,6 +21,8 @@ type generateCommandHandler struct {
 	org          string
 	sessionFile  *string
 	templateVars map[string]string
+	allowEnv     bool
+	strictVars   bool
 }
 
 // NewGenerateCommand returns a new command to generate tests using PromptPex.
@@ -39,6 +41,7 @@ func NewGenerateCommand(cfg *command.Config) *cobra.Command {
 			gh models generate --org my-org --groundtruth-model "openai/gpt-4.1" prompt.yml
 			gh models generate --session-file prompt.session.json prompt.yml
 			gh models generate --var name=Alice --var topic="machine learning" prompt.yml
+			gh models generate --var-file vars.yml --allow-env prompt.yml
 		`),
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -66,6 +69,9 @@ func NewGenerateCommand(cfg *command.Config) *cobra.Command {
 			// Get organization
 			org, _ := cmd.Flags().GetString("org")
 
+			allowEnv, _ := cmd.Flags().GetBool("allow-env")
+			strictVars, _ := cmd.Flags().GetBool("strict-vars")
+
 			// Get session-file flag
 			sessionFile, _ := cmd.Flags().GetString("session-file")
 
@@ -88,6 +94,8 @@ func NewGenerateCommand(cfg *command.Config) *cobra.Command {
 				org:          org,
 				sessionFile:  util.Ptr(sessionFile),
 				templateVars: templateVars,
+				allowEnv:     allowEnv,
+				strictVars:   strictVars,
 			}
 
 			// Create prompt context
@@ -119,7 +127,10 @@ func AddCommandLineFlags(cmd *cobra.Command) {
 	flags.String("effort", "", "Effort level (min, low, medium, high)")
 	flags.String("groundtruth-model", "", "Model to use for generating groundtruth outputs. Defaults to openai/gpt-4o. Use 'none' to disable groundtruth generation.")
 	flags.String("session-file", "", "Session file to load existing context from")
-	flags.StringArray("var", []string{}, "Template variables for prompt files (can be used multiple times: --var name=value)")
+	flags.StringArray("var", []string{}, "Template variables for prompt files (can be used multiple times: --var name=value, --var name=@file or --var name=- for stdin)")
+	flags.StringArray("var-file", []string{}, "Load template variables from a YAML, JSON or .env file (can be used multiple times)")
+	flags.Bool("allow-env", false, "Replace ${env:NAME} references in the prompt file with the values of environment variables")
+	flags.Bool("strict-vars", false, "Fail if the prompt file references a template variable that is not set")
 
 	// Custom instruction flags for each phase
 	flags.String("instruction-intent", "", "Custom system instruction for intent generation phase")
@@ -244,6 +244,36 @@ func TestGenerateCommandExecution(t *testing.T) {
 		require.Contains(t, err.Error(), "failed to create context")
 	})
 
+	t.Run("checks --var values against the declared variables", func(t *testing.T) {
+		const yamlBody = `
+name: Test Prompt
+model: openai/gpt-4o-mini
+variables:
+  - name: input
+    required: true
+  - name: audience
+    required: true
+messages:
+  - role: user
+    content: "Explain {{input}} to {{audience}}"
+`
+
+		promptFile := filepath.Join(t.TempDir(), "test.prompt.yml")
+		require.NoError(t, os.WriteFile(promptFile, []byte(yamlBody), 0644))
+
+		client := azuremodels.NewMockClient()
+		client.MockGetChatCompletionStream = func(ctx context.Context, opt azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+			return nil, errors.New("unexpected call")
+		}
+
+		out := new(bytes.Buffer)
+		cmd := NewGenerateCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{promptFile})
+
+		err := cmd.Execute()
+		require.EqualError(t, err, "failed to create context: invalid template variables: missing required variable 'audience'")
+	})
+
 	t.Run("handles LLM errors gracefully", func(t *testing.T) {
 		// Create test prompt file
 		const yamlBody = `
@@ -456,21 +456,26 @@ func (h *generateCommandHandler) runSingleTestWithContext(input string, modelNam
 	// Use the context if provided, otherwise use the stored context
 	messages := context.Prompt.Messages
 
-	// Build OpenAI messages from our messages format
-	openaiMessages := []azuremodels.ChatMessage{}
-	for _, msg := range messages {
-		templateData := make(map[string]interface{})
+	templateData := make(map[string]interface{})
 
-		// Add the input variable (backward compatibility)
-		templateData["input"] = input
+	// Add the input variable (backward compatibility)
+	templateData["input"] = input
 
-		// Add custom variables
-		for key, value := range h.templateVars {
-			templateData[key] = value
-		}
+	// Add custom variables
+	for key, value := range h.templateVars {
+		templateData[key] = value
+	}
+
+	templateData, err := context.Prompt.ResolveVariables(templateData)
+	if err != nil {
+		return "", err
+	}
 
+	// Build OpenAI messages from our messages format
+	openaiMessages := []azuremodels.ChatMessage{}
+	for _, msg := range messages {
 		// Replace template variables in content
-		content, err := prompt.TemplateString(msg.Content, templateData)
+		content, err := prompt.TemplateString(msg.Content, templateData, h.templateOptions()...)
 		if err != nil {
 			return "", fmt.Errorf("failed to render message content: %w", err)
 		}
@@ -552,19 +557,36 @@ func (h *generateCommandHandler) updatePromptFile(context *PromptPexContext) err
 	context.Prompt.TestData = testData
 
 	// insert output rule evaluator
-	if context.Prompt.Evaluators == nil {
-		context.Prompt.Evaluators = make([]prompt.Evaluator, 0)
-	}
 	evaluator := h.GenerateRulesEvaluator(context)
-	context.Prompt.Evaluators = slices.DeleteFunc(context.Prompt.Evaluators, func(e prompt.Evaluator) bool {
-		return e.Name == evaluator.Name
-	})
-	context.Prompt.Evaluators = append(context.Prompt.Evaluators, evaluator)
+	context.Prompt.Evaluators = replaceEvaluator(context.Prompt.Evaluators, evaluator)
+
+	target := context.Prompt
+	if h.allowEnv {
+		// Update the file as written, so that the values of environment variables are not saved to it
+		raw, err := prompt.LoadFromFile(h.promptFile)
+		if err != nil {
+			return fmt.Errorf("failed to load prompt file: %w", err)
+		}
+		raw.TestData = testData
+		raw.Evaluators = replaceEvaluator(raw.Evaluators, evaluator)
+		target = raw
+	}
 
 	// Save updated prompt to file
-	if err := context.Prompt.SaveToFile(h.promptFile); err != nil {
+	if err := target.UpdateTestDataAndEvaluators(h.promptFile); err != nil {
 		return fmt.Errorf("failed to save updated prompt file: %w", err)
 	}
 
 	return nil
 }
+
+// replaceEvaluator adds the evaluator to the list, replacing any evaluator with the same name
+func replaceEvaluator(evaluators []prompt.Evaluator, evaluator prompt.Evaluator) []prompt.Evaluator {
+	if evaluators == nil {
+		evaluators = make([]prompt.Evaluator, 0)
+	}
+	evaluators = slices.DeleteFunc(evaluators, func(e prompt.Evaluator) bool {
+		return e.Name == evaluator.Name
+	})
+	return append(evaluators, evaluator)
+}
@@ -0,0 +1,158 @@
+// Package lint provides a `gh models lint` command to check prompt files for quality problems.
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/github/gh-models/cmd/eval"
+	"github.com/github/gh-models/pkg/command"
+	"github.com/github/gh-models/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+// Result is the machine-readable output of the lint command
+type Result struct {
+	Clean       bool                `json:"clean"`
+	Files       int                 `json:"files"`
+	Diagnostics []prompt.Diagnostic `json:"diagnostics"`
+}
+
+// NewLintCommand returns a new command to lint prompt files
+func NewLintCommand(cfg *command.Config) *cobra.Command {
+	var rules strings.Builder
+	for _, rule := range prompt.LintRules {
+		rules.WriteString(fmt.Sprintf("  %-30s %-8s %s\n", rule.ID, rule.Severity, rule.Description))
+	}
+
+	cmd := &cobra.Command{
+		Use:   "lint <file>...",
+		Short: "Check prompt files for quality problems",
+		Long: heredoc.Docf(`
+			Checks .prompt.yml files for likely quality problems, such as template variables that
+			the test data does not set, and reports each one with its line, column and rule ID.
+
+			Files that are not valid are reported with the problems found by %[1]sgh models validate%[1]s.
+
+			The following rules are checked:
+
+			%[2]s
+			To suppress a problem, add a %[1]s# gh-models-lint-disable rule-id%[1]s comment on its line or
+			on the line before it, or a %[1]s# gh-models-lint-disable-file rule-id%[1]s comment anywhere in
+			the file. Without rule IDs, the comments suppress every rule. Use %[1]s--disable%[1]s to turn
+			rules off for every file.
+
+			The command exits with a non-zero status if any file has errors, or also warnings with
+			%[1]s--strict%[1]s. Use %[1]s--json%[1]s to print the problems as JSON.
+		`, "`", rules.String()),
+		Example: heredoc.Doc(`
+			gh models lint my_prompt.prompt.yml
+			gh models lint --disable missing-evaluators prompts/*.prompt.yml
+			gh models lint --strict prompts/*.prompt.yml
+		`),
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jsonOutput, err := cmd.Flags().GetBool("json")
+			if err != nil {
+				return err
+			}
+			strict, err := cmd.Flags().GetBool("strict")
+			if err != nil {
+				return err
+			}
+			disabled, err := cmd.Flags().GetStringSlice("disable")
+			if err != nil {
+				return err
+			}
+			for _, id := range disabled {
+				if !slices.ContainsFunc(prompt.LintRules, func(r prompt.LintRule) bool { return r.ID == id }) {
+					return fmt.Errorf("unknown lint rule '%s'", id)
+				}
+			}
+
+			plugins := make(map[string]prompt.LLMEvaluator, len(eval.BuiltInEvaluators))
+			for name, evaluator := range eval.BuiltInEvaluators {
+				plugins[prompt.BuiltinPluginPrefix+name] = evaluator
+			}
+
+			result := Result{Clean: true, Files: len(args), Diagnostics: []prompt.Diagnostic{}}
+			failedFiles := 0
+			failures := 0
+			for _, filePath := range args {
+				diagnostics, err := prompt.LintFile(filePath, prompt.WithDisabledRules(disabled...), prompt.WithPluginEvaluators(plugins))
+				if err != nil {
+					diagnostics = []prompt.Diagnostic{{
+						File:     filePath,
+						Severity: prompt.SeverityError,
+						Message:  fmt.Sprintf("failed to read file: %v", err),
+					}}
+				}
+				if len(diagnostics) > 0 {
+					result.Clean = false
+				}
+				// Warnings are reported, but only fail the command with --strict
+				n := len(diagnostics)
+				if !strict {
+					n = countErrors(diagnostics)
+				}
+				if n > 0 {
+					failedFiles++
+					failures += n
+				}
+				result.Diagnostics = append(result.Diagnostics, diagnostics...)
+			}
+
+			if jsonOutput {
+				data, err := json.MarshalIndent(result, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				cfg.WriteToOut(string(data) + "\n")
+			} else {
+				for _, diagnostic := range result.Diagnostics {
+					cfg.WriteToOut(diagnostic.String() + "\n")
+				}
+				if result.Clean {
+					cfg.WriteToOut(fmt.Sprintf("✓ no problems found in %s\n", pluralize(len(args), "prompt file", "prompt files")))
+				}
+			}
+
+			if failures > 0 {
+				cmd.SilenceUsage = true
+				kind := "error"
+				if strict {
+					kind = "problem"
+				}
+				return fmt.Errorf("found %s in %d of %s", pluralize(failures, kind, kind+"s"),
+					failedFiles, pluralize(len(args), "file", "files"))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("json", false, "Output the problems in JSON format")
+	cmd.Flags().StringSlice("disable", nil, "Lint rules to turn off, by ID")
+	cmd.Flags().Bool("strict", false, "Exit with a non-zero status on warnings as well as errors")
+	return cmd
+}
+
+// countErrors returns the number of diagnostics with the error severity
+func countErrors(diagnostics []prompt.Diagnostic) int {
+	n := 0
+	for _, d := range diagnostics {
+		if d.Severity == prompt.SeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
@@ -0,0 +1,124 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/github/gh-models/internal/azuremodels"
+	"github.com/github/gh-models/pkg/command"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	cleanFile := filepath.Join(dir, "clean.prompt.yml")
+	require.NoError(t, os.WriteFile(cleanFile, []byte(`name: Clean
+messages:
+  - role: user
+    content: "{{input}}"
+testData:
+  - input: hi
+    expected: hello
+evaluators:
+  - name: greets
+    string:
+      contains: "{{expected}}"
+`), 0644))
+	problemFile := filepath.Join(dir, "problem.prompt.yml")
+	require.NoError(t, os.WriteFile(problemFile, []byte(`name: Problem
+messages:
+  - role: system
+    content: "Answer {{input}}"
+testData:
+  - input: hi
+`), 0644))
+
+	t.Run("reports clean files", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		cmd := NewLintCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), true, 100))
+		cmd.SetArgs([]string{cleanFile})
+
+		require.NoError(t, cmd.Execute())
+		require.Equal(t, "✓ no problems found in 1 prompt file\n", out.String())
+	})
+
+	t.Run("prints warnings without failing", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		cmd := NewLintCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), true, 100))
+		cmd.SetArgs([]string{cleanFile, problemFile})
+
+		require.NoError(t, cmd.Execute())
+		require.Contains(t, out.String(), problemFile+":4:14: warning: the system message interpolates {{input}}")
+		require.NotContains(t, out.String(), "no problems found")
+	})
+
+	t.Run("fails on errors", func(t *testing.T) {
+		errorFile := filepath.Join(dir, "error.prompt.yml")
+		require.NoError(t, os.WriteFile(errorFile, []byte(`name: Error
+messages:
+  - role: user
+    content: "{{question}}"
+testData:
+  - input: hi
+evaluators:
+  - name: answers
+    string:
+      contains: "{{input}}"
+`), 0644))
+		out := new(bytes.Buffer)
+		cmd := NewLintCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), true, 100))
+		cmd.SetArgs([]string{cleanFile, errorFile, problemFile})
+
+		err := cmd.Execute()
+		require.EqualError(t, err, "found 1 error in 1 of 3 files")
+		require.Contains(t, out.String(), errorFile+":4:14: error: ")
+		require.NotContains(t, out.String(), "Usage:")
+	})
+
+	t.Run("--strict fails on warnings", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		cmd := NewLintCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), true, 100))
+		cmd.SetArgs([]string{"--strict", cleanFile, problemFile})
+
+		err := cmd.Execute()
+		require.EqualError(t, err, "found 2 problems in 1 of 2 files")
+		require.Contains(t, out.String(), problemFile+":4:14: warning: the system message interpolates {{input}}")
+		require.Contains(t, out.String(), problemFile+":6:3: warning: the prompt file has testData but no evaluators, so gh models eval cannot score its responses [missing-evaluators]\n")
+		require.NotContains(t, out.String(), "Usage:")
+	})
+
+	t.Run("--disable turns rules off", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		cmd := NewLintCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), true, 100))
+		cmd.SetArgs([]string{"--disable", "missing-evaluators,untrusted-system-input", problemFile})
+
+		require.NoError(t, cmd.Execute())
+	})
+
+	t.Run("--disable rejects unknown rules", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		cmd := NewLintCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), true, 100))
+		cmd.SetArgs([]string{"--disable", "missing", problemFile})
+
+		require.EqualError(t, cmd.Execute(), "unknown lint rule 'missing'")
+	})
+
+	t.Run("--json prints machine-readable problems", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		cmd := NewLintCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), true, 100))
+		cmd.SetArgs([]string{"--json", problemFile})
+
+		require.NoError(t, cmd.Execute())
+
+		var result Result
+		require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+		require.False(t, result.Clean)
+		require.Equal(t, 1, result.Files)
+		require.Len(t, result.Diagnostics, 2)
+		require.Equal(t, "untrusted-system-input", result.Diagnostics[0].Rule)
+		require.Equal(t, "missing-evaluators", result.Diagnostics[1].Rule)
+	})
+}
@@ -8,10 +8,17 @@ import (
 	"github.com/MakeNowJust/heredoc"
 	"github.com/cli/go-gh/v2/pkg/auth"
 	"github.com/cli/go-gh/v2/pkg/term"
+	"github.com/github/gh-models/cmd/batch"
+	"github.com/github/gh-models/cmd/compare"
+	"github.com/github/gh-models/cmd/convert"
+	"github.com/github/gh-models/cmd/diff"
 	"github.com/github/gh-models/cmd/eval"
 	"github.com/github/gh-models/cmd/generate"
+	"github.com/github/gh-models/cmd/lint"
 	"github.com/github/gh-models/cmd/list"
 	"github.com/github/gh-models/cmd/run"
+	"github.com/github/gh-models/cmd/schema"
+	"github.com/github/gh-models/cmd/validate"
 	"github.com/github/gh-models/cmd/view"
 	"github.com/github/gh-models/internal/azuremodels"
 	"github.com/github/gh-models/pkg/command"
@@ -56,9 +63,16 @@ func NewRootCommand() *cobra.Command {
 
 	cfg := command.NewConfigWithTerminal(terminal, client)
 
+	cmd.AddCommand(batch.NewBatchCommand(cfg))
+	cmd.AddCommand(compare.NewCompareCommand(cfg))
+	cmd.AddCommand(convert.NewConvertCommand(cfg))
+	cmd.AddCommand(diff.NewDiffCommand(cfg))
 	cmd.AddCommand(eval.NewEvalCommand(cfg))
+	cmd.AddCommand(lint.NewLintCommand(cfg))
 	cmd.AddCommand(list.NewListCommand(cfg))
 	cmd.AddCommand(run.NewRunCommand(cfg))
+	cmd.AddCommand(schema.NewSchemaCommand(cfg))
+	cmd.AddCommand(validate.NewValidateCommand(cfg))
 	cmd.AddCommand(view.NewViewCommand(cfg))
 	cmd.AddCommand(generate.NewGenerateCommand(cfg))
 
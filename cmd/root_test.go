@@ -19,9 +19,16 @@ func TestRoot(t *testing.T) {
 		require.NoError(t, err)
 		output := buf.String()
 		require.Regexp(t, regexp.MustCompile(`Usage:\n\s+gh models \[command\]`), output)
+		require.Regexp(t, regexp.MustCompile(`batch\s+Run inference requests from a JSONL file`), output)
+		require.Regexp(t, regexp.MustCompile(`compare\s+Compare responses from several models`), output)
+		require.Regexp(t, regexp.MustCompile(`convert\s+Convert prompts to and from other formats`), output)
+		require.Regexp(t, regexp.MustCompile(`diff\s+Compare two prompt files`), output)
 		require.Regexp(t, regexp.MustCompile(`eval\s+Evaluate prompts using test data and evaluators`), output)
+		require.Regexp(t, regexp.MustCompile(`lint\s+Check prompt files for quality problems`), output)
 		require.Regexp(t, regexp.MustCompile(`list\s+List available models`), output)
 		require.Regexp(t, regexp.MustCompile(`run\s+Run inference with the specified model`), output)
+		require.Regexp(t, regexp.MustCompile(`schema\s+Print the JSON Schema for prompt files`), output)
+		require.Regexp(t, regexp.MustCompile(`validate\s+Check prompt files for problems`), output)
 		require.Regexp(t, regexp.MustCompile(`view\s+View details about a model`), output)
 		require.Regexp(t, regexp.MustCompile(`generate\s+Generate tests and evaluations for prompts`), output)
 	})
@@ -0,0 +1,236 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/github/gh-models/internal/azuremodels"
+	"github.com/github/gh-models/pkg/util"
+	"github.com/spf13/pflag"
+)
+
+// Context management strategies for long conversations.
+const (
+	// ContextStrategyFull sends the full conversation history every turn.
+	ContextStrategyFull = "full"
+	// ContextStrategySlidingWindow drops the oldest messages until the conversation fits the token budget.
+	ContextStrategySlidingWindow = "sliding-window"
+	// ContextStrategyLastN keeps the system prompt and the last N messages.
+	ContextStrategyLastN = "last-n"
+	// ContextStrategySummarize asks the model to summarize older turns when the token budget is exceeded.
+	ContextStrategySummarize = "summarize"
+)
+
+const (
+	defaultContextMaxTokens = 8000
+	defaultContextKeepLast  = 10
+
+	// messageTokenOverhead approximates the tokens used by the role and formatting of each message.
+	messageTokenOverhead = 4
+	// compactKeepMessages is the number of recent messages left untouched when compacting.
+	compactKeepMessages = 2
+)
+
+var contextStrategies = []string{ContextStrategyFull, ContextStrategySlidingWindow, ContextStrategyLastN, ContextStrategySummarize}
+
+// ContextManager decides which parts of a conversation are sent to the model.
+type ContextManager struct {
+	Strategy  string
+	MaxTokens int
+	KeepLast  int
+}
+
+// NewContextManager returns a context manager that sends the full conversation.
+func NewContextManager() *ContextManager {
+	return &ContextManager{
+		Strategy:  ContextStrategyFull,
+		MaxTokens: defaultContextMaxTokens,
+		KeepLast:  defaultContextKeepLast,
+	}
+}
+
+// PopulateFromFlags populates the context manager from the given flags.
+func (cm *ContextManager) PopulateFromFlags(flags *pflag.FlagSet) error {
+	strategy, err := flags.GetString("context-strategy")
+	if err != nil {
+		return err
+	}
+	if strategy != "" {
+		if err := cm.SetParameterByName("context-strategy", strategy); err != nil {
+			return err
+		}
+	}
+
+	maxTokens, err := flags.GetInt("context-max-tokens")
+	if err != nil {
+		return err
+	}
+	if maxTokens != 0 {
+		if err := cm.SetParameterByName("context-max-tokens", strconv.Itoa(maxTokens)); err != nil {
+			return err
+		}
+	}
+
+	keepLast, err := flags.GetInt("context-keep-last")
+	if err != nil {
+		return err
+	}
+	if keepLast != 0 {
+		if err := cm.SetParameterByName("context-keep-last", strconv.Itoa(keepLast)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetParameterByName sets the context parameter with the given name to the given value.
+func (cm *ContextManager) SetParameterByName(name, value string) error {
+	switch name {
+	case "context-strategy":
+		if !slices.Contains(contextStrategies, value) {
+			return fmt.Errorf("unknown context strategy '%s'. Supported strategies: %s", value, strings.Join(contextStrategies, ", "))
+		}
+		cm.Strategy = value
+
+	case "context-max-tokens":
+		maxTokens, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		if maxTokens <= 0 {
+			return errors.New("context-max-tokens must be greater than zero")
+		}
+		cm.MaxTokens = maxTokens
+
+	case "context-keep-last":
+		keepLast, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		if keepLast <= 0 {
+			return errors.New("context-keep-last must be greater than zero")
+		}
+		cm.KeepLast = keepLast
+
+	default:
+		return errors.New("unknown parameter '" + name + "'. Supported parameters: context-strategy, context-max-tokens, context-keep-last")
+	}
+
+	return nil
+}
+
+// Apply returns the messages that should be sent to the model for the given conversation messages.
+func (cm *ContextManager) Apply(messages []azuremodels.ChatMessage) []azuremodels.ChatMessage {
+	var system, rest []azuremodels.ChatMessage
+	for i, message := range messages {
+		if message.Role != azuremodels.ChatMessageRoleSystem {
+			rest = messages[i:]
+			break
+		}
+		system = append(system, message)
+	}
+
+	switch cm.Strategy {
+	case ContextStrategyLastN:
+		if len(rest) > cm.KeepLast {
+			rest = rest[len(rest)-cm.KeepLast:]
+		}
+
+	case ContextStrategySlidingWindow:
+		budget := cm.MaxTokens - EstimateTokens(system)
+		// Always keep the latest message, even if it alone exceeds the budget
+		for len(rest) > 1 && EstimateTokens(rest) > budget {
+			rest = rest[1:]
+		}
+
+	default:
+		return messages
+	}
+
+	result := make([]azuremodels.ChatMessage, 0, len(system)+len(rest))
+	result = append(result, system...)
+	return append(result, rest...)
+}
+
+// NeedsCompaction returns true if the conversation should be summarized before it is sent.
+func (cm *ContextManager) NeedsCompaction(conversation *Conversation) bool {
+	return cm.Strategy == ContextStrategySummarize &&
+		len(conversation.messages) > compactKeepMessages &&
+		EstimateTokens(conversation.GetMessages()) > cm.MaxTokens
+}
+
+// EstimateTokens returns a rough estimate of the number of tokens used by the given messages.
+// It assumes around four characters per token, which is close enough to keep a conversation
+// within a model's context window without depending on a model-specific tokenizer.
+func EstimateTokens(messages []azuremodels.ChatMessage) int {
+	tokens := 0
+	for _, message := range messages {
+		tokens += messageTokenOverhead
+		if message.Content != nil {
+			tokens += (len(*message.Content) + 3) / 4
+		}
+	}
+	return tokens
+}
+
+const compactSystemPrompt = `You summarize conversations between a user and an AI assistant.
+Write a concise summary of the conversation below that preserves the facts, decisions, open questions and any instructions from the user that are needed to continue the conversation.
+Respond with the summary only.`
+
+// compactConversation replaces all but the most recent messages of the conversation with a
+// summary written by the model. It returns the number of messages that were summarized.
+func (h *runCommandHandler) compactConversation(session *ChatSession) (int, error) {
+	conversation := session.Conversation
+	if len(conversation.messages) <= compactKeepMessages {
+		return 0, nil
+	}
+
+	older := conversation.messages[:len(conversation.messages)-compactKeepMessages]
+	recent := conversation.messages[len(conversation.messages)-compactKeepMessages:]
+
+	var transcript strings.Builder
+	if conversation.summary != "" {
+		transcript.WriteString("Summary of the earlier conversation:\n" + conversation.summary + "\n\n")
+	}
+	for _, message := range older {
+		content := ""
+		if message.Content != nil {
+			content = *message.Content
+		}
+		transcript.WriteString(fmt.Sprintf("%s: %s\n\n", message.Role, strings.TrimSpace(content)))
+	}
+
+	req := azuremodels.ChatCompletionOptions{
+		Model: session.ModelName,
+		Messages: []azuremodels.ChatMessage{
+			{Role: azuremodels.ChatMessageRoleSystem, Content: util.Ptr(compactSystemPrompt)},
+			{Role: azuremodels.ChatMessageRoleUser, Content: util.Ptr(transcript.String())},
+		},
+	}
+
+	result, err := h.runCompletion(req, h.org, outputOptions{json: true})
+	if err != nil {
+		return 0, fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+
+	conversation.summary = strings.TrimSpace(result.Content)
+	conversation.messages = append([]azuremodels.ChatMessage(nil), recent...)
+	return len(older), nil
+}
+
+func (h *runCommandHandler) handleCompactPrompt(session *ChatSession) {
+	count, err := h.compactConversation(session)
+	if err != nil {
+		h.writeToOut(err.Error() + "\n")
+		return
+	}
+	if count == 0 {
+		h.writeToOut("Nothing to compact\n")
+		return
+	}
+	h.writeToOut(fmt.Sprintf("Compacted %d messages into a summary\n", count))
+}
@@ -0,0 +1,96 @@
+package run
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/github/gh-models/internal/azuremodels"
+	"github.com/github/gh-models/pkg/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextManager(t *testing.T) {
+	message := func(role azuremodels.ChatMessageRole, content string) azuremodels.ChatMessage {
+		return azuremodels.ChatMessage{Role: role, Content: util.Ptr(content)}
+	}
+	contents := func(messages []azuremodels.ChatMessage) []string {
+		var result []string
+		for _, m := range messages {
+			result = append(result, *m.Content)
+		}
+		return result
+	}
+
+	messages := []azuremodels.ChatMessage{
+		message(azuremodels.ChatMessageRoleSystem, "system"),
+		message(azuremodels.ChatMessageRoleUser, strings.Repeat("a", 40)),
+		message(azuremodels.ChatMessageRoleAssistant, strings.Repeat("b", 40)),
+		message(azuremodels.ChatMessageRoleUser, strings.Repeat("c", 40)),
+		message(azuremodels.ChatMessageRoleAssistant, strings.Repeat("d", 40)),
+	}
+
+	t.Run("full sends every message", func(t *testing.T) {
+		cm := NewContextManager()
+		require.Equal(t, messages, cm.Apply(messages))
+	})
+
+	t.Run("last-n keeps the system prompt and the last messages", func(t *testing.T) {
+		cm := NewContextManager()
+		require.NoError(t, cm.SetParameterByName("context-strategy", "last-n"))
+		require.NoError(t, cm.SetParameterByName("context-keep-last", "2"))
+
+		result := cm.Apply(messages)
+		require.Equal(t, []string{"system", strings.Repeat("c", 40), strings.Repeat("d", 40)}, contents(result))
+	})
+
+	t.Run("sliding-window drops the oldest messages to fit the budget", func(t *testing.T) {
+		cm := NewContextManager()
+		require.NoError(t, cm.SetParameterByName("context-strategy", "sliding-window"))
+		// system prompt uses 6 tokens and every other message uses 14
+		require.NoError(t, cm.SetParameterByName("context-max-tokens", "40"))
+
+		result := cm.Apply(messages)
+		require.Equal(t, []string{"system", strings.Repeat("c", 40), strings.Repeat("d", 40)}, contents(result))
+	})
+
+	t.Run("sliding-window always keeps the latest message", func(t *testing.T) {
+		cm := NewContextManager()
+		require.NoError(t, cm.SetParameterByName("context-strategy", "sliding-window"))
+		require.NoError(t, cm.SetParameterByName("context-max-tokens", "1"))
+
+		result := cm.Apply(messages)
+		require.Equal(t, []string{"system", strings.Repeat("d", 40)}, contents(result))
+	})
+
+	t.Run("summarize needs compaction over budget", func(t *testing.T) {
+		cm := NewContextManager()
+		require.NoError(t, cm.SetParameterByName("context-strategy", "summarize"))
+		require.NoError(t, cm.SetParameterByName("context-max-tokens", "20"))
+
+		conversation := &Conversation{}
+		conversation.AddMessage(azuremodels.ChatMessageRoleUser, strings.Repeat("a", 40))
+		require.False(t, cm.NeedsCompaction(conversation))
+
+		conversation.AddMessage(azuremodels.ChatMessageRoleAssistant, strings.Repeat("b", 40))
+		conversation.AddMessage(azuremodels.ChatMessageRoleUser, strings.Repeat("c", 40))
+		require.True(t, cm.NeedsCompaction(conversation))
+	})
+
+	t.Run("rejects invalid values", func(t *testing.T) {
+		cm := NewContextManager()
+		require.ErrorContains(t, cm.SetParameterByName("context-strategy", "everything"), "unknown context strategy")
+		require.Error(t, cm.SetParameterByName("context-max-tokens", "0"))
+		require.Error(t, cm.SetParameterByName("context-keep-last", "abc"))
+		require.ErrorContains(t, cm.SetParameterByName("context-size", "1"), "unknown parameter")
+	})
+}
+
+func TestEstimateTokens(t *testing.T) {
+	require.Equal(t, 0, EstimateTokens(nil))
+	require.Equal(t, 14, EstimateTokens([]azuremodels.ChatMessage{
+		{Role: azuremodels.ChatMessageRoleUser, Content: util.Ptr(strings.Repeat("x", 40))},
+	}))
+	require.Equal(t, 4, EstimateTokens([]azuremodels.ChatMessage{
+		{Role: azuremodels.ChatMessageRoleAssistant},
+	}))
+}
@@ -0,0 +1,103 @@
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/github/gh-models/pkg/command"
+	"github.com/github/gh-models/pkg/prompt"
+)
+
+// describePromptFile prints the name, model and template variables of a prompt file, in the style of --help.
+// Variables that the messages use without declaring them are listed too.
+func describePromptFile(cfg *command.Config, filePath string, pf *prompt.File) error {
+	var sb strings.Builder
+
+	name := pf.Name
+	if name == "" {
+		name = filePath
+	}
+	sb.WriteString(name + "\n")
+	if pf.Description != "" {
+		sb.WriteString(strings.TrimSpace(pf.Description) + "\n")
+	}
+	if pf.Model != "" {
+		sb.WriteString(fmt.Sprintf("\nModel: %s\n", pf.Model))
+	}
+
+	var undeclared []string
+	for _, m := range pf.Messages {
+		names, err := prompt.TemplateVariables(m.Content)
+		if err != nil {
+			return fmt.Errorf("invalid template in the %s message: %w", m.Role, err)
+		}
+		for _, name := range names {
+			declared := slices.ContainsFunc(pf.Variables, func(v prompt.Variable) bool { return v.Name == name })
+			if !declared && !slices.Contains(undeclared, name) {
+				undeclared = append(undeclared, name)
+			}
+		}
+	}
+
+	var flags, usages []string
+	for _, v := range pf.Variables {
+		flags = append(flags, variableFlag(v.Name, v.Type))
+		usages = append(usages, variableUsage(v))
+	}
+	for _, name := range undeclared {
+		flags = append(flags, variableFlag(name, ""))
+		usages = append(usages, "Used by the messages, but not declared")
+	}
+
+	if len(flags) == 0 {
+		sb.WriteString("\nThe prompt file does not use any template variables.\n")
+	} else {
+		width := 0
+		for _, flag := range flags {
+			width = max(width, len(flag))
+		}
+		sb.WriteString("\nVariables:\n")
+		for i, flag := range flags {
+			sb.WriteString(strings.TrimRight(fmt.Sprintf("  %-*s   %s", width, flag, usages[i]), " ") + "\n")
+		}
+	}
+
+	example := "gh models run --file " + filePath
+	for _, v := range pf.Variables {
+		if v.Required && v.Default == nil {
+			example += " " + variableFlag(v.Name, v.Type)
+		}
+	}
+	sb.WriteString("\nUsage:\n  " + example + "\n")
+
+	cfg.WriteToOut(sb.String())
+	return nil
+}
+
+func variableFlag(name, typeName string) string {
+	if typeName == "" {
+		typeName = "value"
+	}
+	return fmt.Sprintf("--var %s=<%s>", name, typeName)
+}
+
+func variableUsage(v prompt.Variable) string {
+	usage := v.Description
+	var notes []string
+	if v.Required && v.Default == nil {
+		notes = append(notes, "required")
+	}
+	if v.Default != nil {
+		value, err := json.Marshal(v.Default)
+		if err != nil {
+			value = []byte(fmt.Sprint(v.Default))
+		}
+		notes = append(notes, "default "+string(value))
+	}
+	if len(notes) > 0 {
+		usage = strings.TrimSpace(usage + " (" + strings.Join(notes, ", ") + ")")
+	}
+	return usage
+}
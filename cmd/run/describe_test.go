@@ -0,0 +1,126 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/github/gh-models/internal/azuremodels"
+	"github.com/github/gh-models/internal/sse"
+	"github.com/github/gh-models/pkg/command"
+	"github.com/github/gh-models/pkg/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeclaredVariables(t *testing.T) {
+	const yamlBody = `
+name: Summarizer
+description: Summarizes a document.
+model: openai/test-model
+variables:
+  - name: document
+    type: string
+    description: The document to summarize
+    required: true
+  - name: sentences
+    type: integer
+    description: How long the summary is
+    default: 3
+testData:
+  - document: First
+  - sentences: 2
+messages:
+  - role: system
+    content: Answer in {{sentences}} sentences for {{audience}}.
+  - role: user
+    content: "{{document}}"
+`
+	promptFile := filepath.Join(t.TempDir(), "summarize.prompt.yml")
+	require.NoError(t, os.WriteFile(promptFile, []byte(yamlBody), 0644))
+
+	newClient := func() (*azuremodels.MockClient, *[]azuremodels.ChatCompletionOptions) {
+		client := azuremodels.NewMockClient()
+		client.MockListModels = func(ctx context.Context) ([]*azuremodels.ModelSummary, error) {
+			return []*azuremodels.ModelSummary{{ID: "openai/test-model", Name: "test-model", Publisher: "openai", Task: "chat-completion"}}, nil
+		}
+		var requests []azuremodels.ChatCompletionOptions
+		client.MockGetChatCompletionStream = func(ctx context.Context, req azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+			requests = append(requests, req)
+			return &azuremodels.ChatCompletionResponse{
+				Reader: sse.NewMockEventReader([]azuremodels.ChatCompletion{
+					{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: util.Ptr("Summary")}}}},
+				}),
+			}, nil
+		}
+		return client, &requests
+	}
+
+	t.Run("--describe prints the variables", func(t *testing.T) {
+		client, requests := newClient()
+		out := new(bytes.Buffer)
+		cmd := NewRunCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--file", promptFile, "--describe"})
+
+		require.NoError(t, cmd.Execute())
+		require.Empty(t, *requests)
+		require.Equal(t, `Summarizer
+Summarizes a document.
+
+Model: openai/test-model
+
+Variables:
+  --var document=<string>     The document to summarize (required)
+  --var sentences=<integer>   How long the summary is (default 3)
+  --var audience=<value>      Used by the messages, but not declared
+
+Usage:
+  gh models run --file `+promptFile+` --var document=<string>
+`, out.String())
+	})
+
+	t.Run("--describe requires --file", func(t *testing.T) {
+		client, _ := newClient()
+		out := new(bytes.Buffer)
+		cmd := NewRunCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--describe"})
+
+		require.EqualError(t, cmd.Execute(), "--describe requires --file")
+	})
+
+	t.Run("fails before calling the model", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			args     []string
+			expected string
+		}{
+			{"missing required variable", []string{"--file", promptFile}, "invalid template variables: missing required variable 'document'"},
+			{"wrong type", []string{"--file", promptFile, "--var", "document=Text", "--var", "sentences=few"}, `invalid template variables: variable 'sentences' must be an integer, got "few"`},
+			{"in a row", []string{"--file", promptFile, "--row", "2"}, "row 2: invalid template variables: missing required variable 'document'"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				client, requests := newClient()
+				out := new(bytes.Buffer)
+				cmd := NewRunCommand(command.NewConfig(out, out, client, true, 100))
+				cmd.SetArgs(tt.args)
+
+				require.EqualError(t, cmd.Execute(), tt.expected)
+				require.Empty(t, *requests)
+			})
+		}
+	})
+
+	t.Run("applies defaults", func(t *testing.T) {
+		client, requests := newClient()
+		out := new(bytes.Buffer)
+		cmd := NewRunCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--file", promptFile, "--var", "document=Text", "--var", "audience=kids"})
+
+		require.NoError(t, cmd.Execute())
+		require.Len(t, *requests, 1)
+		require.Equal(t, "Answer in 3 sentences for kids.", *(*requests)[0].Messages[0].Content)
+	})
+}
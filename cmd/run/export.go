@@ -0,0 +1,122 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/github/gh-models/internal/azuremodels"
+	"github.com/github/gh-models/pkg/prompt"
+	"github.com/github/gh-models/pkg/util"
+)
+
+// ToPromptFile converts the chat session into a prompt file with the given name.
+//
+// If templateInput is true, the last user message is replaced with an {{input}} placeholder, the
+// messages after it are dropped and every user turn is added to testData along with the assistant
+// reply as the expected output, so the file can be used with eval right away.
+func (s *ChatSession) ToPromptFile(name string, templateInput bool) *prompt.File {
+	pf := &prompt.File{
+		Name:        name,
+		Description: "Exported from gh models run",
+		Model:       s.ModelName,
+		ModelParameters: prompt.ModelParameters{
+			MaxTokens:   s.Parameters.maxTokens,
+			Temperature: s.Parameters.temperature,
+			TopP:        s.Parameters.topP,
+		},
+	}
+
+	messages := s.Conversation.exportMessages()
+
+	lastUser := -1
+	if templateInput {
+		for i, m := range messages {
+			if m.Role == azuremodels.ChatMessageRoleUser {
+				lastUser = i
+			}
+		}
+	}
+
+	for i, m := range messages {
+		content := ""
+		if m.Content != nil {
+			content = *m.Content
+		}
+
+		if templateInput && m.Role == azuremodels.ChatMessageRoleUser {
+			item := prompt.TestDataItem{"input": content}
+			if i+1 < len(messages) && messages[i+1].Role == azuremodels.ChatMessageRoleAssistant && messages[i+1].Content != nil {
+				item["expected"] = *messages[i+1].Content
+			}
+			pf.TestData = append(pf.TestData, item)
+		}
+
+		if i > lastUser && lastUser >= 0 {
+			continue
+		}
+		if i == lastUser {
+			content = "{{input}}"
+		}
+
+		pf.Messages = append(pf.Messages, prompt.Message{
+			Role:    string(m.Role),
+			Content: content,
+		})
+	}
+
+	return pf
+}
+
+// exportMessages returns the system prompt and the messages of the conversation, without the summary
+// of compacted messages and without empty messages, such as the one an interactive session starts with.
+func (c *Conversation) exportMessages() []azuremodels.ChatMessage {
+	var messages []azuremodels.ChatMessage
+	if c.systemPrompt != "" {
+		messages = append(messages, azuremodels.ChatMessage{
+			Content: util.Ptr(c.systemPrompt),
+			Role:    azuremodels.ChatMessageRoleSystem,
+		})
+	}
+	for _, m := range c.messages {
+		if m.Content != nil && strings.TrimSpace(*m.Content) != "" {
+			messages = append(messages, m)
+		}
+	}
+	return messages
+}
+
+// exportPrompt writes the chat session to the given .prompt.yml file.
+func (h *runCommandHandler) exportPrompt(session *ChatSession, filePath string, templateInput bool) error {
+	if len(session.Conversation.exportMessages()) == 0 {
+		return errors.New("there are no messages to export")
+	}
+
+	name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(filePath), ".yml"), ".prompt")
+	return session.ToPromptFile(name, templateInput).SaveToFile(filePath)
+}
+
+func (h *runCommandHandler) handleExportPrompt(prompt string, session *ChatSession) {
+	templateInput := false
+	var files []string
+	for _, arg := range strings.Fields(prompt)[1:] {
+		if arg == "--input" {
+			templateInput = true
+		} else {
+			files = append(files, arg)
+		}
+	}
+
+	if len(files) != 1 {
+		h.writeToOut("Invalid /export-prompt syntax. Usage: /export-prompt <file> [--input]\n")
+		return
+	}
+	filePath := files[0]
+
+	if err := h.exportPrompt(session, filePath, templateInput); err != nil {
+		h.writeToOut(err.Error() + "\n")
+		return
+	}
+	h.writeToOut(fmt.Sprintf("Exported conversation to %s\n", filePath))
+}
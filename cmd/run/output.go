@@ -0,0 +1,139 @@
+package run
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/cli/go-gh/v2/pkg/jq"
+	"github.com/cli/go-gh/v2/pkg/template"
+	"github.com/github/gh-models/internal/azuremodels"
+	"github.com/github/gh-models/pkg/prompt"
+	"github.com/spf13/pflag"
+)
+
+// RunResult represents the structured result of a single inference request.
+type RunResult struct {
+	// Row is the testData row of the prompt file the result was produced for, starting at 1.
+	Row          int                              `json:"row,omitempty"`
+	Model        string                           `json:"model"`
+	Content      string                           `json:"content"`
+	FinishReason string                           `json:"finishReason"`
+	Usage        *azuremodels.ChatCompletionUsage `json:"usage,omitempty"`
+	LatencyMs    int64                            `json:"latencyMs"`
+	Parameters   RunParameters                    `json:"parameters"`
+	// SchemaViolations lists where the content does not match the prompt file's JSON schema.
+	SchemaViolations []prompt.SchemaViolation `json:"schemaViolations,omitempty"`
+}
+
+// RunParameters represents the request parameters that were sent to the model.
+type RunParameters struct {
+	MaxTokens      *int     `json:"maxTokens,omitempty"`
+	Temperature    *float64 `json:"temperature,omitempty"`
+	TopP           *float64 `json:"topP,omitempty"`
+	ResponseFormat string   `json:"responseFormat,omitempty"`
+}
+
+// RunStreamChunk represents a single line of --jsonl-stream output.
+type RunStreamChunk struct {
+	// Type is "chunk" for incremental content and "result" for the final summary line.
+	Type         string                           `json:"type"`
+	Model        string                           `json:"model"`
+	Content      string                           `json:"content,omitempty"`
+	FinishReason string                           `json:"finishReason,omitempty"`
+	Usage        *azuremodels.ChatCompletionUsage `json:"usage,omitempty"`
+	Result       *RunResult                       `json:"result,omitempty"`
+}
+
+func newRunParameters(req azuremodels.ChatCompletionOptions) RunParameters {
+	params := RunParameters{
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+	}
+	if req.ResponseFormat != nil {
+		params.ResponseFormat = req.ResponseFormat.Type
+	}
+	return params
+}
+
+// outputOptions describes how the run command should format its output.
+type outputOptions struct {
+	json        bool
+	jq          string
+	template    string
+	jsonlStream bool
+	// buffer holds back a text response instead of printing it as it streams
+	buffer bool
+}
+
+func parseOutputOptions(flags *pflag.FlagSet) (outputOptions, error) {
+	var opts outputOptions
+	var err error
+
+	if opts.json, err = flags.GetBool("json"); err != nil {
+		return opts, err
+	}
+	if opts.jq, err = flags.GetString("jq"); err != nil {
+		return opts, err
+	}
+	if opts.template, err = flags.GetString("template"); err != nil {
+		return opts, err
+	}
+	if opts.jsonlStream, err = flags.GetBool("jsonl-stream"); err != nil {
+		return opts, err
+	}
+
+	if opts.jq != "" && opts.template != "" {
+		return opts, errors.New("only one of --jq or --template may be used")
+	}
+	// --jq and --template both operate on the JSON result, so they imply --json
+	if opts.jq != "" || opts.template != "" {
+		opts.json = true
+	}
+	if opts.json && opts.jsonlStream {
+		return opts, errors.New("--json and --jsonl-stream cannot be used together")
+	}
+
+	return opts, nil
+}
+
+// isStructured returns true if the output is machine-readable rather than streamed text.
+func (o outputOptions) isStructured() bool {
+	return o.json || o.jsonlStream
+}
+
+func (h *runCommandHandler) writeStreamChunk(chunk RunStreamChunk) error {
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	h.writeToOut(string(data) + "\n")
+	return nil
+}
+
+// writeResult writes a result, or a slice of results, as JSON filtered according to the output options.
+func (h *runCommandHandler) writeResult(result interface{}, opts outputOptions) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	switch {
+	case opts.jq != "":
+		return jq.Evaluate(bytes.NewReader(data), h.cfg.Out, opts.jq)
+	case opts.template != "":
+		t := template.New(h.cfg.Out, h.cfg.TerminalWidth, h.cfg.IsTerminalOutput)
+		if err := t.Parse(opts.template); err != nil {
+			return err
+		}
+		if err := t.Execute(bytes.NewReader(data)); err != nil {
+			return err
+		}
+		return t.Flush()
+	default:
+		h.writeToOut(string(data) + "\n")
+		return nil
+	}
+}
@@ -0,0 +1,168 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/github/gh-models/internal/azuremodels"
+	"github.com/github/gh-models/pkg/prompt"
+	"github.com/github/gh-models/pkg/util"
+	"github.com/spf13/pflag"
+)
+
+// maxRowValueLength is the longest row value printed in the header of each row.
+const maxRowValueLength = 80
+
+// parseTestDataRows returns the zero-based indexes of the testData rows selected with
+// --all-test-data or --row, or nil if neither flag was used.
+func parseTestDataRows(flags *pflag.FlagSet, pf *prompt.File) ([]int, error) {
+	allRows, err := flags.GetBool("all-test-data")
+	if err != nil {
+		return nil, err
+	}
+	rowNumbers, err := flags.GetIntSlice("row")
+	if err != nil {
+		return nil, err
+	}
+
+	if !allRows && len(rowNumbers) == 0 {
+		return nil, nil
+	}
+	if allRows && len(rowNumbers) > 0 {
+		return nil, errors.New("only one of --all-test-data or --row may be used")
+	}
+	if pf == nil {
+		return nil, errors.New("--all-test-data and --row require --file")
+	}
+	if len(pf.TestData) == 0 {
+		return nil, errors.New("the prompt file does not contain any testData")
+	}
+
+	var rows []int
+	if allRows {
+		for i := range pf.TestData {
+			rows = append(rows, i)
+		}
+		return rows, nil
+	}
+
+	for _, n := range rowNumbers {
+		if n < 1 || n > len(pf.TestData) {
+			return nil, fmt.Errorf("row %d is out of range: the prompt file has %d testData rows", n, len(pf.TestData))
+		}
+		rows = append(rows, n-1)
+	}
+	return rows, nil
+}
+
+// testDataRun describes a run of a prompt file over some of its testData rows.
+type testDataRun struct {
+	pf            *prompt.File
+	rows          []int
+	input         string
+	templateVars  map[string]string
+	templateOpts  []prompt.TemplateOption
+	systemPrompt  string
+	parameters    *ModelParameters
+	modelName     string
+	schemaRetries int
+	outputDir     string
+}
+
+// runTestData runs the prompt file once for each selected testData row and prints the responses.
+func (h *runCommandHandler) runTestData(run testDataRun, opts outputOptions) error {
+	if run.outputDir != "" {
+		if err := os.MkdirAll(run.outputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	var results []*RunResult
+	var invalidRows []string
+	for i, row := range run.rows {
+		rowData := run.pf.TestData[row]
+
+		templateData := map[string]interface{}{"input": run.input}
+		for key, value := range run.templateVars {
+			templateData[key] = value
+		}
+		for key, value := range rowData {
+			templateData[key] = value
+		}
+
+		conversation := Conversation{systemPrompt: run.systemPrompt}
+		if err := conversation.AddPromptFileMessages(run.pf, templateData, run.templateOpts...); err != nil {
+			return fmt.Errorf("row %d: %w", row+1, err)
+		}
+
+		req := run.pf.BuildChatCompletionOptions(conversation.GetMessages())
+		req.Model = run.modelName
+		run.parameters.UpdateRequest(&req)
+		if opts.isStructured() {
+			req.StreamOptions = &azuremodels.StreamOptions{IncludeUsage: true}
+		}
+
+		if !opts.isStructured() {
+			if i > 0 {
+				h.writeToOut("\n")
+			}
+			h.writeRowHeader(row, len(run.pf.TestData), rowData)
+		}
+
+		result, err := h.runCheckedCompletion(run.pf, req, run.schemaRetries, h.org, opts)
+		if err != nil {
+			return fmt.Errorf("row %d: %w", row+1, err)
+		}
+		result.Row = row + 1
+		results = append(results, result)
+
+		if len(result.SchemaViolations) > 0 {
+			invalidRows = append(invalidRows, fmt.Sprintf("%d", row+1))
+			if !opts.isStructured() {
+				util.WriteToOut(h.cfg.ErrOut, (&prompt.SchemaValidationError{Violations: result.SchemaViolations}).Error()+"\n")
+			}
+		}
+
+		if run.outputDir != "" {
+			path := filepath.Join(run.outputDir, fmt.Sprintf("row-%d.txt", row+1))
+			if err := os.WriteFile(path, []byte(result.Content), 0644); err != nil {
+				return fmt.Errorf("failed to write response: %w", err)
+			}
+		}
+	}
+
+	if opts.json {
+		if err := h.writeResult(results, opts); err != nil {
+			return err
+		}
+	}
+
+	if len(invalidRows) > 0 {
+		return fmt.Errorf("the responses for rows %s do not match the JSON schema", strings.Join(invalidRows, ", "))
+	}
+	return nil
+}
+
+// writeRowHeader prints the row number and the row's values before its response.
+func (h *runCommandHandler) writeRowHeader(row, total int, rowData prompt.TestDataItem) {
+	h.writeToOut(fmt.Sprintf("Row %d/%d\n", row+1, total))
+
+	keys := make([]string, 0, len(rowData))
+	for key := range rowData {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := strings.ReplaceAll(fmt.Sprintf("%v", rowData[key]), "\n", " ")
+		if len([]rune(value)) > maxRowValueLength {
+			value = string([]rune(value)[:maxRowValueLength-1]) + "…"
+		}
+		h.writeToOut(fmt.Sprintf("  %s: %s\n", key, value))
+	}
+	h.writeToOut("\n")
+}
@@ -0,0 +1,164 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/github/gh-models/internal/azuremodels"
+	"github.com/github/gh-models/internal/sse"
+	"github.com/github/gh-models/pkg/command"
+	"github.com/github/gh-models/pkg/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunTestData(t *testing.T) {
+	const yamlBody = `
+name: Greeting
+model: openai/test-model
+testData:
+  - name: Alice
+  - name: Bob
+    tone: grumpy
+  - name: Carol
+messages:
+  - role: system
+    content: You are {{tone}}.
+  - role: user
+    content: "Greet {{name}}"
+`
+	promptFile := filepath.Join(t.TempDir(), "greeting.prompt.yml")
+	require.NoError(t, os.WriteFile(promptFile, []byte(yamlBody), 0644))
+
+	newClient := func() (*azuremodels.MockClient, *[]azuremodels.ChatCompletionOptions) {
+		client := azuremodels.NewMockClient()
+		client.MockListModels = func(ctx context.Context) ([]*azuremodels.ModelSummary, error) {
+			return []*azuremodels.ModelSummary{{ID: "openai/test-model", Name: "test-model", Publisher: "openai", Task: "chat-completion"}}, nil
+		}
+		var requests []azuremodels.ChatCompletionOptions
+		client.MockGetChatCompletionStream = func(ctx context.Context, req azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+			requests = append(requests, req)
+			reply := "Hello from " + *req.Messages[1].Content
+			return &azuremodels.ChatCompletionResponse{
+				Reader: sse.NewMockEventReader([]azuremodels.ChatCompletion{
+					{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: util.Ptr(reply)}}}},
+				}),
+			}, nil
+		}
+		return client, &requests
+	}
+
+	t.Run("--all-test-data runs every row", func(t *testing.T) {
+		client, requests := newClient()
+		out := new(bytes.Buffer)
+		cmd := NewRunCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--file", promptFile, "--all-test-data", "--var", "tone=cheerful"})
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+
+		require.Len(t, *requests, 3)
+		require.Equal(t, "You are cheerful.", *(*requests)[0].Messages[0].Content)
+		require.Equal(t, "Greet Alice", *(*requests)[0].Messages[1].Content)
+		require.Equal(t, "You are grumpy.", *(*requests)[1].Messages[0].Content)
+		require.Equal(t, "Greet Carol", *(*requests)[2].Messages[1].Content)
+
+		output := out.String()
+		require.Contains(t, output, "Row 1/3\n  name: Alice\n")
+		require.Contains(t, output, "Row 2/3\n  name: Bob\n  tone: grumpy\n")
+		require.Contains(t, output, "Hello from Greet Carol")
+	})
+
+	t.Run("--row selects rows and --json prints every result", func(t *testing.T) {
+		client, requests := newClient()
+		out := new(bytes.Buffer)
+		cmd := NewRunCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--file", promptFile, "--row", "3", "--row", "1", "--json"})
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+		require.Len(t, *requests, 2)
+
+		var results []RunResult
+		require.NoError(t, json.Unmarshal(out.Bytes(), &results))
+		require.Len(t, results, 2)
+		require.Equal(t, 3, results[0].Row)
+		require.Equal(t, "Hello from Greet Carol", results[0].Content)
+		require.Equal(t, 1, results[1].Row)
+	})
+
+	t.Run("--output-dir writes a file per row", func(t *testing.T) {
+		client, _ := newClient()
+		outputDir := filepath.Join(t.TempDir(), "responses")
+		out := new(bytes.Buffer)
+		cmd := NewRunCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--file", promptFile, "--row", "2", "--output-dir", outputDir})
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(filepath.Join(outputDir, "row-2.txt"))
+		require.NoError(t, err)
+		require.Equal(t, "Hello from Greet Bob", string(content))
+		require.NoFileExists(t, filepath.Join(outputDir, "row-1.txt"))
+	})
+
+	t.Run("rejects invalid rows", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			args     []string
+			expected string
+		}{
+			{"out of range", []string{"--file", promptFile, "--row", "4"}, "row 4 is out of range: the prompt file has 3 testData rows"},
+			{"both flags", []string{"--file", promptFile, "--row", "1", "--all-test-data"}, "only one of --all-test-data or --row may be used"},
+			{"without a prompt file", []string{"--all-test-data", "openai/test-model", "hi"}, "--all-test-data and --row require --file"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				client, requests := newClient()
+				out := new(bytes.Buffer)
+				cmd := NewRunCommand(command.NewConfig(out, out, client, true, 100))
+				cmd.SetArgs(tt.args)
+
+				err := cmd.Execute()
+				require.EqualError(t, err, tt.expected)
+				require.Empty(t, *requests)
+			})
+		}
+	})
+
+	t.Run("--strict-vars reports undefined variables", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			args     []string
+			expected string
+		}{
+			{"in a row", []string{"--file", promptFile, "--all-test-data", "--strict-vars"}, "row 1: undefined template variable: tone (line 1)"},
+			{"without rows", []string{"--file", promptFile, "--strict-vars", "--var", "tone=calm"}, "undefined template variable: name (line 1)"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				client, requests := newClient()
+				out := new(bytes.Buffer)
+				cmd := NewRunCommand(command.NewConfig(out, out, client, true, 100))
+				cmd.SetArgs(tt.args)
+
+				err := cmd.Execute()
+				require.EqualError(t, err, tt.expected)
+				require.Empty(t, *requests)
+			})
+		}
+
+		client, requests := newClient()
+		out := new(bytes.Buffer)
+		cmd := NewRunCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--file", promptFile, "--row", "2", "--strict-vars"})
+		require.NoError(t, cmd.Execute())
+		require.Len(t, *requests, 1)
+	})
+}
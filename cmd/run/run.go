@@ -137,6 +137,8 @@ func (mp *ModelParameters) UpdateRequest(req *azuremodels.ChatCompletionOptions)
 type Conversation struct {
 	messages     []azuremodels.ChatMessage
 	systemPrompt string
+	// summary is a model-written summary of older messages that were compacted.
+	summary string
 }
 
 // AddMessage adds a message to the conversation.
@@ -153,18 +155,29 @@ func (c *Conversation) GetMessages() []azuremodels.ChatMessage {
 	if c.systemPrompt != "" {
 		length++
 	}
+	if c.summary != "" {
+		length++
+	}
 
 	messages := make([]azuremodels.ChatMessage, length)
 	startIndex := 0
 
 	if c.systemPrompt != "" {
-		messages[0] = azuremodels.ChatMessage{
+		messages[startIndex] = azuremodels.ChatMessage{
 			Content: util.Ptr(c.systemPrompt),
 			Role:    azuremodels.ChatMessageRoleSystem,
 		}
 		startIndex++
 	}
 
+	if c.summary != "" {
+		messages[startIndex] = azuremodels.ChatMessage{
+			Content: util.Ptr("Summary of the earlier conversation:\n" + c.summary),
+			Role:    azuremodels.ChatMessageRoleSystem,
+		}
+		startIndex++
+	}
+
 	for i, message := range c.messages {
 		messages[startIndex+i] = message
 	}
@@ -172,12 +185,53 @@ func (c *Conversation) GetMessages() []azuremodels.ChatMessage {
 	return messages
 }
 
+// AddPromptFileMessages templates the messages of the prompt file with the given data and adds them
+// to the conversation. A system message from the file replaces the conversation's system prompt.
+func (c *Conversation) AddPromptFileMessages(pf *prompt.File, templateData map[string]interface{}, opts ...prompt.TemplateOption) error {
+	templateData, err := pf.ResolveVariables(templateData)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range pf.Messages {
+		content, err := prompt.TemplateString(m.Content, templateData, opts...)
+		if err != nil {
+			return err
+		}
+
+		role, err := prompt.GetAzureChatMessageRole(m.Role)
+		if err != nil {
+			return err
+		}
+
+		switch role {
+		case azuremodels.ChatMessageRoleSystem:
+			c.systemPrompt = content
+		case azuremodels.ChatMessageRoleUser:
+			c.AddMessage(azuremodels.ChatMessageRoleUser, content)
+		case azuremodels.ChatMessageRoleAssistant:
+			c.AddMessage(azuremodels.ChatMessageRoleAssistant, content)
+		}
+	}
+	return nil
+}
+
 // Reset removes messages from the conversation.
 func (c *Conversation) Reset() {
 	c.messages = nil
+	c.summary = ""
 }
 
-func isPipe(r io.Reader) bool {
+// ChatSession holds the state of an interactive chat that can change between turns.
+type ChatSession struct {
+	Conversation *Conversation
+	Parameters   *ModelParameters
+	Context      *ContextManager
+	ModelName    string
+}
+
+// IsPipe reports whether r is a pipe, such as stdin when another command's output is piped in.
+func IsPipe(r io.Reader) bool {
 	if f, ok := r.(*os.File); ok {
 		stat, err := f.Stat()
 		if err != nil {
@@ -208,27 +262,84 @@ func NewRunCommand(cfg *command.Config) *cobra.Command {
 			When using prompt files, you can pass template variables using the %[1]s--var%[1]s flag:
 			%[1]sgh models run --file prompt.yml --var name=Alice --var topic=AI%[1]s
 
+			A variable's value can also be read from a file with %[1]s--var name=@path%[1]s (use %[1]s@@%[1]s for a literal %[1]s@%[1]s)
+			or from stdin with %[1]s--var name=-%[1]s. Use %[1]s--var-file%[1]s to load many variables from a YAML, JSON or
+			.env file; values passed with %[1]s--var%[1]s take precedence. With %[1]s--allow-env%[1]s, %[1]s${env:NAME}%[1]s references
+			in the prompt file are replaced with the values of environment variables (write %[1]s$${env:NAME}%[1]s to keep
+			the reference as is).
+
+			Prompt file messages support a subset of Mustache: %[1]s{{user.name}}%[1]s for nested values,
+			%[1]s{{#items}}...{{/items}}%[1]s sections to loop over lists or render text when a value is set,
+			%[1]s{{^items}}...{{/items}}%[1]s for the opposite, and %[1]s{{name | default: "none"}}%[1]s for fallbacks. Undefined
+			variables are left in the message as is, unless %[1]s--strict-vars%[1]s is set.
+
+			Prompt files can declare their variables in a %[1]svariables%[1]s section, with a type, a description,
+			a default and whether they are required. Missing required variables and values of the wrong
+			type are reported before the model is called. Use %[1]s--describe%[1]s to list the variables of a
+			prompt file without running it.
+
 			When running inference against an organization, pass the organization name using the %[1]s--org%[1]s flag:
 			%[1]sgh models run --org my-org openai/gpt-4o-mini "What is AI?"%[1]s
 
+			For scripting, use %[1]s--json%[1]s to print a single JSON object containing the model ID, final content,
+			finish reason, token usage, latency and request parameters. The %[1]s--jq%[1]s and %[1]s--template%[1]s flags
+			filter or format that object. Use %[1]s--jsonl-stream%[1]s to print each streamed chunk as a line of JSON
+			followed by a final result line.
+
+			Long interactive conversations can exceed a model's context window. Use %[1]s--context-strategy%[1]s to
+			drop the oldest messages once a token budget is reached (%[1]ssliding-window%[1]s), keep only the last N
+			messages (%[1]slast-n%[1]s), or have the model summarize older turns (%[1]ssummarize%[1]s). The %[1]s/compact%[1]s
+			command summarizes older turns on demand.
+
+			When the prompt file has %[1]stestData%[1]s, use %[1]s--all-test-data%[1]s or %[1]s--row%[1]s to run the prompt once per
+			row, templating each row's values into the messages. Values passed with %[1]s--var%[1]s are used for
+			variables that a row does not define. Use %[1]s--output-dir%[1]s to also write each response to a file.
+
+			Use %[1]s--export-prompt%[1]s or the %[1]s/export-prompt%[1]s command to save the conversation as a .prompt.yml
+			file that can be used with %[1]sgh models eval%[1]s. With %[1]s--export-input%[1]s, the last user message is
+			replaced with an %[1]s{{input}}%[1]s placeholder and each turn is added to the file's test data.
+
+			When a prompt file uses %[1]sresponseFormat: json_schema%[1]s, the response is validated against the schema
+			and the command fails if it does not conform. Use %[1]s--schema-retries%[1]s to send the validation errors
+			back to the model and ask it to fix its response. The response is then printed once it is final, rather
+			than as it streams.
+
 			The return value will be the response to your prompt from the selected model.
 		`, "`"),
 		Example: heredoc.Doc(`
 			gh models run openai/gpt-4o-mini "how many types of hyena are there?"
 			gh models run --org my-org openai/gpt-4o-mini "how many types of hyena are there?"
 			gh models run --file prompt.yml --var name=Alice --var topic="machine learning"
+			gh models run --file prompt.yml --describe
+			gh models run --file prompt.yml --var-file vars.env --var document=@notes.md
+			git diff | gh models run --file review.prompt.yml --var diff=-
+			gh models run --json openai/gpt-4o-mini "how many types of hyena are there?"
+			gh models run --jq .content openai/gpt-4o-mini "how many types of hyena are there?"
+			gh models run --file prompt.yml --all-test-data --output-dir responses
+			gh models run --export-prompt hyenas.prompt.yml --export-input openai/gpt-4o-mini "how many types of hyena are there?"
 		`),
 		Args: cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			filePath, _ := cmd.Flags().GetString("file")
 			org, _ := cmd.Flags().GetString("org")
+			allowEnv, _ := cmd.Flags().GetBool("allow-env")
 			var pf *prompt.File
+			if describe, _ := cmd.Flags().GetBool("describe"); describe && filePath == "" {
+				return errors.New("--describe requires --file")
+			}
 			if filePath != "" {
+				var loadOpts []prompt.LoadOption
+				if allowEnv {
+					loadOpts = append(loadOpts, prompt.WithEnvInterpolation())
+				}
 				var err error
-				pf, err = prompt.LoadFromFile(filePath)
+				pf, err = prompt.LoadFromFile(filePath, loadOpts...)
 				if err != nil {
 					return err
 				}
+				if describe, _ := cmd.Flags().GetBool("describe"); describe {
+					return describePromptFile(cfg, filePath, pf)
+				}
 				// Inject model name as the first positional arg if user didn't supply one
 				if pf.Model != "" && len(args) == 0 {
 					args = append([]string{pf.Model}, args...)
@@ -241,10 +352,29 @@ func NewRunCommand(cfg *command.Config) *cobra.Command {
 				return err
 			}
 
+			var templateOpts []prompt.TemplateOption
+			if strictVars, _ := cmd.Flags().GetBool("strict-vars"); strictVars {
+				templateOpts = append(templateOpts, prompt.WithStrictVariables())
+			}
+
+			outputOpts, err := parseOutputOptions(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			rows, err := parseTestDataRows(cmd.Flags(), pf)
+			if err != nil {
+				return err
+			}
+			if len(rows) > 0 && outputOpts.jsonlStream {
+				return errors.New("--jsonl-stream cannot be used with --all-test-data or --row")
+			}
+
 			cmdHandler := newRunCommandHandler(cmd, cfg, args)
 			if cmdHandler == nil {
 				return nil
 			}
+			cmdHandler.org = org
 
 			models, err := cmdHandler.loadModels()
 			if err != nil {
@@ -265,7 +395,7 @@ func NewRunCommand(cfg *command.Config) *cobra.Command {
 				interactiveMode = false
 			}
 
-			if isPipe(os.Stdin) {
+			if IsPipe(os.Stdin) {
 				promptFromPipe, _ := io.ReadAll(os.Stdin)
 				if len(promptFromPipe) > 0 {
 					interactiveMode = false
@@ -283,6 +413,23 @@ func NewRunCommand(cfg *command.Config) *cobra.Command {
 				return err
 			}
 
+			exportPath, err := cmd.Flags().GetString("export-prompt")
+			if err != nil {
+				return err
+			}
+			exportInput, err := cmd.Flags().GetBool("export-input")
+			if err != nil {
+				return err
+			}
+
+			schemaRetries, err := cmd.Flags().GetInt("schema-retries")
+			if err != nil {
+				return err
+			}
+			if schemaRetries < 0 {
+				return errors.New("--schema-retries must not be negative")
+			}
+
 			conversation := Conversation{
 				systemPrompt: systemPrompt,
 			}
@@ -292,7 +439,7 @@ func NewRunCommand(cfg *command.Config) *cobra.Command {
 			// using the provided template variables and initialPrompt.
 			if pf == nil {
 				conversation.AddMessage(azuremodels.ChatMessageRoleUser, initialPrompt)
-			} else {
+			} else if len(rows) == 0 {
 				interactiveMode = false
 
 				// Template the messages with the variables
@@ -306,26 +453,16 @@ func NewRunCommand(cfg *command.Config) *cobra.Command {
 					templateData[key] = value
 				}
 
-				for _, m := range pf.Messages {
-					content, err := prompt.TemplateString(m.Content, templateData)
-					if err != nil {
-						return err
-					}
-
-					role, err := prompt.GetAzureChatMessageRole(m.Role)
-					if err != nil {
-						return err
-					}
-
-					switch role {
-					case azuremodels.ChatMessageRoleSystem:
-						conversation.systemPrompt = content
-					case azuremodels.ChatMessageRoleUser:
-						conversation.AddMessage(azuremodels.ChatMessageRoleUser, content)
-					case azuremodels.ChatMessageRoleAssistant:
-						conversation.AddMessage(azuremodels.ChatMessageRoleAssistant, content)
-					}
+				if err := conversation.AddPromptFileMessages(pf, templateData, templateOpts...); err != nil {
+					return err
 				}
+			} else {
+				// The messages are templated with the values of each row
+				interactiveMode = false
+			}
+
+			if interactiveMode && outputOpts.isStructured() {
+				return errors.New("--json, --jq, --template and --jsonl-stream require a prompt, piped input or --file")
 			}
 
 			mp := ModelParameters{}
@@ -341,88 +478,143 @@ func NewRunCommand(cfg *command.Config) *cobra.Command {
 				return err
 			}
 
+			if len(rows) > 0 {
+				if exportPath != "" {
+					return errors.New("--export-prompt cannot be used with --all-test-data or --row")
+				}
+				outputDir, err := cmd.Flags().GetString("output-dir")
+				if err != nil {
+					return err
+				}
+				err = cmdHandler.runTestData(testDataRun{
+					pf:            pf,
+					rows:          rows,
+					input:         initialPrompt,
+					templateVars:  templateVars,
+					templateOpts:  templateOpts,
+					systemPrompt:  systemPrompt,
+					parameters:    &mp,
+					modelName:     modelName,
+					schemaRetries: schemaRetries,
+					outputDir:     outputDir,
+				}, outputOpts)
+				if err != nil {
+					cmd.SilenceUsage = true
+				}
+				return err
+			}
+
+			contextManager := NewContextManager()
+			err = contextManager.PopulateFromFlags(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			session := &ChatSession{
+				Conversation: &conversation,
+				Parameters:   &mp,
+				Context:      contextManager,
+				ModelName:    modelName,
+			}
+
 			for {
 				if interactiveMode {
-					conversation, err = cmdHandler.ChatWithUser(conversation, mp)
+					sent, err := cmdHandler.ChatWithUser(session)
 					if errors.Is(err, ErrExitChat) || errors.Is(err, io.EOF) {
 						break
 					} else if err != nil {
 						return err
 					}
+					if !sent {
+						continue
+					}
+				}
+
+				if session.Context.NeedsCompaction(&conversation) {
+					count, err := cmdHandler.compactConversation(session)
+					if err != nil {
+						return err
+					}
+					util.WriteToOut(cfg.ErrOut, fmt.Sprintf("Compacted %d messages into a summary\n", count))
 				}
+				messages := session.Context.Apply(conversation.GetMessages())
 
 				var req azuremodels.ChatCompletionOptions
 				if pf != nil {
 					// Use the prompt file's BuildChatCompletionOptions method to include responseFormat and jsonSchema
-					req = pf.BuildChatCompletionOptions(conversation.GetMessages())
+					req = pf.BuildChatCompletionOptions(messages)
 					// Override the model name if provided via CLI
-					req.Model = modelName
+					req.Model = session.ModelName
 				} else {
 					req = azuremodels.ChatCompletionOptions{
-						Messages: conversation.GetMessages(),
-						Model:    modelName,
+						Messages: messages,
+						Model:    session.ModelName,
 					}
 				}
 
 				mp.UpdateRequest(&req)
+				if outputOpts.isStructured() {
+					req.StreamOptions = &azuremodels.StreamOptions{IncludeUsage: true}
+				}
 
-				sp := spinner.New(spinner.CharSets[14], 100*time.Millisecond, spinner.WithWriter(cmdHandler.cfg.ErrOut))
-				sp.Start()
-				//nolint:gocritic,revive // TODO
-				defer sp.Stop()
-
-				reader, err := cmdHandler.getChatCompletionStreamReader(req, org)
+				result, err := cmdHandler.runCheckedCompletion(pf, req, schemaRetries, org, outputOpts)
 				if err != nil {
 					return err
 				}
-				//nolint:gocritic,revive // TODO
-				defer reader.Close()
 
-				messageBuilder := strings.Builder{}
+				conversation.AddMessage(azuremodels.ChatMessageRoleAssistant, result.Content)
 
-				for {
-					completion, err := reader.Read()
-					if err != nil {
-						if errors.Is(err, io.EOF) {
-							break
-						}
+				if outputOpts.json {
+					if err := cmdHandler.writeResult(result, outputOpts); err != nil {
 						return err
 					}
-
-					sp.Stop()
-
-					for _, choice := range completion.Choices {
-						err = cmdHandler.handleCompletionChoice(choice, messageBuilder)
-						if err != nil {
-							return err
-						}
-					}
 				}
 
-				cmdHandler.writeToOut("\n")
-				_, err = messageBuilder.WriteString("\n")
-				if err != nil {
-					return err
+				if len(result.SchemaViolations) > 0 {
+					cmd.SilenceUsage = true
+					return &prompt.SchemaValidationError{Violations: result.SchemaViolations}
 				}
 
-				conversation.AddMessage(azuremodels.ChatMessageRoleAssistant, messageBuilder.String())
-
 				if !interactiveMode {
 					break
 				}
 			}
 
+			if exportPath != "" {
+				if err := cmdHandler.exportPrompt(session, exportPath, exportInput); err != nil {
+					return fmt.Errorf("failed to export prompt: %w", err)
+				}
+				util.WriteToOut(cfg.ErrOut, fmt.Sprintf("Exported conversation to %s\n", exportPath))
+			}
+
 			return nil
 		},
 	}
 
 	cmd.Flags().String("file", "", "Path to a .prompt.yml file.")
-	cmd.Flags().StringArray("var", []string{}, "Template variables for prompt files (can be used multiple times: --var name=value)")
+	cmd.Flags().StringArray("var", []string{}, "Template variables for prompt files (can be used multiple times: --var name=value, --var name=@file or --var name=- for stdin)")
+	cmd.Flags().StringArray("var-file", []string{}, "Load template variables from a YAML, JSON or .env file (can be used multiple times)")
+	cmd.Flags().Bool("allow-env", false, "Replace ${env:NAME} references in the prompt file with the values of environment variables")
+	cmd.Flags().Bool("strict-vars", false, "Fail if the prompt file references a template variable that is not set")
+	cmd.Flags().Bool("describe", false, "Print the name, model and template variables of the prompt file without running it")
 	cmd.Flags().String("max-tokens", "", "Limit the maximum tokens for the model response.")
 	cmd.Flags().String("temperature", "", "Controls randomness in the response, use lower to be more deterministic.")
 	cmd.Flags().String("top-p", "", "Controls text diversity by selecting the most probable words until a set probability is reached.")
 	cmd.Flags().String("system-prompt", "", "Prompt the system.")
 	cmd.Flags().String("org", "", "Organization to attribute usage to (omitting will attribute usage to the current actor")
+	cmd.Flags().Bool("all-test-data", false, "Run the prompt file once for each of its testData rows")
+	cmd.Flags().IntSlice("row", nil, "Run the prompt file for the given testData rows, starting at 1 (can be used multiple times: --row 1 --row 3)")
+	cmd.Flags().String("output-dir", "", "Write the response for each testData row to a row-<n>.txt file in this directory")
+	cmd.Flags().String("export-prompt", "", "Save the conversation to a .prompt.yml file when the command finishes")
+	cmd.Flags().Bool("export-input", false, "Replace the last user message of the exported prompt with an {{input}} placeholder and seed testData from the conversation")
+	cmd.Flags().Int("schema-retries", 0, "Number of times to ask the model to fix a response that does not match the prompt file's JSON schema")
+	cmd.Flags().String("context-strategy", "", "How to keep long conversations within the context window: full, sliding-window, last-n or summarize (default: full)")
+	cmd.Flags().Int("context-max-tokens", 0, fmt.Sprintf("Token budget for the sliding-window and summarize context strategies (default: %d)", defaultContextMaxTokens))
+	cmd.Flags().Int("context-keep-last", 0, fmt.Sprintf("Number of messages kept by the last-n context strategy (default: %d)", defaultContextKeepLast))
+	cmd.Flags().Bool("json", false, "Output the response as a JSON object with the content, finish reason, token usage and latency.")
+	cmd.Flags().StringP("jq", "q", "", "Filter JSON output using a jq expression.")
+	cmd.Flags().StringP("template", "t", "", "Format JSON output using a Go template.")
+	cmd.Flags().Bool("jsonl-stream", false, "Output each streamed chunk as a line of JSON.")
 
 	return cmd
 }
@@ -432,6 +624,9 @@ type runCommandHandler struct {
 	cfg    *command.Config
 	client azuremodels.Client
 	args   []string
+	org    string
+	models []*azuremodels.ModelSummary
+	stdin  *bufio.Reader
 }
 
 func newRunCommandHandler(cmd *cobra.Command, cfg *command.Config, args []string) *runCommandHandler {
@@ -446,6 +641,7 @@ func (h *runCommandHandler) loadModels() ([]*azuremodels.ModelSummary, error) {
 	}
 
 	azuremodels.SortModels(models)
+	h.models = models
 	return models, nil
 }
 
@@ -455,20 +651,8 @@ func (h *runCommandHandler) getModelNameFromArgs(models []*azuremodels.ModelSumm
 	switch {
 	case len(h.args) == 0:
 		// Need to prompt for a model
-		prompt := &survey.Select{
-			Message: "Select a model:",
-			Options: []string{},
-		}
-
-		for _, model := range models {
-			if !model.IsChatModel() {
-				continue
-			}
-
-			prompt.Options = append(prompt.Options, model.ID)
-		}
-
-		err := survey.AskOne(prompt, &modelName, survey.WithPageSize(10))
+		var err error
+		modelName, err = selectModel(models, "")
 		if err != nil {
 			return "", err
 		}
@@ -477,10 +661,38 @@ func (h *runCommandHandler) getModelNameFromArgs(models []*azuremodels.ModelSumm
 		modelName = h.args[0]
 	}
 
-	return validateModelName(modelName, models)
+	return ValidateModelName(modelName, models)
+}
+
+// selectModel prompts the user to pick one of the given chat models.
+func selectModel(models []*azuremodels.ModelSummary, current string) (string, error) {
+	prompt := &survey.Select{
+		Message: "Select a model:",
+		Options: []string{},
+	}
+
+	for _, model := range models {
+		if !model.IsChatModel() {
+			continue
+		}
+
+		prompt.Options = append(prompt.Options, model.ID)
+		if model.HasName(current) {
+			prompt.Default = model.ID
+		}
+	}
+
+	modelName := ""
+	err := survey.AskOne(prompt, &modelName, survey.WithPageSize(10))
+	if err != nil {
+		return "", err
+	}
+	return modelName, nil
 }
 
-func validateModelName(modelName string, models []*azuremodels.ModelSummary) (string, error) {
+// ValidateModelName returns the canonical name of a model, or an error if it is not one of models.
+// Models of the custom provider are not validated.
+func ValidateModelName(modelName string, models []*azuremodels.ModelSummary) (string, error) {
 	noMatchErrorMessage := fmt.Sprintf("The specified model '%s' is not found. Run 'gh models list' to see available models or 'gh models run' to select interactively.", modelName)
 
 	if modelName == "" {
@@ -522,33 +734,49 @@ func (h *runCommandHandler) getChatCompletionStreamReader(req azuremodels.ChatCo
 	return resp.Reader, nil
 }
 
-func (h *runCommandHandler) handleParametersPrompt(conversation Conversation, mp ModelParameters) {
+func (h *runCommandHandler) handleParametersPrompt(session *ChatSession) {
+	h.writeToOut("Model:\n")
+	h.writeToOut("  " + session.ModelName + "\n")
+	h.writeToOut("\n")
 	h.writeToOut("Current parameters:\n")
 	names := []string{"max-tokens", "temperature", "top-p"}
 	for _, name := range names {
-		h.writeToOut(fmt.Sprintf("  %s: %s\n", name, mp.FormatParameter(name)))
+		h.writeToOut(fmt.Sprintf("  %s: %s\n", name, session.Parameters.FormatParameter(name)))
 	}
 	h.writeToOut("\n")
 	h.writeToOut("System Prompt:\n")
-	if conversation.systemPrompt != "" {
-		h.writeToOut("  " + conversation.systemPrompt + "\n")
+	if session.Conversation.systemPrompt != "" {
+		h.writeToOut("  " + session.Conversation.systemPrompt + "\n")
 	} else {
 		h.writeToOut("  <not set>\n")
 	}
+	h.writeToOut("\n")
+	messages := session.Conversation.GetMessages()
+	sent := session.Context.Apply(messages)
+	h.writeToOut("Context:\n")
+	h.writeToOut(fmt.Sprintf("  context-strategy: %s\n", session.Context.Strategy))
+	h.writeToOut(fmt.Sprintf("  context-max-tokens: %d\n", session.Context.MaxTokens))
+	h.writeToOut(fmt.Sprintf("  context-keep-last: %d\n", session.Context.KeepLast))
+	h.writeToOut(fmt.Sprintf("  estimated tokens: %d of %d messages sent (%d total)\n", EstimateTokens(sent), len(sent), EstimateTokens(messages)))
 }
 
-func (h *runCommandHandler) handleResetPrompt(conversation Conversation) {
+func (h *runCommandHandler) handleResetPrompt(conversation *Conversation) {
 	conversation.Reset()
 	h.writeToOut("Reset chat history\n")
 }
 
-func (h *runCommandHandler) handleSetPrompt(prompt string, mp ModelParameters) {
-	parts := strings.Split(prompt, " ")
+func (h *runCommandHandler) handleSetPrompt(prompt string, session *ChatSession) {
+	parts := strings.Fields(prompt)
 	if len(parts) == 3 {
 		name := parts[1]
 		value := parts[2]
 
-		err := mp.SetParameterByName(name, value)
+		var err error
+		if strings.HasPrefix(name, "context-") {
+			err = session.Context.SetParameterByName(name, value)
+		} else {
+			err = session.Parameters.SetParameterByName(name, value)
+		}
 		if err != nil {
 			h.writeToOut(err.Error() + "\n")
 			return
@@ -560,18 +788,42 @@ func (h *runCommandHandler) handleSetPrompt(prompt string, mp ModelParameters) {
 	}
 }
 
-func (h *runCommandHandler) handleSystemPrompt(prompt string, conversation Conversation) Conversation {
+func (h *runCommandHandler) handleSystemPrompt(prompt string, conversation *Conversation) {
 	conversation.systemPrompt = strings.Trim(strings.TrimPrefix(prompt, "/system-prompt "), "\"")
 	h.writeToOut("Updated system prompt\n")
-	return conversation
+}
+
+// handleModelPrompt switches the model used for the rest of the conversation, keeping the chat history.
+func (h *runCommandHandler) handleModelPrompt(prompt string, session *ChatSession) {
+	modelName := strings.TrimSpace(strings.TrimPrefix(prompt, "/model"))
+	if modelName == "" {
+		var err error
+		modelName, err = selectModel(h.models, session.ModelName)
+		if err != nil {
+			h.writeToOut(err.Error() + "\n")
+			return
+		}
+	}
+
+	modelName, err := ValidateModelName(modelName, h.models)
+	if err != nil {
+		h.writeToOut(err.Error() + "\n")
+		return
+	}
+
+	session.ModelName = modelName
+	h.writeToOut("Switched model to " + modelName + "\n")
 }
 
 func (h *runCommandHandler) handleHelpPrompt() {
 	h.writeToOut("Commands:\n")
 	h.writeToOut("  /bye, /exit, /quit - Exit the chat\n")
+	h.writeToOut("  /compact - Summarize older messages to free up context\n")
+	h.writeToOut("  /export-prompt <file> [--input] - Save the conversation as a .prompt.yml file, optionally templating the last user message as {{input}}\n")
+	h.writeToOut("  /model [model] - Switch to another model, keeping the chat history\n")
 	h.writeToOut("  /parameters - Show current model parameters\n")
 	h.writeToOut("  /reset, /clear - Reset chat context\n")
-	h.writeToOut("  /set <name> <value> - Set a model parameter\n")
+	h.writeToOut("  /set <name> <value> - Set a model or context parameter\n")
 	h.writeToOut("  /system-prompt <prompt> - Set the system prompt\n")
 	h.writeToOut("  /help - Show this help message\n")
 }
@@ -580,87 +832,253 @@ func (h *runCommandHandler) handleUnrecognizedPrompt(prompt string) {
 	h.writeToOut("Unknown command '" + prompt + "'. See /help for supported commands.\n")
 }
 
-func (h *runCommandHandler) handleCompletionChoice(choice azuremodels.ChatChoice, messageBuilder strings.Builder) error {
+// runCompletion sends the request to the model and streams the response according to the output options.
+func (h *runCommandHandler) runCompletion(req azuremodels.ChatCompletionOptions, org string, opts outputOptions) (*RunResult, error) {
+	var sp *spinner.Spinner
+	if !opts.isStructured() {
+		sp = spinner.New(spinner.CharSets[14], 100*time.Millisecond, spinner.WithWriter(h.cfg.ErrOut))
+		sp.Start()
+		defer sp.Stop()
+	}
+
+	start := time.Now()
+	reader, err := h.getChatCompletionStreamReader(req, org)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := &RunResult{
+		Model:      req.Model,
+		Parameters: newRunParameters(req),
+	}
+	messageBuilder := strings.Builder{}
+
+	for {
+		completion, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+
+		if sp != nil {
+			sp.Stop()
+		}
+
+		if completion.Usage != nil {
+			result.Usage = completion.Usage
+		}
+
+		for _, choice := range completion.Choices {
+			if choice.FinishReason != "" {
+				result.FinishReason = choice.FinishReason
+			}
+
+			if opts.jsonlStream {
+				err = h.handleStreamedChoice(choice, req.Model, &messageBuilder)
+			} else {
+				err = h.handleCompletionChoice(choice, &messageBuilder, !opts.json && !opts.buffer)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if opts.jsonlStream && completion.Usage != nil && len(completion.Choices) == 0 {
+			if err := h.writeStreamChunk(RunStreamChunk{Type: "chunk", Model: req.Model, Usage: completion.Usage}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result.Content = messageBuilder.String()
+	result.LatencyMs = time.Since(start).Milliseconds()
+
+	switch {
+	case opts.jsonlStream:
+		if err := h.writeStreamChunk(RunStreamChunk{Type: "result", Model: req.Model, Result: result}); err != nil {
+			return nil, err
+		}
+	case !opts.json && !opts.buffer:
+		h.writeToOut("\n")
+	}
+
+	return result, nil
+}
+
+// runCheckedCompletion runs the completion, and repairs the response when it does not match the JSON
+// schema of the prompt file, if there is one. Text responses that may be repaired are printed once the
+// final response is known, so that only that response is written to the output.
+func (h *runCommandHandler) runCheckedCompletion(pf *prompt.File, req azuremodels.ChatCompletionOptions, retries int, org string, opts outputOptions) (*RunResult, error) {
+	if pf == nil {
+		return h.runCompletion(req, org, opts)
+	}
+
+	buffered := !opts.isStructured() && retries > 0 && pf.ResponseSchema() != nil
+	runOpts := opts
+	runOpts.buffer = buffered
+	result, err := h.runCompletion(req, org, runOpts)
+	if err != nil {
+		return nil, err
+	}
+	result, err = h.repairResponse(pf, req, result, retries, org, runOpts)
+	if err != nil {
+		return nil, err
+	}
+	if buffered {
+		h.writeToOut(result.Content + "\n")
+	}
+	return result, nil
+}
+
+// repairResponse validates the result against the prompt file's JSON schema. If the response does not
+// conform, the model is asked to fix it up to retries times. Remaining violations are recorded on the result.
+func (h *runCommandHandler) repairResponse(pf *prompt.File, req azuremodels.ChatCompletionOptions, result *RunResult, retries int, org string, opts outputOptions) (*RunResult, error) {
+	messages := append([]azuremodels.ChatMessage(nil), req.Messages...)
+	for attempt := 0; ; attempt++ {
+		violations := pf.ValidateResponse(result.Content)
+		if len(violations) == 0 || attempt >= retries {
+			result.SchemaViolations = violations
+			return result, nil
+		}
+
+		util.WriteToOut(h.cfg.ErrOut, fmt.Sprintf("Response does not match the JSON schema, asking the model to fix it (attempt %d/%d)\n", attempt+1, retries))
+
+		messages = append(messages,
+			azuremodels.ChatMessage{Role: azuremodels.ChatMessageRoleAssistant, Content: util.Ptr(result.Content)},
+			azuremodels.ChatMessage{Role: azuremodels.ChatMessageRoleUser, Content: util.Ptr(prompt.SchemaRepairPrompt(violations))},
+		)
+		req.Messages = messages
+
+		var err error
+		result, err = h.runCompletion(req, org, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// choiceContent returns the content of a completion choice, if any.
+func choiceContent(choice azuremodels.ChatChoice) *string {
 	// Streamed responses from the OpenAI API have their data in `.Delta`, while
 	// non-streamed responses use `.Message`, so let's support both
 	if choice.Delta != nil && choice.Delta.Content != nil {
-		content := choice.Delta.Content
-		_, err := messageBuilder.WriteString(*content)
-		if err != nil {
-			return err
-		}
-		h.writeToOut(*content)
+		return choice.Delta.Content
 	} else if choice.Message != nil && choice.Message.Content != nil {
-		content := choice.Message.Content
+		return choice.Message.Content
+	}
+	return nil
+}
+
+func (h *runCommandHandler) handleCompletionChoice(choice azuremodels.ChatChoice, messageBuilder *strings.Builder, print bool) error {
+	if content := choiceContent(choice); content != nil {
 		_, err := messageBuilder.WriteString(*content)
 		if err != nil {
 			return err
 		}
-		h.writeToOut(*content)
+		if print {
+			h.writeToOut(*content)
+		}
 	}
 
 	// Introduce a small delay in between response tokens to better simulate a conversation
-	if h.cfg.IsTerminalOutput {
+	if print && h.cfg.IsTerminalOutput {
 		time.Sleep(10 * time.Millisecond)
 	}
 
 	return nil
 }
 
+func (h *runCommandHandler) handleStreamedChoice(choice azuremodels.ChatChoice, model string, messageBuilder *strings.Builder) error {
+	chunk := RunStreamChunk{Type: "chunk", Model: model, FinishReason: choice.FinishReason}
+	if content := choiceContent(choice); content != nil {
+		_, err := messageBuilder.WriteString(*content)
+		if err != nil {
+			return err
+		}
+		chunk.Content = *content
+	}
+	if chunk.Content == "" && chunk.FinishReason == "" {
+		return nil
+	}
+	return h.writeStreamChunk(chunk)
+}
+
 func (h *runCommandHandler) writeToOut(message string) {
 	h.cfg.WriteToOut(message)
 }
 
 var ErrExitChat = errors.New("exiting chat")
 
-func (h *runCommandHandler) ChatWithUser(conversation Conversation, mp ModelParameters) (Conversation, error) {
+// ChatWithUser reads the next line of input from the user and applies it to the session.
+// It returns true if a new user message was added to the conversation and should be sent to the model.
+func (h *runCommandHandler) ChatWithUser(session *ChatSession) (bool, error) {
 	fmt.Printf(">>> ")
-	reader := bufio.NewReader(os.Stdin)
+	if h.stdin == nil {
+		h.stdin = bufio.NewReader(os.Stdin)
+	}
 
-	prompt, err := reader.ReadString('\n')
+	prompt, err := h.stdin.ReadString('\n')
 	if err != nil {
-		return conversation, err
+		return false, err
 	}
 
 	prompt = strings.TrimSpace(prompt)
 	if prompt == "" {
-		return conversation, nil
+		return false, nil
 	}
 
 	if strings.HasPrefix(prompt, "/") {
 		if prompt == "/bye" || prompt == "/exit" || prompt == "/quit" {
-			return conversation, ErrExitChat
+			return false, ErrExitChat
 		}
 
 		if prompt == "/parameters" {
-			h.handleParametersPrompt(conversation, mp)
-			return conversation, nil
+			h.handleParametersPrompt(session)
+			return false, nil
 		}
 
 		if prompt == "/reset" || prompt == "/clear" {
-			h.handleResetPrompt(conversation)
-			return conversation, nil
+			h.handleResetPrompt(session.Conversation)
+			return false, nil
 		}
 
 		if strings.HasPrefix(prompt, "/set ") {
-			h.handleSetPrompt(prompt, mp)
-			return conversation, nil
+			h.handleSetPrompt(prompt, session)
+			return false, nil
+		}
+
+		if prompt == "/export-prompt" || strings.HasPrefix(prompt, "/export-prompt ") {
+			h.handleExportPrompt(prompt, session)
+			return false, nil
+		}
+
+		if prompt == "/compact" {
+			h.handleCompactPrompt(session)
+			return false, nil
+		}
+
+		if prompt == "/model" || strings.HasPrefix(prompt, "/model ") {
+			h.handleModelPrompt(prompt, session)
+			return false, nil
 		}
 
 		if strings.HasPrefix(prompt, "/system-prompt ") {
-			conversation = h.handleSystemPrompt(prompt, conversation)
-			return conversation, nil
+			h.handleSystemPrompt(prompt, session.Conversation)
+			return false, nil
 		}
 
 		if prompt == "/help" {
 			h.handleHelpPrompt()
-			return conversation, nil
+			return false, nil
 		}
 
 		h.handleUnrecognizedPrompt(prompt)
-		return conversation, nil
+		return false, nil
 	}
 
-	conversation.AddMessage(azuremodels.ChatMessageRoleUser, prompt)
-	return conversation, nil
+	session.Conversation.AddMessage(azuremodels.ChatMessageRoleUser, prompt)
+	return true, nil
 }
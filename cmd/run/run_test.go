@@ -1,15 +1,20 @@
 package run
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/github/gh-models/internal/azuremodels"
 	"github.com/github/gh-models/internal/sse"
 	"github.com/github/gh-models/pkg/command"
+	"github.com/github/gh-models/pkg/prompt"
 	"github.com/github/gh-models/pkg/util"
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/require"
@@ -62,6 +67,123 @@ func TestRun(t *testing.T) {
 		require.Contains(t, output, fakeMessageFromModel)
 	})
 
+	t.Run("--json prints a structured result", func(t *testing.T) {
+		client := azuremodels.NewMockClient()
+		modelSummary := &azuremodels.ModelSummary{
+			ID:        "openai/test-model",
+			Name:      "test-model",
+			Publisher: "openai",
+			Task:      "chat-completion",
+		}
+		client.MockListModels = func(ctx context.Context) ([]*azuremodels.ModelSummary, error) {
+			return []*azuremodels.ModelSummary{modelSummary}, nil
+		}
+		var capturedReq azuremodels.ChatCompletionOptions
+		client.MockGetChatCompletionStream = func(ctx context.Context, opt azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+			capturedReq = opt
+			return &azuremodels.ChatCompletionResponse{
+				Reader: sse.NewMockEventReader([]azuremodels.ChatCompletion{
+					{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: util.Ptr("hello ")}}}},
+					{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: util.Ptr("world")}, FinishReason: "stop"}}},
+					{Usage: &azuremodels.ChatCompletionUsage{PromptTokens: 5, CompletionTokens: 2, TotalTokens: 7}},
+				}),
+			}, nil
+		}
+
+		out := new(bytes.Buffer)
+		cfg := command.NewConfig(out, out, client, true, 80)
+		runCmd := NewRunCommand(cfg)
+		runCmd.SetArgs([]string{"--json", "--temperature", "0.2", modelSummary.ID, "say hello"})
+
+		_, err := runCmd.ExecuteC()
+		require.NoError(t, err)
+
+		require.NotNil(t, capturedReq.StreamOptions)
+		require.True(t, capturedReq.StreamOptions.IncludeUsage)
+
+		var result RunResult
+		require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+		require.Equal(t, "openai/test-model", result.Model)
+		require.Equal(t, "hello world", result.Content)
+		require.Equal(t, "stop", result.FinishReason)
+		require.NotNil(t, result.Usage)
+		require.Equal(t, 7, result.Usage.TotalTokens)
+		require.NotNil(t, result.Parameters.Temperature)
+		require.Equal(t, 0.2, *result.Parameters.Temperature)
+	})
+
+	t.Run("--jq filters the structured result", func(t *testing.T) {
+		client := azuremodels.NewMockClient()
+		modelSummary := &azuremodels.ModelSummary{
+			ID:        "openai/test-model",
+			Name:      "test-model",
+			Publisher: "openai",
+			Task:      "chat-completion",
+		}
+		client.MockListModels = func(ctx context.Context) ([]*azuremodels.ModelSummary, error) {
+			return []*azuremodels.ModelSummary{modelSummary}, nil
+		}
+		client.MockGetChatCompletionStream = func(ctx context.Context, opt azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+			return &azuremodels.ChatCompletionResponse{
+				Reader: sse.NewMockEventReader([]azuremodels.ChatCompletion{
+					{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: util.Ptr("just the content")}, FinishReason: "stop"}}},
+				}),
+			}, nil
+		}
+
+		out := new(bytes.Buffer)
+		cfg := command.NewConfig(out, out, client, true, 80)
+		runCmd := NewRunCommand(cfg)
+		runCmd.SetArgs([]string{"--jq", ".content", modelSummary.ID, "say something"})
+
+		_, err := runCmd.ExecuteC()
+		require.NoError(t, err)
+		require.Equal(t, "just the content\n", out.String())
+	})
+
+	t.Run("--jsonl-stream prints a line per chunk", func(t *testing.T) {
+		client := azuremodels.NewMockClient()
+		modelSummary := &azuremodels.ModelSummary{
+			ID:        "openai/test-model",
+			Name:      "test-model",
+			Publisher: "openai",
+			Task:      "chat-completion",
+		}
+		client.MockListModels = func(ctx context.Context) ([]*azuremodels.ModelSummary, error) {
+			return []*azuremodels.ModelSummary{modelSummary}, nil
+		}
+		client.MockGetChatCompletionStream = func(ctx context.Context, opt azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+			return &azuremodels.ChatCompletionResponse{
+				Reader: sse.NewMockEventReader([]azuremodels.ChatCompletion{
+					{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: util.Ptr("one")}}}},
+					{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: util.Ptr("two")}, FinishReason: "stop"}}},
+				}),
+			}, nil
+		}
+
+		out := new(bytes.Buffer)
+		cfg := command.NewConfig(out, out, client, true, 80)
+		runCmd := NewRunCommand(cfg)
+		runCmd.SetArgs([]string{"--jsonl-stream", modelSummary.ID, "count"})
+
+		_, err := runCmd.ExecuteC()
+		require.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		require.Len(t, lines, 3)
+
+		var chunk RunStreamChunk
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &chunk))
+		require.Equal(t, "chunk", chunk.Type)
+		require.Equal(t, "one", chunk.Content)
+
+		require.NoError(t, json.Unmarshal([]byte(lines[2]), &chunk))
+		require.Equal(t, "result", chunk.Type)
+		require.NotNil(t, chunk.Result)
+		require.Equal(t, "onetwo", chunk.Result.Content)
+		require.Equal(t, "stop", chunk.Result.FinishReason)
+	})
+
 	t.Run("--help prints usage info", func(t *testing.T) {
 		outBuf := new(bytes.Buffer)
 		errBuf := new(bytes.Buffer)
@@ -196,7 +318,7 @@ messages:
 			}, nil
 		}
 
-		// create a pipe to fake stdin so that isPipe(os.Stdin)==true
+		// create a pipe to fake stdin so that IsPipe(os.Stdin)==true
 		r, w, err := os.Pipe()
 		require.NoError(t, err)
 		oldStdin := os.Stdin
@@ -369,7 +491,7 @@ messages:
 		var capturedRequest azuremodels.ChatCompletionOptions
 		client.MockGetChatCompletionStream = func(ctx context.Context, req azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
 			capturedRequest = req
-			reply := "hello this is a test response"
+			reply := `{"name": "Ada", "age": 36}`
 			reader := sse.NewMockEventReader([]azuremodels.ChatCompletion{
 				{
 					Choices: []azuremodels.ChatChoice{
@@ -416,6 +538,97 @@ messages:
 		require.Contains(t, required, "name")
 		require.Contains(t, required, "age")
 	})
+
+	t.Run("--file validates the response against the JSON schema", func(t *testing.T) {
+		const yamlBody = `
+name: JSON Schema Test
+model: openai/test-model
+responseFormat: json_schema
+jsonSchema: '{"name": "person_schema", "schema": {"type": "object", "properties": {"name": {"type": "string"}, "age": {"type": "integer"}}, "required": ["name", "age"]}}'
+messages:
+  - role: user
+    content: "Generate a person"
+`
+
+		promptFile := filepath.Join(t.TempDir(), "test.prompt.yml")
+		require.NoError(t, os.WriteFile(promptFile, []byte(yamlBody), 0644))
+
+		newClient := func(replies ...string) (*azuremodels.MockClient, *[]azuremodels.ChatCompletionOptions) {
+			client := azuremodels.NewMockClient()
+			client.MockListModels = func(ctx context.Context) ([]*azuremodels.ModelSummary, error) {
+				return []*azuremodels.ModelSummary{{ID: "openai/test-model", Name: "test-model", Publisher: "openai", Task: "chat-completion"}}, nil
+			}
+			var requests []azuremodels.ChatCompletionOptions
+			client.MockGetChatCompletionStream = func(ctx context.Context, req azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+				reply := replies[len(requests)]
+				requests = append(requests, req)
+				return &azuremodels.ChatCompletionResponse{
+					Reader: sse.NewMockEventReader([]azuremodels.ChatCompletion{
+						{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: util.Ptr(reply)}}}},
+					}),
+				}, nil
+			}
+			return client, &requests
+		}
+
+		t.Run("fails with path-level violations", func(t *testing.T) {
+			client, requests := newClient(`{"name": "Ada", "age": "old"}`)
+			out := new(bytes.Buffer)
+			cmd := NewRunCommand(command.NewConfig(out, out, client, true, 100))
+			cmd.SetArgs([]string{"--file", promptFile})
+
+			err := cmd.Execute()
+			require.ErrorContains(t, err, "$.age: expected integer, got string")
+			require.Len(t, *requests, 1)
+		})
+
+		t.Run("--schema-retries asks the model to fix the response", func(t *testing.T) {
+			client, requests := newClient(`{"name": "Ada"}`, `{"name": "Ada", "age": 36}`)
+			out := new(bytes.Buffer)
+			cmd := NewRunCommand(command.NewConfig(out, out, client, true, 100))
+			cmd.SetArgs([]string{"--file", promptFile, "--schema-retries", "2", "--json"})
+
+			err := cmd.Execute()
+			require.NoError(t, err)
+			require.Len(t, *requests, 2)
+
+			repairMessages := (*requests)[1].Messages
+			require.Len(t, repairMessages, 3)
+			require.Equal(t, azuremodels.ChatMessageRoleAssistant, repairMessages[1].Role)
+			require.Contains(t, *repairMessages[2].Content, `$: missing required property "age"`)
+
+			output := out.String()
+			require.Contains(t, output, "asking the model to fix it (attempt 1/2)")
+			var result RunResult
+			require.NoError(t, json.Unmarshal([]byte(output[strings.Index(output, "{"):]), &result))
+			require.Equal(t, `{"name": "Ada", "age": 36}`, result.Content)
+			require.Empty(t, result.SchemaViolations)
+		})
+
+		t.Run("--schema-retries only prints the final text response", func(t *testing.T) {
+			client, _ := newClient(`{"name": "Ada"}`, `{"name": "Ada", "age": 36}`)
+			out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+			cmd := NewRunCommand(command.NewConfig(out, errOut, client, false, 100))
+			cmd.SetArgs([]string{"--file", promptFile, "--schema-retries", "2"})
+
+			require.NoError(t, cmd.Execute())
+			require.Equal(t, "{\"name\": \"Ada\", \"age\": 36}\n", out.String())
+			require.Contains(t, errOut.String(), "asking the model to fix it (attempt 1/2)")
+		})
+
+		t.Run("gives up after --schema-retries attempts", func(t *testing.T) {
+			client, requests := newClient(`{}`, `{}`)
+			out := new(bytes.Buffer)
+			cmd := NewRunCommand(command.NewConfig(out, out, client, true, 100))
+			cmd.SetArgs([]string{"--file", promptFile, "--schema-retries", "1"})
+
+			err := cmd.Execute()
+			var validationErr *prompt.SchemaValidationError
+			require.ErrorAs(t, err, &validationErr)
+			require.Len(t, validationErr.Violations, 2)
+			require.Len(t, *requests, 2)
+		})
+	})
 }
 
 func TestParseTemplateVariables(t *testing.T) {
@@ -541,7 +754,7 @@ func TestValidateModelName(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := validateModelName(tt.modelName, models)
+			result, err := ValidateModelName(tt.modelName, models)
 
 			if tt.expectError {
 				require.Error(t, err)
@@ -552,3 +765,267 @@ func TestValidateModelName(t *testing.T) {
 		})
 	}
 }
+
+func TestChatWithUser(t *testing.T) {
+	models := []*azuremodels.ModelSummary{
+		{ID: "openai/model-a", Name: "model-a", Publisher: "openai", Task: "chat-completion"},
+		{ID: "openai/model-b", Name: "model-b", Publisher: "openai", Task: "chat-completion"},
+	}
+
+	newSession := func() *ChatSession {
+		return &ChatSession{
+			Conversation: &Conversation{},
+			Parameters:   &ModelParameters{},
+			Context:      NewContextManager(),
+			ModelName:    "openai/model-a",
+		}
+	}
+
+	newHandler := func(input string) (*runCommandHandler, *bytes.Buffer) {
+		out := new(bytes.Buffer)
+		client := azuremodels.NewMockClient()
+		client.MockGetChatCompletionStream = func(ctx context.Context, req azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+			return &azuremodels.ChatCompletionResponse{
+				Reader: sse.NewMockEventReader([]azuremodels.ChatCompletion{
+					{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: util.Ptr("the user said hello")}}}},
+				}),
+			}, nil
+		}
+		cfg := command.NewConfig(out, out, client, false, 80)
+		return &runCommandHandler{
+			ctx:    context.Background(),
+			cfg:    cfg,
+			client: client,
+			models: models,
+			stdin:  bufio.NewReader(strings.NewReader(input)),
+		}, out
+	}
+
+	t.Run("/set persists for later turns", func(t *testing.T) {
+		handler, _ := newHandler("/set temperature 0.5\n/set max-tokens 42\n")
+		session := newSession()
+
+		sent, err := handler.ChatWithUser(session)
+		require.NoError(t, err)
+		require.False(t, sent)
+		sent, err = handler.ChatWithUser(session)
+		require.NoError(t, err)
+		require.False(t, sent)
+
+		var req azuremodels.ChatCompletionOptions
+		session.Parameters.UpdateRequest(&req)
+		require.Equal(t, 0.5, *req.Temperature)
+		require.Equal(t, 42, *req.MaxTokens)
+	})
+
+	t.Run("/model switches models and keeps history", func(t *testing.T) {
+		handler, out := newHandler("hello\n/model openai/model-b\n")
+		session := newSession()
+
+		sent, err := handler.ChatWithUser(session)
+		require.NoError(t, err)
+		require.True(t, sent)
+		sent, err = handler.ChatWithUser(session)
+		require.NoError(t, err)
+		require.False(t, sent)
+
+		require.Equal(t, "openai/model-b", session.ModelName)
+		require.Len(t, session.Conversation.GetMessages(), 1)
+		require.Contains(t, out.String(), "Switched model to openai/model-b")
+	})
+
+	t.Run("/model rejects unknown models", func(t *testing.T) {
+		handler, out := newHandler("/model openai/unknown\n")
+		session := newSession()
+
+		_, err := handler.ChatWithUser(session)
+		require.NoError(t, err)
+		require.Equal(t, "openai/model-a", session.ModelName)
+		require.Contains(t, out.String(), "The specified model 'openai/unknown' is not found")
+	})
+
+	t.Run("/parameters reflects the live state", func(t *testing.T) {
+		handler, out := newHandler("/set top-p 0.25\n/model openai/model-b\n/parameters\n")
+		session := newSession()
+
+		for i := 0; i < 3; i++ {
+			_, err := handler.ChatWithUser(session)
+			require.NoError(t, err)
+		}
+
+		output := out.String()
+		require.Regexp(t, `Model:\n\s+openai/model-b`, output)
+		require.Contains(t, output, "top-p: 0.250000")
+	})
+
+	t.Run("/reset clears the history", func(t *testing.T) {
+		handler, _ := newHandler("hello\n/reset\n")
+		session := newSession()
+
+		_, err := handler.ChatWithUser(session)
+		require.NoError(t, err)
+		require.Len(t, session.Conversation.GetMessages(), 1)
+
+		_, err = handler.ChatWithUser(session)
+		require.NoError(t, err)
+		require.Empty(t, session.Conversation.GetMessages())
+	})
+
+	t.Run("/set context parameters", func(t *testing.T) {
+		handler, out := newHandler("/set context-strategy last-n\n/set context-keep-last 4\n/parameters\n")
+		session := newSession()
+
+		for i := 0; i < 3; i++ {
+			_, err := handler.ChatWithUser(session)
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, ContextStrategyLastN, session.Context.Strategy)
+		require.Equal(t, 4, session.Context.KeepLast)
+		require.Contains(t, out.String(), "context-strategy: last-n")
+	})
+
+	t.Run("/compact summarizes older messages", func(t *testing.T) {
+		handler, out := newHandler("/compact\n")
+		session := newSession()
+		session.Conversation.systemPrompt = "You are helpful."
+		session.Conversation.AddMessage(azuremodels.ChatMessageRoleUser, "hello")
+		session.Conversation.AddMessage(azuremodels.ChatMessageRoleAssistant, "hi there")
+		session.Conversation.AddMessage(azuremodels.ChatMessageRoleUser, "how are you?")
+		session.Conversation.AddMessage(azuremodels.ChatMessageRoleAssistant, "great")
+
+		_, err := handler.ChatWithUser(session)
+		require.NoError(t, err)
+
+		require.Contains(t, out.String(), "Compacted 2 messages into a summary")
+		messages := session.Conversation.GetMessages()
+		require.Len(t, messages, 4)
+		require.Equal(t, "You are helpful.", *messages[0].Content)
+		require.Equal(t, azuremodels.ChatMessageRoleSystem, messages[1].Role)
+		require.Contains(t, *messages[1].Content, "the user said hello")
+		require.Equal(t, "how are you?", *messages[2].Content)
+	})
+
+	t.Run("/compact with a short conversation", func(t *testing.T) {
+		handler, out := newHandler("/compact\n")
+		session := newSession()
+		session.Conversation.AddMessage(azuremodels.ChatMessageRoleUser, "hello")
+
+		_, err := handler.ChatWithUser(session)
+		require.NoError(t, err)
+		require.Contains(t, out.String(), "Nothing to compact")
+	})
+
+	t.Run("/export-prompt saves the conversation", func(t *testing.T) {
+		exportPath := filepath.Join(t.TempDir(), "chat.prompt.yml")
+		handler, out := newHandler("/set temperature 0.2\n/export-prompt " + exportPath + " --input\n")
+		session := newSession()
+		session.Conversation.systemPrompt = "You are helpful."
+		session.Conversation.AddMessage(azuremodels.ChatMessageRoleUser, "hello")
+		session.Conversation.AddMessage(azuremodels.ChatMessageRoleAssistant, "hi there")
+		session.Conversation.AddMessage(azuremodels.ChatMessageRoleUser, "how are you?")
+		session.Conversation.AddMessage(azuremodels.ChatMessageRoleAssistant, "great")
+
+		for i := 0; i < 2; i++ {
+			_, err := handler.ChatWithUser(session)
+			require.NoError(t, err)
+		}
+		require.Contains(t, out.String(), "Exported conversation to "+exportPath)
+
+		pf, err := prompt.LoadFromFile(exportPath)
+		require.NoError(t, err)
+		require.Equal(t, "chat", pf.Name)
+		require.Equal(t, "openai/model-a", pf.Model)
+		require.Equal(t, 0.2, *pf.ModelParameters.Temperature)
+		require.Equal(t, []prompt.Message{
+			{Role: "system", Content: "You are helpful."},
+			{Role: "user", Content: "hello"},
+			{Role: "assistant", Content: "hi there"},
+			{Role: "user", Content: "{{input}}"},
+		}, pf.Messages)
+		require.Equal(t, []prompt.TestDataItem{
+			{"input": "hello", "expected": "hi there"},
+			{"input": "how are you?", "expected": "great"},
+		}, pf.TestData)
+	})
+
+	t.Run("/export-prompt skips empty messages and the compacted summary", func(t *testing.T) {
+		exportPath := filepath.Join(t.TempDir(), "chat.prompt.yml")
+		handler, _ := newHandler("/export-prompt " + exportPath + " --input\n")
+		session := newSession()
+		// Interactive sessions start with an empty user message
+		session.Conversation.AddMessage(azuremodels.ChatMessageRoleUser, "")
+		session.Conversation.summary = "They said hello."
+		session.Conversation.AddMessage(azuremodels.ChatMessageRoleUser, "how are you?")
+		session.Conversation.AddMessage(azuremodels.ChatMessageRoleAssistant, "great")
+
+		_, err := handler.ChatWithUser(session)
+		require.NoError(t, err)
+
+		pf, err := prompt.LoadFromFile(exportPath)
+		require.NoError(t, err)
+		require.Equal(t, []prompt.Message{
+			{Role: "user", Content: "{{input}}"},
+		}, pf.Messages)
+		require.Equal(t, []prompt.TestDataItem{
+			{"input": "how are you?", "expected": "great"},
+		}, pf.TestData)
+	})
+
+	t.Run("/export-prompt requires a file", func(t *testing.T) {
+		handler, out := newHandler("/export-prompt\n")
+		_, err := handler.ChatWithUser(newSession())
+		require.NoError(t, err)
+		require.Contains(t, out.String(), "Usage: /export-prompt <file> [--input]")
+	})
+}
+
+func TestExportPromptFlag(t *testing.T) {
+	client := azuremodels.NewMockClient()
+	client.MockListModels = func(ctx context.Context) ([]*azuremodels.ModelSummary, error) {
+		return []*azuremodels.ModelSummary{
+			{ID: "openai/test-model", Name: "test-model", Publisher: "openai", Task: "chat-completion"},
+			{ID: "openai/other-model", Name: "other-model", Publisher: "openai", Task: "chat-completion"},
+		}, nil
+	}
+	client.MockGetChatCompletionStream = func(ctx context.Context, req azuremodels.ChatCompletionOptions, org string) (*azuremodels.ChatCompletionResponse, error) {
+		return &azuremodels.ChatCompletionResponse{
+			Reader: sse.NewMockEventReader([]azuremodels.ChatCompletion{
+				{Choices: []azuremodels.ChatChoice{{Message: &azuremodels.ChatChoiceMessage{Content: util.Ptr("three")}}}},
+			}),
+		}, nil
+	}
+
+	exportPath := filepath.Join(t.TempDir(), "hyenas.prompt.yml")
+	out := new(bytes.Buffer)
+	cmd := NewRunCommand(command.NewConfig(out, out, client, true, 100))
+	cmd.SetArgs([]string{"--export-prompt", exportPath, "--system-prompt", "Be brief.", "openai/test-model", "how many types of hyena are there?"})
+
+	err := cmd.Execute()
+	require.NoError(t, err)
+
+	pf, err := prompt.LoadFromFile(exportPath)
+	require.NoError(t, err)
+	require.Equal(t, "hyenas", pf.Name)
+	require.Equal(t, []prompt.Message{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "how many types of hyena are there?"},
+		{Role: "assistant", Content: "three"},
+	}, pf.Messages)
+	require.Empty(t, pf.TestData)
+
+	t.Run("replaces an earlier export", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		cmd := NewRunCommand(command.NewConfig(out, out, client, true, 100))
+		cmd.SetArgs([]string{"--export-prompt", exportPath, "openai/other-model", "and lions?"})
+		require.NoError(t, cmd.Execute())
+
+		pf, err := prompt.LoadFromFile(exportPath)
+		require.NoError(t, err)
+		require.Equal(t, "openai/other-model", pf.Model)
+		require.Equal(t, []prompt.Message{
+			{Role: "user", Content: "and lions?"},
+			{Role: "assistant", Content: "three"},
+		}, pf.Messages)
+	})
+}
@@ -0,0 +1,43 @@
+// Package schema provides a `gh models schema` command to print the JSON Schema for prompt files.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/github/gh-models/pkg/command"
+	"github.com/github/gh-models/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+// NewSchemaCommand returns a new command to print the JSON Schema for prompt files
+func NewSchemaCommand(cfg *command.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema for prompt files",
+		Long: heredoc.Docf(`
+			Prints the JSON Schema that describes .prompt.yml files, so that editors and other tools
+			can offer completion and validation for them.
+
+			The schema is also published at %[1]s%[2]s%[1]s. To use it with the YAML language server,
+			for example in VS Code, add this comment to the top of a prompt file:
+
+			  # yaml-language-server: $schema=%[2]s
+		`, "`", prompt.PromptFileSchemaID),
+		Example: heredoc.Doc(`
+			gh models schema > prompt.schema.json
+		`),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := json.MarshalIndent(prompt.PromptFileSchema(), "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal JSON: %w", err)
+			}
+			cfg.WriteToOut(string(data) + "\n")
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,28 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/github/gh-models/internal/azuremodels"
+	"github.com/github/gh-models/pkg/command"
+	"github.com/github/gh-models/pkg/prompt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema(t *testing.T) {
+	t.Run("prints the prompt file schema", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		cmd := NewSchemaCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), true, 100))
+		cmd.SetArgs([]string{})
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+
+		var schema map[string]interface{}
+		require.NoError(t, json.Unmarshal(out.Bytes(), &schema))
+		require.Equal(t, prompt.PromptFileSchemaID, schema["$id"])
+		require.Contains(t, schema["properties"], "messages")
+	})
+}
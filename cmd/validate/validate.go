@@ -0,0 +1,120 @@
+// Package validate provides a `gh models validate` command to check prompt files for problems.
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/github/gh-models/cmd/eval"
+	"github.com/github/gh-models/pkg/command"
+	"github.com/github/gh-models/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+// Result is the machine-readable output of the validate command
+type Result struct {
+	Valid       bool                `json:"valid"`
+	Files       int                 `json:"files"`
+	Diagnostics []prompt.Diagnostic `json:"diagnostics"`
+}
+
+// NewValidateCommand returns a new command to validate prompt files
+func NewValidateCommand(cfg *command.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate <file>...",
+		Short: "Check prompt files for problems",
+		Long: heredoc.Docf(`
+			Checks .prompt.yml files for problems without running them, and reports each one with
+			its line and column.
+
+			Validation catches invalid YAML, unknown keys, values of the wrong type, unknown message
+			roles, invalid %[1]sresponseFormat%[1]s and %[1]sjsonSchema%[1]s values, template syntax errors, and
+			evaluators that are incomplete, use an unknown %[1]sgithub/%[1]s plugin, or run a command that
+			cannot be found.
+
+			The command exits with a non-zero status if any file has problems. Use %[1]s--json%[1]s to print
+			the problems as JSON, for example to annotate pull requests in CI.
+		`, "`"),
+		Example: heredoc.Doc(`
+			gh models validate my_prompt.prompt.yml
+			gh models validate --json prompts/*.prompt.yml
+		`),
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jsonOutput, err := cmd.Flags().GetBool("json")
+			if err != nil {
+				return err
+			}
+
+			var plugins []string
+			for name := range eval.BuiltInEvaluators {
+				plugins = append(plugins, "github/"+name)
+			}
+
+			result := Result{Valid: true, Files: len(args), Diagnostics: []prompt.Diagnostic{}}
+			invalidFiles := 0
+			problems := 0
+			for _, filePath := range args {
+				diagnostics, err := prompt.ValidateFile(filePath, prompt.WithPlugins(plugins...))
+				if err != nil {
+					diagnostics = []prompt.Diagnostic{{
+						File:     filePath,
+						Severity: prompt.SeverityError,
+						Message:  fmt.Sprintf("failed to read file: %v", err),
+					}}
+				}
+				// Warnings are reported, but only errors make a file invalid
+				if n := countErrors(diagnostics); n > 0 {
+					invalidFiles++
+					problems += n
+					result.Valid = false
+				}
+				result.Diagnostics = append(result.Diagnostics, diagnostics...)
+			}
+
+			if jsonOutput {
+				data, err := json.MarshalIndent(result, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				cfg.WriteToOut(string(data) + "\n")
+			} else {
+				for _, diagnostic := range result.Diagnostics {
+					cfg.WriteToOut(diagnostic.String() + "\n")
+				}
+				if result.Valid {
+					cfg.WriteToOut(fmt.Sprintf("✓ %s valid\n", pluralize(len(args), "prompt file is", "prompt files are")))
+				}
+			}
+
+			if !result.Valid {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("found %s in %d of %s", pluralize(problems, "problem", "problems"),
+					invalidFiles, pluralize(len(args), "file", "files"))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("json", false, "Output the problems in JSON format")
+	return cmd
+}
+
+// countErrors returns the number of diagnostics with the error severity
+func countErrors(diagnostics []prompt.Diagnostic) int {
+	n := 0
+	for _, d := range diagnostics {
+		if d.Severity == prompt.SeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
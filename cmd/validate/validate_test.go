@@ -0,0 +1,86 @@
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/github/gh-models/internal/azuremodels"
+	"github.com/github/gh-models/pkg/command"
+	"github.com/github/gh-models/pkg/prompt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	validFile := filepath.Join(dir, "valid.prompt.yml")
+	require.NoError(t, os.WriteFile(validFile, []byte(`
+name: Valid
+model: openai/gpt-4o
+messages:
+  - role: user
+    content: "{{input}}"
+evaluators:
+  - name: similarity
+    uses: github/similarity
+`), 0644))
+	invalidFile := filepath.Join(dir, "invalid.prompt.yml")
+	require.NoError(t, os.WriteFile(invalidFile, []byte(`name: Invalid
+messages:
+  - role: bot
+    content: hi
+evaluators:
+  - name: missing
+    uses: github/missing
+`), 0644))
+
+	t.Run("reports valid files", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		cmd := NewValidateCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), true, 100))
+		cmd.SetArgs([]string{validFile})
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+		require.Equal(t, "✓ 1 prompt file is valid\n", out.String())
+	})
+
+	t.Run("prints diagnostics and fails", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		cmd := NewValidateCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), true, 100))
+		cmd.SetArgs([]string{validFile, invalidFile})
+
+		err := cmd.Execute()
+		require.EqualError(t, err, "found 2 problems in 1 of 2 files")
+		require.Contains(t, out.String(), invalidFile+":3:11: error: unknown role 'bot' in messages[0], expected one of: system, user, assistant\n")
+		require.Contains(t, out.String(), invalidFile+":7:11: error: unknown evaluator plugin 'github/missing'\n")
+		require.NotContains(t, out.String(), "Usage:")
+	})
+
+	t.Run("--json prints machine-readable diagnostics", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		cmd := NewValidateCommand(command.NewConfig(out, out, azuremodels.NewMockClient(), true, 100))
+		cmd.SetArgs([]string{"--json", invalidFile, filepath.Join(dir, "missing.prompt.yml")})
+
+		err := cmd.Execute()
+		require.Error(t, err)
+
+		var result Result
+		require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+		require.False(t, result.Valid)
+		require.Equal(t, 2, result.Files)
+		require.Len(t, result.Diagnostics, 3)
+		require.Equal(t, 3, result.Diagnostics[0].Line)
+		require.Equal(t, 11, result.Diagnostics[0].Column)
+		require.Contains(t, result.Diagnostics[2].Message, "failed to read file")
+	})
+}
+
+func TestCountErrors(t *testing.T) {
+	require.Equal(t, 1, countErrors([]prompt.Diagnostic{
+		{Severity: prompt.SeverityWarning, Message: "likely a mistake"},
+		{Severity: prompt.SeverityError, Message: "broken"},
+	}))
+	require.Zero(t, countErrors([]prompt.Diagnostic{{Severity: prompt.SeverityWarning, Message: "likely a mistake"}}))
+}
@@ -53,6 +53,11 @@ func (c *AzureClient) GetChatCompletionStream(ctx context.Context, req ChatCompl
 		req.Stream = true
 	}
 
+	// Stream options are only valid for streamed requests
+	if !req.Stream {
+		req.StreamOptions = nil
+	}
+
 	bodyBytes, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
@@ -13,6 +13,13 @@ type ChatCompletionOptions struct {
 	Temperature    *float64        `json:"temperature,omitempty"`
 	TopP           *float64        `json:"top_p,omitempty"`
 	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	StreamOptions  *StreamOptions  `json:"stream_options,omitempty"`
+}
+
+// StreamOptions represents options for streamed chat completion responses.
+type StreamOptions struct {
+	// IncludeUsage requests a final chunk containing token usage for the whole request.
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // ResponseFormat represents the response format specification
@@ -58,9 +65,17 @@ type ChatChoice struct {
 	Message      *ChatChoiceMessage `json:"message,omitempty"`
 }
 
+// ChatCompletionUsage represents the token usage reported for a chat completion.
+type ChatCompletionUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
 // ChatCompletion represents a chat completion.
 type ChatCompletion struct {
-	Choices []ChatChoice `json:"choices"`
+	Choices []ChatChoice         `json:"choices"`
+	Usage   *ChatCompletionUsage `json:"usage,omitempty"`
 }
 
 // ChatCompletionResponse represents a response to a chat completion request.
@@ -0,0 +1,56 @@
+package prompt
+
+import (
+	"fmt"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envReferencePattern matches ${env:NAME} references, and escaped $${env:NAME} references
+var envReferencePattern = regexp.MustCompile(`\$?\$\{env:([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// interpolateEnv replaces ${env:NAME} references in the scalar values of the YAML document
+func interpolateEnv(node *yaml.Node, lookupEnv func(string) (string, bool)) error {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			if err := interpolateEnv(child, lookupEnv); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		// Only values are interpolated, keys are left untouched
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := interpolateEnv(node.Content[i], lookupEnv); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if !envReferencePattern.MatchString(node.Value) {
+			return nil
+		}
+
+		var missing string
+		node.Value = envReferencePattern.ReplaceAllStringFunc(node.Value, func(match string) string {
+			if match[1] == '$' {
+				return match[1:]
+			}
+			name := envReferencePattern.FindStringSubmatch(match)[1]
+			value, ok := lookupEnv(name)
+			if !ok && missing == "" {
+				missing = name
+			}
+			return value
+		})
+		if missing != "" {
+			return fmt.Errorf("line %d: environment variable %s is not set", node.Line, missing)
+		}
+
+		// Resolve the type of plain values again, so that numbers and booleans can come from the environment
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+	}
+	return nil
+}
@@ -0,0 +1,394 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"gopkg.in/yaml.v3"
+)
+
+// Prompt files can share content with other files in two ways:
+//
+//   - extends: a path, or a list of paths, to prompt files whose fields are used as defaults. Later
+//     files override earlier ones, and the file itself overrides them all. Scalar fields, messages
+//     and testData are replaced as a whole, modelParameters are merged key by key, and evaluators
+//     are merged by name, so that an evaluator with the same name replaces the inherited one.
+//   - include: an item of messages, testData or evaluators that is replaced with the items of
+//     another file. The file can contain a list of items, or a mapping with a list under the same
+//     key as the including section, such as another prompt file.
+//
+// Paths are relative to the directory of the file that contains them.
+
+// extendsKey is the key of the prompt files a prompt file extends
+const extendsKey = "extends"
+
+// includeKey is the key of list items that include the items of another file
+const includeKey = "include"
+
+// includableSections are the lists of a prompt file that can contain include items
+var includableSections = []string{"messages", "testData", "evaluators"}
+
+// resolveImports loads the prompt file at the given path and resolves its extends and include
+// references, returning the mapping node of the combined prompt file.
+func resolveImports(filePath string) (*yaml.Node, error) {
+	r := &importResolver{}
+	return r.resolveFile(filePath)
+}
+
+type importResolver struct {
+	// stack holds the files that are being resolved, to detect cycles
+	stack []string
+	// rootDir is the directory of the prompt file that is being loaded
+	rootDir string
+}
+
+// enter adds the file to the stack, failing if it is already being resolved
+func (r *importResolver) enter(filePath string) (string, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", err
+	}
+	if i := slices.Index(r.stack, absPath); i >= 0 {
+		cycle := append(slices.Clone(r.stack[i:]), absPath)
+		for j := range cycle {
+			cycle[j] = filepath.Base(cycle[j])
+		}
+		return "", fmt.Errorf("import cycle: %s", strings.Join(cycle, " -> "))
+	}
+	if len(r.stack) == 0 {
+		r.rootDir = filepath.Dir(absPath)
+	}
+	r.stack = append(r.stack, absPath)
+	return absPath, nil
+}
+
+func (r *importResolver) leave() {
+	r.stack = r.stack[:len(r.stack)-1]
+}
+
+// loadNode reads a YAML file and returns its root node
+func loadNode(filePath string) (*yaml.Node, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	return doc.Content[0], nil
+}
+
+func (r *importResolver) resolveFile(filePath string) (*yaml.Node, error) {
+	absPath, err := r.enter(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.leave()
+
+	root, err := loadNode(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: a prompt file must be a mapping", filePath)
+	}
+	dir := filepath.Dir(absPath)
+	if dir != r.rootDir {
+		rebaseFileReferences(root, dir, r.rootDir)
+	}
+
+	for _, section := range includableSections {
+		if value := mappingValue(root, section); value != nil {
+			if err := r.expandIncludes(section, value, dir); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if mappingValue(root, extendsKey) == nil {
+		return root, nil
+	}
+
+	merged, err := r.resolveParents(filePath, root)
+	if err != nil {
+		return nil, err
+	}
+	removeMappingKey(root, extendsKey)
+	mergePromptNodes(merged, root)
+	return merged, nil
+}
+
+// resolveParents returns the combined prompt file of the files that a prompt file extends
+func (r *importResolver) resolveParents(filePath string, root *yaml.Node) (*yaml.Node, error) {
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	extends := mappingValue(root, extendsKey)
+	if extends == nil {
+		return merged, nil
+	}
+
+	var parents []string
+	switch extends.Kind {
+	case yaml.ScalarNode:
+		parents = []string{extends.Value}
+	case yaml.SequenceNode:
+		for _, item := range extends.Content {
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("%s: line %d: extends must be a path or a list of paths", filePath, item.Line)
+			}
+			parents = append(parents, item.Value)
+		}
+	default:
+		return nil, fmt.Errorf("%s: line %d: extends must be a path or a list of paths", filePath, extends.Line)
+	}
+
+	for _, parent := range parents {
+		parentNode, err := r.resolveFile(filepath.Join(filepath.Dir(filePath), parent))
+		if err != nil {
+			return nil, err
+		}
+		mergePromptNodes(merged, parentNode)
+	}
+	return merged, nil
+}
+
+// inheritedNode returns the combined prompt file of the files that the prompt file at filePath, with
+// the given root node, extends. It is an empty mapping when the file does not extend any file.
+func inheritedNode(filePath string, root *yaml.Node) (*yaml.Node, error) {
+	r := &importResolver{}
+	if _, err := r.enter(filePath); err != nil {
+		return nil, err
+	}
+	defer r.leave()
+	return r.resolveParents(filePath, root)
+}
+
+// expandItem returns the items that an item of a section of the prompt file at filePath stands for:
+// the items of the included file for an include item, and the item itself otherwise
+func expandItem(filePath, section string, item *yaml.Node) ([]*yaml.Node, error) {
+	includePath, ok := includeReference(item)
+	if !ok {
+		return []*yaml.Node{item}, nil
+	}
+
+	r := &importResolver{}
+	if _, err := r.enter(filePath); err != nil {
+		return nil, err
+	}
+	defer r.leave()
+	return r.resolveInclude(section, filepath.Join(filepath.Dir(filePath), includePath))
+}
+
+// rebaseFileReferences makes the paths of the testData, jsonSchema and evaluator file references in
+// an extended or included file relative to the prompt file that is being loaded
+func rebaseFileReferences(root *yaml.Node, fromDir, toDir string) {
+	references := []*yaml.Node{mappingValue(root, "testData")}
+	if references[0] != nil && references[0].Kind == yaml.MappingNode {
+		references[0] = mappingValue(references[0], "file")
+	}
+	if jsonSchema := mappingValue(root, "jsonSchema"); jsonSchema != nil {
+		references = append(references, schemaFileRef(jsonSchema))
+	}
+
+	for _, reference := range references {
+		rebasePath(reference, fromDir, toDir)
+	}
+
+	if evaluators := mappingValue(root, "evaluators"); evaluators != nil && evaluators.Kind == yaml.SequenceNode {
+		rebaseEvaluators(evaluators.Content, fromDir, toDir)
+	}
+}
+
+// rebaseEvaluators makes the json schema references, and the programs that uses commands run when
+// they are given as relative paths, of evaluators from another directory relative to toDir
+func rebaseEvaluators(evaluators []*yaml.Node, fromDir, toDir string) {
+	for _, evaluator := range evaluators {
+		if evaluator.Kind != yaml.MappingNode {
+			continue
+		}
+		if jsonNode := mappingValue(evaluator, "json"); jsonNode != nil {
+			if schema := mappingValue(jsonNode, "schema"); schema != nil {
+				rebasePath(schemaFileRef(schema), fromDir, toDir)
+			}
+		}
+		if uses := mappingValue(evaluator, "uses"); uses != nil && uses.Kind == yaml.ScalarNode && IsCommandPlugin(uses.Value) {
+			args, err := shellquote.Split(uses.Value)
+			if err != nil || len(args) == 0 || !isRelativeProgram(args[0]) {
+				continue
+			}
+			program := &yaml.Node{Kind: yaml.ScalarNode, Value: args[0]}
+			rebasePath(program, fromDir, toDir)
+			// Keep the program a path, so that it is not looked up in PATH
+			if !isRelativeProgram(program.Value) {
+				program.Value = "./" + program.Value
+			}
+			args[0] = program.Value
+			uses.Value = shellquote.Join(args...)
+		}
+	}
+}
+
+// rebasePath makes a relative path in a scalar node, which is relative to fromDir, relative to toDir
+func rebasePath(reference *yaml.Node, fromDir, toDir string) {
+	if reference == nil || reference.Kind != yaml.ScalarNode || filepath.IsAbs(reference.Value) {
+		return
+	}
+	if rel, err := filepath.Rel(toDir, filepath.Join(fromDir, reference.Value)); err == nil {
+		reference.Value = filepath.ToSlash(rel)
+	}
+}
+
+// expandIncludes replaces the include items of a section with the items of the included files
+func (r *importResolver) expandIncludes(section string, list *yaml.Node, dir string) error {
+	if list.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	var items []*yaml.Node
+	for _, item := range list.Content {
+		includePath, ok := includeReference(item)
+		if !ok {
+			items = append(items, item)
+			continue
+		}
+
+		included, err := r.resolveInclude(section, filepath.Join(dir, includePath))
+		if err != nil {
+			return err
+		}
+		items = append(items, included...)
+	}
+	list.Content = items
+	return nil
+}
+
+// resolveInclude returns the items of the section in the included file
+func (r *importResolver) resolveInclude(section, filePath string) ([]*yaml.Node, error) {
+	root, err := loadNode(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if root.Kind == yaml.MappingNode {
+		// Resolve mappings as prompt files, so that they can use extends and include too
+		resolved, err := r.resolveFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		list := mappingValue(resolved, section)
+		if list == nil || list.Kind != yaml.SequenceNode {
+			return nil, fmt.Errorf("%s: expected a '%s' list", filePath, section)
+		}
+		return list.Content, nil
+	}
+
+	if root.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("%s: expected a list of %s, or a mapping with a '%s' list", filePath, section, section)
+	}
+
+	absPath, err := r.enter(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.leave()
+
+	dir := filepath.Dir(absPath)
+	if section == "evaluators" && dir != r.rootDir {
+		rebaseEvaluators(root.Content, dir, r.rootDir)
+	}
+	if err := r.expandIncludes(section, root, dir); err != nil {
+		return nil, err
+	}
+	return root.Content, nil
+}
+
+// includeReference returns the path of an include item, which is a mapping with only an include key
+func includeReference(item *yaml.Node) (string, bool) {
+	if item.Kind != yaml.MappingNode || len(item.Content) != 2 || item.Content[0].Value != includeKey {
+		return "", false
+	}
+	return item.Content[1].Value, true
+}
+
+// mergePromptNodes merges the keys of the override mapping into the base mapping
+func mergePromptNodes(base, override *yaml.Node) {
+	for i := 0; i+1 < len(override.Content); i += 2 {
+		key, value := override.Content[i], override.Content[i+1]
+		existing := mappingValue(base, key.Value)
+
+		switch {
+		case existing == nil:
+			base.Content = append(base.Content, key, value)
+		case key.Value == "modelParameters" && existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: slices.Clone(existing.Content)}
+			mergePromptNodes(merged, value)
+			setMappingValue(base, key.Value, merged)
+		case key.Value == "evaluators" && existing.Kind == yaml.SequenceNode && value.Kind == yaml.SequenceNode:
+			setMappingValue(base, key.Value, mergeEvaluatorNodes(existing, value))
+		default:
+			setMappingValue(base, key.Value, value)
+		}
+	}
+}
+
+// mergeEvaluatorNodes replaces the inherited evaluators that have the same name as an overriding
+// evaluator, and appends the others
+func mergeEvaluatorNodes(base, override *yaml.Node) *yaml.Node {
+	merged := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: slices.Clone(base.Content)}
+	for _, evaluator := range override.Content {
+		name := mappingValue(evaluator, "name")
+		index := -1
+		if name != nil {
+			index = slices.IndexFunc(merged.Content, func(existing *yaml.Node) bool {
+				existingName := mappingValue(existing, "name")
+				return existingName != nil && existingName.Value == name.Value
+			})
+		}
+		if index >= 0 {
+			merged.Content[index] = evaluator
+		} else {
+			merged.Content = append(merged.Content, evaluator)
+		}
+	}
+	return merged
+}
+
+// mappingValue returns the value of the key in a mapping node, or nil if it is not set
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+func removeMappingKey(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
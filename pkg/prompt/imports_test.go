@@ -0,0 +1,235 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles writes the files under dir, creating their directories
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func TestImports(t *testing.T) {
+	t.Run("extends merges fields with overrides", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"base.prompt.yml": `
+name: Base
+model: openai/gpt-4o
+modelParameters:
+  maxTokens: 100
+  temperature: 0.2
+messages:
+  - role: system
+    content: You are helpful.
+evaluators:
+  - name: contains-hello
+    string:
+      contains: hello
+  - name: similarity
+    uses: github/similarity
+`,
+			"child.prompt.yml": `
+extends: base.prompt.yml
+name: Child
+modelParameters:
+  temperature: 0.9
+messages:
+  - role: user
+    content: "{{input}}"
+evaluators:
+  - name: contains-hello
+    string:
+      contains: world
+`,
+		})
+
+		pf, err := LoadFromFile(filepath.Join(dir, "child.prompt.yml"))
+		require.NoError(t, err)
+		require.Equal(t, "Child", pf.Name)
+		require.Equal(t, "openai/gpt-4o", pf.Model)
+		require.Equal(t, 100, *pf.ModelParameters.MaxTokens)
+		require.Equal(t, 0.9, *pf.ModelParameters.Temperature)
+		require.Equal(t, []Message{{Role: "user", Content: "{{input}}"}}, pf.Messages)
+		require.Len(t, pf.Evaluators, 2)
+		require.Equal(t, "contains-hello", pf.Evaluators[0].Name)
+		require.Equal(t, "world", pf.Evaluators[0].String.Contains)
+		require.Equal(t, "github/similarity", pf.Evaluators[1].Uses)
+	})
+
+	t.Run("extends a list of files in order", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"shared/model.yml":   "model: openai/gpt-4o\nname: First\n",
+			"shared/persona.yml": "name: Second\nmessages:\n  - role: system\n    content: Be brief.\n",
+			"prompts/child.prompt.yml": `
+extends:
+  - ../shared/model.yml
+  - ../shared/persona.yml
+description: Child
+`,
+		})
+
+		pf, err := LoadFromFile(filepath.Join(dir, "prompts", "child.prompt.yml"))
+		require.NoError(t, err)
+		require.Equal(t, "Second", pf.Name)
+		require.Equal(t, "Child", pf.Description)
+		require.Equal(t, "openai/gpt-4o", pf.Model)
+		require.Equal(t, "Be brief.", pf.Messages[0].Content)
+	})
+
+	t.Run("include splices items from list and prompt files", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"fragments/system.yml": "- role: system\n  content: You are helpful.\n",
+			"fragments/data.yml":   "- input: one\n- include: more.yml\n",
+			"fragments/more.yml":   "- input: two\n",
+			"other.prompt.yml": `
+messages:
+  - role: user
+    content: hi
+evaluators:
+  - name: similarity
+    uses: github/similarity
+`,
+			"main.prompt.yml": `
+messages:
+  - include: fragments/system.yml
+  - role: user
+    content: "{{input}}"
+testData:
+  - include: fragments/data.yml
+  - input: three
+evaluators:
+  - include: other.prompt.yml
+`,
+		})
+
+		pf, err := LoadFromFile(filepath.Join(dir, "main.prompt.yml"))
+		require.NoError(t, err)
+		require.Equal(t, []Message{
+			{Role: "system", Content: "You are helpful."},
+			{Role: "user", Content: "{{input}}"},
+		}, pf.Messages)
+		require.Equal(t, []TestDataItem{{"input": "one"}, {"input": "two"}, {"input": "three"}}, pf.TestData)
+		require.Len(t, pf.Evaluators, 1)
+		require.Equal(t, "github/similarity", pf.Evaluators[0].Uses)
+	})
+
+	t.Run("rebases evaluator references of files in other directories", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"shared/base.yml": `
+evaluators:
+  - name: shape
+    json:
+      schema:
+        $ref: schemas/answer.json
+  - name: check
+    uses: ./bin/check.sh --mode 'very strict'
+  - name: lookup
+    uses: python3 lookup.py
+`,
+			"shared/evaluators.yml": `
+- name: item
+  json:
+    schema:
+      $ref: schemas/item.json
+- name: words
+  uses: ../words.sh
+`,
+			"shared/schemas/answer.json": `{"type": "object", "required": ["answer"]}`,
+			"shared/schemas/item.json":   `{"type": "array"}`,
+			"shared/bin/check.sh":        "#!/bin/sh\n",
+			"words.sh":                   "#!/bin/sh\n",
+			"prompts/parts/evaluators.yml": `
+- name: local
+  uses: ../local.sh
+`,
+			"prompts/local.sh": "#!/bin/sh\n",
+			"prompts/main.prompt.yml": `
+extends: ../shared/base.yml
+messages:
+  - role: user
+    content: hi
+evaluators:
+  - include: ../shared/evaluators.yml
+  - include: parts/evaluators.yml
+`,
+		})
+		mainPath := filepath.Join(dir, "prompts", "main.prompt.yml")
+
+		pf, err := LoadFromFile(mainPath)
+		require.NoError(t, err)
+		require.Len(t, pf.Evaluators, 6)
+		require.Equal(t, "../shared/schemas/answer.json", pf.Evaluators[0].JSON.Schema.Ref)
+		require.Equal(t, []interface{}{"answer"}, pf.Evaluators[0].JSON.Schema.Parsed["required"])
+		require.Equal(t, "../shared/bin/check.sh --mode 'very strict'", pf.Evaluators[1].Uses)
+		require.Equal(t, "python3 lookup.py", pf.Evaluators[2].Uses)
+		require.Equal(t, "../shared/schemas/item.json", pf.Evaluators[3].JSON.Schema.Ref)
+		require.Equal(t, "array", pf.Evaluators[3].JSON.Schema.Parsed["type"])
+		require.Equal(t, "../words.sh", pf.Evaluators[4].Uses)
+		require.Equal(t, "./local.sh", pf.Evaluators[5].Uses)
+
+		diagnostics, err := ValidateFile(mainPath)
+		require.NoError(t, err)
+		require.Empty(t, diagnostics)
+	})
+
+	t.Run("reports import cycles", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"a.prompt.yml": "extends: b.prompt.yml\n",
+			"b.prompt.yml": "extends: a.prompt.yml\n",
+		})
+
+		_, err := LoadFromFile(filepath.Join(dir, "a.prompt.yml"))
+		require.ErrorContains(t, err, "import cycle: a.prompt.yml -> b.prompt.yml -> a.prompt.yml")
+	})
+
+	t.Run("reports missing included files", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"main.prompt.yml": "messages:\n  - include: missing.yml\n",
+		})
+
+		_, err := LoadFromFile(filepath.Join(dir, "main.prompt.yml"))
+		require.ErrorContains(t, err, "missing.yml")
+	})
+
+	t.Run("validation resolves extends and include", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"base.prompt.yml":  "messages:\n  - role: user\n    content: hi\n",
+			"valid.prompt.yml": "extends: base.prompt.yml\nname: Valid\n",
+			"invalid.prompt.yml": `extends: missing.prompt.yml
+testData:
+  - include: missing.yml
+`,
+		})
+
+		diagnostics, err := ValidateFile(filepath.Join(dir, "valid.prompt.yml"))
+		require.NoError(t, err)
+		require.Empty(t, diagnostics)
+
+		diagnostics, err = ValidateFile(filepath.Join(dir, "invalid.prompt.yml"))
+		require.NoError(t, err)
+		var actual []string
+		for _, d := range diagnostics {
+			actual = append(actual, d.Message)
+		}
+		require.Equal(t, []string{
+			"cannot find the file 'missing.prompt.yml'",
+			"cannot find the file 'missing.yml'",
+		}, actual)
+	})
+}
@@ -0,0 +1,162 @@
+package prompt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSONPath is a parsed JSONPath expression. It supports the subset used to pick values out of
+// responses: the root $, child keys written as .name or ['name'], array indexes such as [0] or
+// [-1] for the last item, and the wildcards .* and [*].
+type JSONPath struct {
+	expr  string
+	steps []jsonPathStep
+}
+
+type jsonPathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// ParseJSONPath parses a JSONPath expression, which must start with $
+func ParseJSONPath(expr string) (*JSONPath, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(expr), "$")
+	if !ok {
+		return nil, fmt.Errorf("JSONPath '%s' must start with $", expr)
+	}
+
+	path := &JSONPath{expr: expr}
+	for rest != "" {
+		var step jsonPathStep
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			return nil, fmt.Errorf("JSONPath '%s' uses recursive descent, which is not supported", expr)
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			if name == "" {
+				return nil, fmt.Errorf("JSONPath '%s' has an empty key", expr)
+			}
+			step = jsonPathStep{key: name, wildcard: name == "*"}
+			rest = rest[end:]
+		case strings.HasPrefix(rest, "["):
+			end := closingBracket(rest)
+			if end < 0 {
+				return nil, fmt.Errorf("JSONPath '%s' has an unclosed [", expr)
+			}
+			selector := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			switch {
+			case selector == "*":
+				step = jsonPathStep{wildcard: true}
+			case strings.HasPrefix(selector, "'") || strings.HasPrefix(selector, `"`):
+				key, err := unquoteJSONPathKey(selector)
+				if err != nil {
+					return nil, fmt.Errorf("JSONPath '%s' has an invalid key %s", expr, selector)
+				}
+				step = jsonPathStep{key: key}
+			default:
+				index, err := strconv.Atoi(selector)
+				if err != nil {
+					return nil, fmt.Errorf("JSONPath '%s' has an invalid index [%s]", expr, selector)
+				}
+				step = jsonPathStep{index: index, isIndex: true}
+			}
+		default:
+			return nil, fmt.Errorf("JSONPath '%s' has an unexpected '%c'", expr, rest[0])
+		}
+		path.steps = append(path.steps, step)
+	}
+	return path, nil
+}
+
+// closingBracket returns the index of the ] that closes the [ at the start of s, skipping quoted keys
+func closingBracket(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote != 0 && s[i] == '\\':
+			i++
+		case quote != 0 && s[i] == quote:
+			quote = 0
+		case quote == 0 && (s[i] == '\'' || s[i] == '"'):
+			quote = s[i]
+		case quote == 0 && s[i] == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+func unquoteJSONPathKey(selector string) (string, error) {
+	if strings.HasPrefix(selector, "'") {
+		if len(selector) < 2 || !strings.HasSuffix(selector, "'") {
+			return "", fmt.Errorf("unterminated key")
+		}
+		inner := strings.ReplaceAll(selector[1:len(selector)-1], `\'`, `'`)
+		selector = strconv.Quote(inner)
+	}
+	return strconv.Unquote(selector)
+}
+
+// String returns the expression the path was parsed from
+func (p *JSONPath) String() string {
+	return p.expr
+}
+
+// Select returns the value at the path in a decoded JSON value. A path with wildcards selects a list
+// of every matching value. It reports false when the path does not match any value.
+func (p *JSONPath) Select(value interface{}) (interface{}, bool) {
+	values := []interface{}{value}
+	wildcard := false
+	for _, step := range p.steps {
+		var next []interface{}
+		for _, v := range values {
+			switch current := v.(type) {
+			case map[string]interface{}:
+				if step.wildcard {
+					for _, key := range sortedKeys(current) {
+						next = append(next, current[key])
+					}
+				} else if !step.isIndex {
+					if child, ok := current[step.key]; ok {
+						next = append(next, child)
+					}
+				}
+			case []interface{}:
+				switch {
+				case step.wildcard:
+					next = append(next, current...)
+				case step.isIndex:
+					index := step.index
+					if index < 0 {
+						index += len(current)
+					}
+					if index >= 0 && index < len(current) {
+						next = append(next, current[index])
+					}
+				}
+			}
+		}
+		wildcard = wildcard || step.wildcard
+		values = next
+	}
+
+	if wildcard {
+		if values == nil {
+			values = []interface{}{}
+		}
+		return values, true
+	}
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
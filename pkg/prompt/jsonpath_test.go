@@ -0,0 +1,57 @@
+package prompt
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPath(t *testing.T) {
+	var doc interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"status": "ok",
+		"items": [{"name": "a", "tags": ["x"]}, {"name": "b", "tags": []}],
+		"odd key": {"a.b": 1}
+	}`), &doc))
+
+	tests := []struct {
+		path     string
+		expected interface{}
+		found    bool
+	}{
+		{path: "$", expected: doc, found: true},
+		{path: "$.status", expected: "ok", found: true},
+		{path: "$.items[1].name", expected: "b", found: true},
+		{path: "$.items[-1].name", expected: "b", found: true},
+		{path: "$['odd key'][\"a.b\"]", expected: 1.0, found: true},
+		{path: "$.items[*].name", expected: []interface{}{"a", "b"}, found: true},
+		{path: "$.items.*.tags[0]", expected: []interface{}{"x"}, found: true},
+		{path: "$.missing", found: false},
+		{path: "$.items[2]", found: false},
+		{path: "$.status.length", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path, err := ParseJSONPath(tt.path)
+			require.NoError(t, err)
+			value, found := path.Select(doc)
+			require.Equal(t, tt.found, found)
+			require.Equal(t, tt.expected, value)
+		})
+	}
+
+	t.Run("invalid paths", func(t *testing.T) {
+		for path, message := range map[string]string{
+			"status":     "JSONPath 'status' must start with $",
+			"$..name":    "JSONPath '$..name' uses recursive descent, which is not supported",
+			"$.items[0":  "JSONPath '$.items[0' has an unclosed [",
+			"$.items[a]": "JSONPath '$.items[a]' has an invalid index [a]",
+			"$name":      "JSONPath '$name' has an unexpected 'n'",
+		} {
+			_, err := ParseJSONPath(path)
+			require.EqualError(t, err, message)
+		}
+	})
+}
@@ -0,0 +1,425 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SchemaViolation describes a single place where a JSON value does not conform to a JSON schema
+type SchemaViolation struct {
+	// Path is a JSONPath-like location of the offending value, such as $.items[0].name
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// String returns the violation formatted as "path: message"
+func (v SchemaViolation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// SchemaValidationError is returned when a model response does not conform to the prompt's JSON schema
+type SchemaValidationError struct {
+	Violations []SchemaViolation
+}
+
+func (e *SchemaValidationError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("response does not match the JSON schema:")
+	for _, v := range e.Violations {
+		sb.WriteString("\n  - " + v.String())
+	}
+	return sb.String()
+}
+
+// ResponseSchema returns the JSON schema the model response must conform to, or nil if the
+// prompt file does not use responseFormat json_schema
+func (f *File) ResponseSchema() map[string]interface{} {
+	if f.ResponseFormat == nil || *f.ResponseFormat != "json_schema" || f.JsonSchema == nil {
+		return nil
+	}
+	schema, _ := f.JsonSchema.Parsed["schema"].(map[string]interface{})
+	return schema
+}
+
+// ValidateResponse validates a model response against the prompt's JSON schema. It returns
+// nil if the response conforms or if the prompt file does not use responseFormat json_schema.
+func (f *File) ValidateResponse(content string) []SchemaViolation {
+	schema := f.ResponseSchema()
+	if schema == nil {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(content)), &value); err != nil {
+		return []SchemaViolation{{Path: "$", Message: fmt.Sprintf("response is not valid JSON: %v", err)}}
+	}
+
+	return ValidateJSONSchema(schema, value)
+}
+
+// SchemaRepairPrompt returns a user message asking the model to fix a response with the given violations
+func SchemaRepairPrompt(violations []SchemaViolation) string {
+	var sb strings.Builder
+	sb.WriteString("Your previous response does not match the required JSON schema:\n")
+	for _, v := range violations {
+		sb.WriteString("- " + v.String() + "\n")
+	}
+	sb.WriteString("\nRespond again with only the corrected JSON.")
+	return sb.String()
+}
+
+// ValidateJSONSchema validates a decoded JSON value against a JSON schema.
+//
+// It supports the subset of JSON Schema used for structured outputs: type, enum, const,
+// properties, required, additionalProperties, items, prefixItems, minItems, maxItems,
+// uniqueItems, minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum,
+// exclusiveMaximum, multipleOf, minProperties, maxProperties, allOf, anyOf, oneOf, not
+// and local $ref pointers such as #/$defs/name.
+func ValidateJSONSchema(schema map[string]interface{}, value interface{}) []SchemaViolation {
+	v := &schemaValidator{root: schema}
+	v.validate(schema, value, "$")
+	return v.violations
+}
+
+type schemaValidator struct {
+	root       map[string]interface{}
+	violations []SchemaViolation
+	depth      int
+}
+
+// maxRefDepth guards against infinitely recursive $ref definitions
+const maxRefDepth = 64
+
+func (v *schemaValidator) addf(path, format string, args ...interface{}) {
+	v.violations = append(v.violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) validate(schema map[string]interface{}, value interface{}, path string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		resolved, err := v.resolveRef(ref)
+		if err != nil {
+			v.addf(path, "%v", err)
+			return
+		}
+		if v.depth >= maxRefDepth {
+			v.addf(path, "$ref %s is nested too deeply", ref)
+			return
+		}
+		v.depth++
+		v.validate(resolved, value, path)
+		v.depth--
+	}
+
+	if types, ok := schemaTypes(schema["type"]); ok && !matchesAnyType(value, types) {
+		v.addf(path, "expected %s, got %s", strings.Join(types, " or "), jsonTypeName(value))
+		// The remaining keywords assume the value has the expected type
+		return
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range enum {
+			if jsonEqual(candidate, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.addf(path, "value %s is not one of %s", formatJSON(value), formatJSON(enum))
+		}
+	}
+
+	if constValue, ok := schema["const"]; ok && !jsonEqual(constValue, value) {
+		v.addf(path, "value %s does not equal %s", formatJSON(value), formatJSON(constValue))
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(schema, typed, path)
+	case []interface{}:
+		v.validateArray(schema, typed, path)
+	case string:
+		v.validateString(schema, typed, path)
+	case float64:
+		v.validateNumber(schema, typed, path)
+	}
+
+	v.validateCombinators(schema, value, path)
+}
+
+func (v *schemaValidator) validateObject(schema map[string]interface{}, obj map[string]interface{}, path string) {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, present := obj[name]; !present {
+				v.addf(path, "missing required property %q", name)
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	for _, key := range sortedKeys(obj) {
+		childPath := joinPath(path, key)
+		if propSchema, ok := properties[key].(map[string]interface{}); ok {
+			v.validate(propSchema, obj[key], childPath)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.addf(childPath, "additional property %q is not allowed", key)
+			}
+		case map[string]interface{}:
+			v.validate(additional, obj[key], childPath)
+		}
+	}
+
+	if n, ok := schemaInt(schema["minProperties"]); ok && len(obj) < n {
+		v.addf(path, "expected at least %d properties, got %d", n, len(obj))
+	}
+	if n, ok := schemaInt(schema["maxProperties"]); ok && len(obj) > n {
+		v.addf(path, "expected at most %d properties, got %d", n, len(obj))
+	}
+}
+
+func (v *schemaValidator) validateArray(schema map[string]interface{}, arr []interface{}, path string) {
+	prefixItems, _ := schema["prefixItems"].([]interface{})
+	for i, item := range arr {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		if i < len(prefixItems) {
+			if itemSchema, ok := prefixItems[i].(map[string]interface{}); ok {
+				v.validate(itemSchema, item, itemPath)
+			}
+			continue
+		}
+		if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+			v.validate(itemSchema, item, itemPath)
+		}
+	}
+
+	if n, ok := schemaInt(schema["minItems"]); ok && len(arr) < n {
+		v.addf(path, "expected at least %d items, got %d", n, len(arr))
+	}
+	if n, ok := schemaInt(schema["maxItems"]); ok && len(arr) > n {
+		v.addf(path, "expected at most %d items, got %d", n, len(arr))
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if jsonEqual(arr[i], arr[j]) {
+					v.addf(path, "items %d and %d are equal but items must be unique", i, j)
+				}
+			}
+		}
+	}
+}
+
+func (v *schemaValidator) validateString(schema map[string]interface{}, s string, path string) {
+	length := len([]rune(s))
+	if n, ok := schemaInt(schema["minLength"]); ok && length < n {
+		v.addf(path, "expected at least %d characters, got %d", n, length)
+	}
+	if n, ok := schemaInt(schema["maxLength"]); ok && length > n {
+		v.addf(path, "expected at most %d characters, got %d", n, length)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			v.addf(path, "invalid pattern %q in schema: %v", pattern, err)
+		} else if !re.MatchString(s) {
+			v.addf(path, "value %q does not match pattern %q", s, pattern)
+		}
+	}
+}
+
+func (v *schemaValidator) validateNumber(schema map[string]interface{}, n float64, path string) {
+	if min, ok := schema["minimum"].(float64); ok && n < min {
+		v.addf(path, "value %v is less than the minimum %v", n, min)
+	}
+	if max, ok := schema["maximum"].(float64); ok && n > max {
+		v.addf(path, "value %v is greater than the maximum %v", n, max)
+	}
+	if min, ok := schema["exclusiveMinimum"].(float64); ok && n <= min {
+		v.addf(path, "value %v must be greater than %v", n, min)
+	}
+	if max, ok := schema["exclusiveMaximum"].(float64); ok && n >= max {
+		v.addf(path, "value %v must be less than %v", n, max)
+	}
+	if multiple, ok := schema["multipleOf"].(float64); ok && multiple > 0 {
+		quotient := n / multiple
+		if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			v.addf(path, "value %v is not a multiple of %v", n, multiple)
+		}
+	}
+}
+
+func (v *schemaValidator) validateCombinators(schema map[string]interface{}, value interface{}, path string) {
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range allOf {
+			if subSchema, ok := sub.(map[string]interface{}); ok {
+				v.validate(subSchema, value, path)
+			}
+		}
+	}
+
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		if v.countMatches(anyOf, value, path) == 0 {
+			v.addf(path, "value does not match any of the allowed schemas")
+		}
+	}
+
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		if matches := v.countMatches(oneOf, value, path); matches != 1 {
+			v.addf(path, "value must match exactly one schema, but matches %d", matches)
+		}
+	}
+
+	if not, ok := schema["not"].(map[string]interface{}); ok {
+		if v.countMatches([]interface{}{not}, value, path) == 1 {
+			v.addf(path, "value must not match the schema")
+		}
+	}
+}
+
+// countMatches returns how many of the given schemas the value conforms to, without
+// recording the violations of the schemas that did not match
+func (v *schemaValidator) countMatches(schemas []interface{}, value interface{}, path string) int {
+	matches := 0
+	for _, sub := range schemas {
+		subSchema, ok := sub.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		nested := &schemaValidator{root: v.root, depth: v.depth}
+		nested.validate(subSchema, value, path)
+		if len(nested.violations) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+// resolveRef resolves a local JSON pointer such as #/$defs/address against the root schema
+func (v *schemaValidator) resolveRef(ref string) (map[string]interface{}, error) {
+	if ref == "#" {
+		return v.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %q: only local references are supported", ref)
+	}
+
+	var current interface{} = v.root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		if current, ok = obj[token]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+
+	resolved, ok := current.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("$ref %q does not point to a schema", ref)
+	}
+	return resolved, nil
+}
+
+func schemaTypes(t interface{}) ([]string, bool) {
+	switch typed := t.(type) {
+	case string:
+		return []string{typed}, true
+	case []interface{}:
+		var types []string
+		for _, item := range typed {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types, len(types) > 0
+	default:
+		return nil, false
+	}
+}
+
+func matchesAnyType(value interface{}, types []string) bool {
+	for _, t := range types {
+		switch t {
+		case "integer":
+			if n, ok := value.(float64); ok && n == math.Trunc(n) {
+				return true
+			}
+		case "number":
+			if _, ok := value.(float64); ok {
+				return true
+			}
+		default:
+			if jsonTypeName(value) == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func schemaInt(value interface{}) (int, bool) {
+	n, ok := value.(float64)
+	return int(n), ok
+}
+
+func jsonEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func formatJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func joinPath(path, key string) string {
+	if identifierPattern.MatchString(key) {
+		return path + "." + key
+	}
+	return path + "[" + strconv.Quote(key) + "]"
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
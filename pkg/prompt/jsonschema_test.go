@@ -0,0 +1,175 @@
+package prompt
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateJSONSchema(t *testing.T) {
+	const personSchema = `{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"age": {"type": "integer", "minimum": 0},
+			"email": {"type": ["string", "null"], "pattern": "^[^@]+@[^@]+$"},
+			"role": {"enum": ["admin", "user"]},
+			"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+			"address": {"$ref": "#/$defs/address"}
+		},
+		"required": ["name", "age"],
+		"additionalProperties": false,
+		"$defs": {
+			"address": {
+				"type": "object",
+				"properties": {"city": {"type": "string"}},
+				"required": ["city"]
+			}
+		}
+	}`
+
+	tests := []struct {
+		name     string
+		value    string
+		expected []string
+	}{
+		{
+			name:  "valid value",
+			value: `{"name": "Ada", "age": 36, "email": null, "role": "admin", "tags": ["math"], "address": {"city": "London"}}`,
+		},
+		{
+			name:     "wrong root type",
+			value:    `["Ada"]`,
+			expected: []string{"$: expected object, got array"},
+		},
+		{
+			name:     "missing required property",
+			value:    `{"name": "Ada"}`,
+			expected: []string{`$: missing required property "age"`},
+		},
+		{
+			name:     "wrong property types",
+			value:    `{"name": 1, "age": 36.5}`,
+			expected: []string{"$.age: expected integer, got number", "$.name: expected string, got number"},
+		},
+		{
+			name:     "additional property",
+			value:    `{"name": "Ada", "age": 36, "first name": "Ada"}`,
+			expected: []string{`$["first name"]: additional property "first name" is not allowed`},
+		},
+		{
+			name:  "nested violations",
+			value: `{"name": "", "age": -1, "role": "owner", "tags": ["a", 2, "c"], "address": {}, "email": "nope"}`,
+			expected: []string{
+				`$.address: missing required property "city"`,
+				"$.age: value -1 is less than the minimum 0",
+				`$.email: value "nope" does not match pattern "^[^@]+@[^@]+$"`,
+				"$.name: expected at least 1 characters, got 0",
+				`$.role: value "owner" is not one of ["admin","user"]`,
+				"$.tags[1]: expected string, got number",
+				"$.tags: expected at most 2 items, got 3",
+			},
+		},
+	}
+
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(personSchema), &schema))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value interface{}
+			require.NoError(t, json.Unmarshal([]byte(tt.value), &value))
+
+			var actual []string
+			for _, v := range ValidateJSONSchema(schema, value) {
+				actual = append(actual, v.String())
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+
+	t.Run("combinators", func(t *testing.T) {
+		var schema map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"anyOf": [{"type": "string"}, {"type": "number"}],
+			"not": {"const": "forbidden"}
+		}`), &schema))
+
+		require.Empty(t, ValidateJSONSchema(schema, "hello"))
+		require.Empty(t, ValidateJSONSchema(schema, 3.0))
+		require.Len(t, ValidateJSONSchema(schema, true), 1)
+		require.Len(t, ValidateJSONSchema(schema, "forbidden"), 1)
+	})
+}
+
+func TestValidateResponse(t *testing.T) {
+	const yamlBody = `
+name: JSON Schema Test
+model: openai/gpt-4o
+responseFormat: json_schema
+jsonSchema: '{"name": "person", "schema": {"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}}'
+messages:
+  - role: user
+    content: "Generate a person"
+`
+	promptFilePath := filepath.Join(t.TempDir(), "test.prompt.yml")
+	require.NoError(t, os.WriteFile(promptFilePath, []byte(yamlBody), 0644))
+
+	promptFile, err := LoadFromFile(promptFilePath)
+	require.NoError(t, err)
+	require.NotNil(t, promptFile.ResponseSchema())
+
+	require.Empty(t, promptFile.ValidateResponse(`{"name": "Ada"}`))
+
+	violations := promptFile.ValidateResponse(`{}`)
+	require.Equal(t, []SchemaViolation{{Path: "$", Message: `missing required property "name"`}}, violations)
+
+	violations = promptFile.ValidateResponse("not json")
+	require.Len(t, violations, 1)
+	require.Contains(t, violations[0].Message, "response is not valid JSON")
+
+	repair := SchemaRepairPrompt(violations)
+	require.Contains(t, repair, "- $: response is not valid JSON")
+
+	t.Run("checks numeric keywords of YAML mapping schemas", func(t *testing.T) {
+		const yamlBody = `
+name: YAML Schema
+model: openai/gpt-4o
+responseFormat: json_schema
+jsonSchema:
+  name: tagged
+  schema:
+    type: object
+    properties:
+      tags:
+        type: array
+        minItems: 3
+      n:
+        type: number
+        minimum: 10
+messages:
+  - role: user
+    content: "Generate tags"
+`
+		promptFilePath := filepath.Join(t.TempDir(), "yaml.prompt.yml")
+		require.NoError(t, os.WriteFile(promptFilePath, []byte(yamlBody), 0644))
+
+		promptFile, err := LoadFromFile(promptFilePath)
+		require.NoError(t, err)
+
+		var paths []string
+		for _, v := range promptFile.ValidateResponse(`{"tags": [], "n": 1}`) {
+			paths = append(paths, v.Path)
+		}
+		require.ElementsMatch(t, []string{"$.tags", "$.n"}, paths)
+	})
+
+	t.Run("no validation without json_schema", func(t *testing.T) {
+		textFile := &File{Name: "text"}
+		require.Nil(t, textFile.ResponseSchema())
+		require.Empty(t, textFile.ValidateResponse("not json"))
+	})
+}
@@ -0,0 +1,398 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LintRule is a check for a likely problem with the quality of a prompt file
+type LintRule struct {
+	ID          string
+	Severity    Severity
+	Description string
+}
+
+// LintRules are the rules checked by LintFile
+var LintRules = []LintRule{
+	{
+		ID:          "undefined-variable",
+		Severity:    SeverityError,
+		Description: "A message uses a template variable that some testData rows do not set.",
+	},
+	{
+		ID:          "unused-test-data",
+		Severity:    SeverityWarning,
+		Description: "A testData key is not used by the messages or evaluators.",
+	},
+	{
+		ID:          "evaluator-undefined-variable",
+		Severity:    SeverityError,
+		Description: "An evaluator uses a template variable, such as {{expected}}, that some testData rows do not set.",
+	},
+	{
+		ID:          "untrusted-system-input",
+		Severity:    SeverityWarning,
+		Description: "A system message interpolates a variable that usually holds untrusted input, such as {{input}}.",
+	},
+	{
+		ID:          "response-format-conflict",
+		Severity:    SeverityWarning,
+		Description: "The messages ask for a different format than responseFormat.",
+	},
+	{
+		ID:          "missing-evaluators",
+		Severity:    SeverityWarning,
+		Description: "The prompt file has testData but no evaluators to score the responses.",
+	},
+}
+
+// untrustedVariables are variable names that usually hold input from users
+var untrustedVariables = []string{"input", "query", "question", "userInput", "user_input"}
+
+var (
+	// lintSuppression matches comments that disable lint rules, for the line they are on and the next line,
+	// or for the whole file with -file
+	lintSuppression = regexp.MustCompile(`#\s*gh-models-lint-disable(-file)?(?:\s+([\w\s,-]*))?$`)
+	asksForJSON     = regexp.MustCompile(`(?i)\b(respond|reply|answer|output|return|format)\b[^.\n]{0,40}\bjson\b`)
+	asksForFormat   = regexp.MustCompile(`(?i)\b(respond|reply|answer|output|return|format)\b[^.\n]{0,40}\b(markdown|plain text|yaml|xml|csv)\b`)
+	mentionsJSON    = regexp.MustCompile(`(?i)\bjson\b`)
+)
+
+// LintOption configures how a prompt file is linted
+type LintOption func(*lintOptions)
+
+type lintOptions struct {
+	disabled map[string]bool
+	plugins  map[string]LLMEvaluator
+}
+
+// WithDisabledRules turns off the lint rules with the given IDs
+func WithDisabledRules(ids ...string) LintOption {
+	return func(o *lintOptions) {
+		if o.disabled == nil {
+			o.disabled = make(map[string]bool)
+		}
+		for _, id := range ids {
+			o.disabled[id] = true
+		}
+	}
+}
+
+// WithPluginEvaluators sets the LLM evaluators of the built-in plugins, by the name they are used
+// with, such as github/similarity, so that the variables their prompts use are checked
+func WithPluginEvaluators(evaluators map[string]LLMEvaluator) LintOption {
+	return func(o *lintOptions) {
+		o.plugins = evaluators
+	}
+}
+
+// LintFile checks the prompt file at the given path for likely quality problems, and returns them
+// ordered by position. Files that are not valid are reported with the problems found by Validate
+// instead. An error is only returned if the file cannot be read.
+//
+// Problems can be suppressed with a "# gh-models-lint-disable rule-id" comment on the line of the
+// problem or on the line before it, or for the whole file with "# gh-models-lint-disable-file rule-id".
+// Without rule IDs, the comments suppress every rule.
+func LintFile(filePath string, opts ...LintOption) ([]Diagnostic, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics := Validate(filePath, data)
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == SeverityError {
+			return diagnostics, nil
+		}
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return diagnostics, nil
+	}
+	pf, err := LoadFromFile(filePath)
+	if err != nil {
+		return append(diagnostics, Diagnostic{
+			File: filePath, Line: 1, Column: 1, Severity: SeverityError, Message: err.Error(),
+		}), nil
+	}
+
+	l := &linter{file: filePath, root: doc.Content[0], pf: pf}
+	for _, opt := range opts {
+		opt(&l.options)
+	}
+	l.lint()
+
+	diagnostics = append(diagnostics, suppressDiagnostics(l.diagnostics, string(data))...)
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return diagnostics, nil
+}
+
+type linter struct {
+	file    string
+	root    *yaml.Node
+	pf      *File
+	options lintOptions
+	// reported holds the rules and variables that were reported, to report each variable once
+	reported    map[string]bool
+	diagnostics []Diagnostic
+}
+
+func (l *linter) report(rule string, node *yaml.Node, format string, args ...interface{}) {
+	if l.options.disabled[rule] {
+		return
+	}
+	i := slices.IndexFunc(LintRules, func(r LintRule) bool { return r.ID == rule })
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		File:     l.file,
+		Line:     node.Line,
+		Column:   node.Column,
+		Severity: LintRules[i].Severity,
+		Message:  fmt.Sprintf(format, args...),
+		Rule:     rule,
+	})
+}
+
+// reportOnce reports a problem with a variable only the first time it is found
+func (l *linter) reportOnce(rule, variable string, node *yaml.Node, format string, args ...interface{}) {
+	if l.reported == nil {
+		l.reported = make(map[string]bool)
+	}
+	if l.reported[rule+"/"+variable] {
+		return
+	}
+	l.reported[rule+"/"+variable] = true
+	l.report(rule, node, format, args...)
+}
+
+// node returns the node at the path of keys and list indices in the file as written. Items that
+// come from other files through extends or include have no node of their own, so the closest
+// node on the path is returned for them.
+func (l *linter) node(path ...interface{}) *yaml.Node {
+	current := l.root
+	for _, step := range path {
+		var next *yaml.Node
+		switch s := step.(type) {
+		case string:
+			next = mappingValue(current, s)
+		case int:
+			// The index of an item only matches the file when the list includes no other files
+			if current.Kind == yaml.SequenceNode && s < len(current.Content) &&
+				!slices.ContainsFunc(current.Content, func(item *yaml.Node) bool { _, ok := includeReference(item); return ok }) {
+				next = current.Content[s]
+			}
+		}
+		if next == nil {
+			return current
+		}
+		current = next
+	}
+	return current
+}
+
+func (l *linter) lint() {
+	usedVariables := make(map[string]bool)
+
+	for i, message := range l.pf.Messages {
+		names, err := TemplateVariables(message.Content)
+		if err != nil {
+			continue
+		}
+		content := l.node("messages", i, "content")
+		for _, name := range names {
+			usedVariables[name] = true
+			if missing := l.rowsWithout(name); missing != "" {
+				l.reportOnce("undefined-variable", name, content, "{{%s}} in messages[%d] is not set by %s", name, i, missing)
+			}
+			if strings.EqualFold(message.Role, "system") && slices.Contains(untrustedVariables, name) {
+				l.report("untrusted-system-input", content, "the system message interpolates {{%s}}, which usually holds untrusted input; move it to a user message to reduce the risk of prompt injection", name)
+			}
+		}
+	}
+
+	// Evaluators that run a command, or plugins whose prompts are unknown, may use any testData key
+	usesAnyVariable := false
+	for i, evaluator := range l.pf.Evaluators {
+		llm := evaluator.LLM
+		if evaluator.Uses != "" {
+			if plugin, ok := l.options.plugins[evaluator.Uses]; ok {
+				llm = &plugin
+			} else {
+				usesAnyVariable = true
+			}
+		}
+
+		var templates []string
+		if evaluator.String != nil {
+			templates = append(templates, evaluator.String.Templates()...)
+		}
+		if evaluator.JSON != nil {
+			templates = append(templates, evaluator.JSON.Templates()...)
+		}
+		if llm != nil {
+			templates = append(templates, llm.Prompt)
+		}
+
+		node := l.node("evaluators", i)
+		for _, template := range templates {
+			names, err := TemplateVariables(template)
+			if err != nil {
+				continue
+			}
+			for _, name := range names {
+				usedVariables[name] = true
+				if name == "completion" && llm != nil {
+					continue
+				}
+				if missing := l.rowsWithout(name); missing != "" {
+					l.report("evaluator-undefined-variable", node, "evaluator '%s' uses {{%s}}, but it is not set by %s", evaluator.Name, name, missing)
+				}
+			}
+		}
+	}
+
+	if !usesAnyVariable {
+		l.lintTestData(usedVariables)
+	}
+	l.lintResponseFormat()
+
+	if len(l.pf.TestData) > 0 && len(l.pf.Evaluators) == 0 {
+		l.report("missing-evaluators", l.node("testData"), "the prompt file has testData but no evaluators, so gh models eval cannot score its responses")
+	}
+}
+
+// rowsWithout describes the testData rows that do not set the variable, or returns "" if every row
+// sets it, the variable has a default, or there are no rows
+func (l *linter) rowsWithout(name string) string {
+	if len(l.pf.TestData) == 0 {
+		return ""
+	}
+	for _, variable := range l.pf.Variables {
+		if variable.Name == name && variable.Default != nil {
+			return ""
+		}
+	}
+
+	var rows []string
+	for i, row := range l.pf.TestData {
+		if _, ok := row[name]; !ok {
+			rows = append(rows, fmt.Sprintf("%d", i+1))
+		}
+	}
+	switch {
+	case len(rows) == 0:
+		return ""
+	case len(rows) == len(l.pf.TestData):
+		return "any testData row"
+	case len(rows) == 1:
+		return "testData row " + rows[0]
+	default:
+		return "testData rows " + strings.Join(rows, ", ")
+	}
+}
+
+func (l *linter) lintTestData(usedVariables map[string]bool) {
+	var keys []string
+	firstRow := make(map[string]int)
+	for i, row := range l.pf.TestData {
+		for key := range row {
+			if _, ok := firstRow[key]; !ok {
+				firstRow[key] = i
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if firstRow[keys[i]] != firstRow[keys[j]] {
+			return firstRow[keys[i]] < firstRow[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	for _, key := range keys {
+		if !usedVariables[key] {
+			l.report("unused-test-data", l.node("testData", firstRow[key], key), "testData key '%s' is not used by the messages or evaluators", key)
+		}
+	}
+}
+
+func (l *linter) lintResponseFormat() {
+	format := ""
+	if l.pf.ResponseFormat != nil {
+		format = *l.pf.ResponseFormat
+	}
+
+	mentionedJSON := false
+	for i, message := range l.pf.Messages {
+		if mentionsJSON.MatchString(message.Content) {
+			mentionedJSON = true
+		}
+		content := l.node("messages", i, "content")
+		switch format {
+		case "json_object", "json_schema":
+			if match := asksForFormat.FindStringSubmatch(message.Content); match != nil {
+				l.report("response-format-conflict", content, "messages[%d] asks for %s, but responseFormat is %s", i, strings.ToLower(match[2]), format)
+			}
+		case "text":
+			if asksForJSON.MatchString(message.Content) {
+				l.report("response-format-conflict", content, "messages[%d] asks for JSON, but responseFormat is text", i)
+			}
+		}
+	}
+
+	if format == "json_object" && !mentionedJSON {
+		l.report("response-format-conflict", l.node("responseFormat"), "responseFormat is json_object, but no message mentions JSON, which the API requires")
+	}
+}
+
+// suppressDiagnostics removes the diagnostics that are disabled by comments in the file
+func suppressDiagnostics(diagnostics []Diagnostic, data string) []Diagnostic {
+	type suppression struct {
+		line int
+		file bool
+		// rules are the disabled rules, or nil for every rule
+		rules []string
+	}
+
+	var suppressions []suppression
+	for i, line := range strings.Split(data, "\n") {
+		match := lintSuppression.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil {
+			continue
+		}
+		suppressions = append(suppressions, suppression{
+			line:  i + 1,
+			file:  match[1] != "",
+			rules: strings.FieldsFunc(match[2], func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }),
+		})
+	}
+	if len(suppressions) == 0 {
+		return diagnostics
+	}
+
+	var kept []Diagnostic
+	for _, diagnostic := range diagnostics {
+		suppressed := slices.ContainsFunc(suppressions, func(s suppression) bool {
+			if !s.file && diagnostic.Line != s.line && diagnostic.Line != s.line+1 {
+				return false
+			}
+			return len(s.rules) == 0 || slices.Contains(s.rules, diagnostic.Rule)
+		})
+		if !suppressed {
+			kept = append(kept, diagnostic)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,186 @@
+package prompt
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name     string
+		yamlBody string
+		opts     []LintOption
+		expected []string
+	}{
+		{
+			name: "clean prompt file",
+			yamlBody: `responseFormat: json_object
+messages:
+  - role: system
+    content: Reply with a JSON object.
+  - role: user
+    content: "{{input}}"
+testData:
+  - input: hi
+    expected: hello
+evaluators:
+  - name: greets
+    string:
+      contains: "{{expected}}"
+`,
+		},
+		{
+			name: "variables and testData",
+			yamlBody: `variables:
+  - name: tone
+    default: calm
+messages:
+  - role: system
+    content: "Use a {{tone}} tone. Context: {{input}}"
+  - role: user
+    content: "{{question}} {{#items}}{{name}}{{/items}}"
+testData:
+  - input: a
+    question: b
+    items: []
+    notes: unused
+  - input: c
+    items: []
+evaluators:
+  - name: matches
+    string:
+      equals: "{{expected}}"
+  - name: judge
+    llm:
+      modelId: openai/gpt-4o
+      prompt: "Is {{completion}} right?"
+      choices:
+        - choice: "yes"
+          score: 1
+`,
+			expected: []string{
+				"test.prompt.yml:6:14: warning: the system message interpolates {{input}}, which usually holds untrusted input; move it to a user message to reduce the risk of prompt injection [untrusted-system-input]",
+				"test.prompt.yml:8:14: error: {{question}} in messages[1] is not set by testData row 2 [undefined-variable]",
+				"test.prompt.yml:13:12: warning: testData key 'notes' is not used by the messages or evaluators [unused-test-data]",
+				"test.prompt.yml:17:5: error: evaluator 'matches' uses {{expected}}, but it is not set by any testData row [evaluator-undefined-variable]",
+			},
+		},
+		{
+			name: "response format conflicts",
+			yamlBody: `responseFormat: json_object
+messages:
+  - role: user
+    content: Answer in Markdown, with a heading.
+`,
+			expected: []string{
+				"test.prompt.yml:1:17: warning: responseFormat is json_object, but no message mentions JSON, which the API requires [response-format-conflict]",
+				"test.prompt.yml:4:14: warning: messages[0] asks for markdown, but responseFormat is json_object [response-format-conflict]",
+			},
+		},
+		{
+			name: "text format asking for JSON",
+			yamlBody: `responseFormat: text
+messages:
+  - role: user
+    content: Return the answer as JSON.
+`,
+			expected: []string{
+				"test.prompt.yml:4:14: warning: messages[0] asks for JSON, but responseFormat is text [response-format-conflict]",
+			},
+		},
+		{
+			name: "missing evaluators",
+			yamlBody: `messages:
+  - role: user
+    content: "{{input}}"
+testData:
+  - input: hi
+`,
+			expected: []string{
+				"test.prompt.yml:5:3: warning: the prompt file has testData but no evaluators, so gh models eval cannot score its responses [missing-evaluators]",
+			},
+		},
+		{
+			name: "suppression comments",
+			yamlBody: `# gh-models-lint-disable-file missing-evaluators
+messages:
+  - role: system
+    # gh-models-lint-disable untrusted-system-input
+    content: "{{input}}"
+  - role: user
+    content: "{{question}}" # gh-models-lint-disable
+testData:
+  - input: hi
+`,
+		},
+		{
+			name: "disabled rules",
+			yamlBody: `messages:
+  - role: user
+    content: "{{input}}"
+testData:
+  - input: hi
+`,
+			opts: []LintOption{WithDisabledRules("missing-evaluators")},
+		},
+		{
+			name: "plugin evaluators use their variables",
+			yamlBody: `messages:
+  - role: user
+    content: "{{question}}"
+testData:
+  - question: hi
+    expected: hello
+    context: greetings
+evaluators:
+  - name: similar
+    uses: github/similarity
+`,
+			opts: []LintOption{WithPluginEvaluators(map[string]LLMEvaluator{
+				"github/similarity": {Prompt: "Compare {{completion}} with {{expected}}"},
+			})},
+			expected: []string{
+				"test.prompt.yml:7:14: warning: testData key 'context' is not used by the messages or evaluators [unused-test-data]",
+			},
+		},
+		{
+			name: "command evaluators may use any testData key",
+			yamlBody: `messages:
+  - role: user
+    content: "{{question}}"
+testData:
+  - question: hi
+    context: greetings
+evaluators:
+  - name: check
+    uses: python3 check.py
+`,
+		},
+		{
+			name:     "invalid files are reported by validation",
+			yamlBody: "messages:\n  - role: robot\n    content: hi\n",
+			expected: []string{
+				"test.prompt.yml:2:11: error: unknown role 'robot' in messages[0], expected one of: system, user, assistant",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"test.prompt.yml": tt.yamlBody})
+
+			diagnostics, err := LintFile(filepath.Join(dir, "test.prompt.yml"), tt.opts...)
+			require.NoError(t, err)
+
+			var actual []string
+			for _, d := range diagnostics {
+				d.File = filepath.Base(d.File)
+				actual = append(actual, d.String())
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
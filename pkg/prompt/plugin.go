@@ -0,0 +1,46 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// BuiltinPluginPrefix is the prefix of the evaluator plugins that are built into the eval command.
+// Any other uses value is a command that is run to evaluate the response.
+const BuiltinPluginPrefix = "github/"
+
+// IsCommandPlugin reports whether a uses value is a command to run, rather than a built-in evaluator
+func IsCommandPlugin(uses string) bool {
+	return !strings.HasPrefix(uses, BuiltinPluginPrefix)
+}
+
+// ParsePluginCommand splits the command of a uses value into the program and its arguments, which
+// can be quoted as in a shell, such as "go run ./evaluators/check" or "python3 'my check.py'". A program
+// given as a relative path, such as ./evaluators/check.sh, is resolved against dir, the directory of
+// the prompt file, to an absolute path, so that it does not depend on the working directory of the
+// command. Other programs are looked up in PATH.
+func ParsePluginCommand(uses, dir string) ([]string, error) {
+	args, err := shellquote.Split(uses)
+	if err != nil {
+		return nil, fmt.Errorf("invalid command '%s': %w", uses, err)
+	}
+	if len(args) == 0 {
+		return nil, errors.New("the command is empty")
+	}
+	if program := args[0]; isRelativeProgram(program) {
+		if args[0], err = filepath.Abs(filepath.Join(dir, program)); err != nil {
+			return nil, fmt.Errorf("invalid program '%s': %w", program, err)
+		}
+	}
+	return args, nil
+}
+
+// isRelativeProgram reports whether the program of a command is a path relative to the prompt file,
+// rather than a program in PATH or an absolute path
+func isRelativeProgram(program string) bool {
+	return strings.ContainsAny(program, "/"+string(filepath.Separator)) && !filepath.IsAbs(program)
+}
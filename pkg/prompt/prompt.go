@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/github/gh-models/internal/azuremodels"
@@ -13,16 +14,21 @@ import (
 
 // File represents the structure of a .prompt.yml file
 type File struct {
-	Name            string          `yaml:"name"`
-	Description     string          `yaml:"description"`
-	Model           string          `yaml:"model"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Model       string `yaml:"model"`
+	// Models are only used by eval command, which evaluates the prompt with each of them
+	Models          []string        `yaml:"models,omitempty"`
 	ModelParameters ModelParameters `yaml:"modelParameters,omitempty"`
 	ResponseFormat  *string         `yaml:"responseFormat,omitempty"`
 	JsonSchema      *JsonSchema     `yaml:"jsonSchema,omitempty"`
+	Variables       []Variable      `yaml:"variables,omitempty"`
 	Messages        []Message       `yaml:"messages"`
 	// TestData and Evaluators are only used by eval command
 	TestData   []TestDataItem `yaml:"testData,omitempty"`
 	Evaluators []Evaluator    `yaml:"evaluators,omitempty"`
+	// TestDataSource is set when testData references files, which TestData is loaded from and saved to
+	TestDataSource *TestDataSource `yaml:"-"`
 }
 
 // ModelParameters represents model configuration parameters
@@ -46,15 +52,67 @@ type Evaluator struct {
 	Name   string           `yaml:"name"`
 	String *StringEvaluator `yaml:"string,omitempty"`
 	LLM    *LLMEvaluator    `yaml:"llm,omitempty"`
+	JSON   *JSONEvaluator   `yaml:"json,omitempty"`
 	Uses   string           `yaml:"uses,omitempty"`
+	// With is passed to the command of a uses evaluator that runs a command
+	With map[string]interface{} `yaml:"with,omitempty"`
 }
 
-// StringEvaluator represents string-based evaluation
+// StringEvaluator represents string-based evaluation. Every criterion that is set is checked, and
+// the evaluator passes when all of them pass, or any of them with Mode "any".
 type StringEvaluator struct {
-	EndsWith   string `yaml:"endsWith,omitempty"`
-	StartsWith string `yaml:"startsWith,omitempty"`
-	Contains   string `yaml:"contains,omitempty"`
-	Equals     string `yaml:"equals,omitempty"`
+	EndsWith    string   `yaml:"endsWith,omitempty"`
+	StartsWith  string   `yaml:"startsWith,omitempty"`
+	Contains    string   `yaml:"contains,omitempty"`
+	Equals      string   `yaml:"equals,omitempty"`
+	NotContains string   `yaml:"notContains,omitempty"`
+	ContainsAny []string `yaml:"containsAny,omitempty"`
+	ContainsAll []string `yaml:"containsAll,omitempty"`
+	Regex       string   `yaml:"regex,omitempty"`
+	MinLength   *int     `yaml:"minLength,omitempty"`
+	MaxLength   *int     `yaml:"maxLength,omitempty"`
+	MinLines    *int     `yaml:"minLines,omitempty"`
+	MaxLines    *int     `yaml:"maxLines,omitempty"`
+	// CaseSensitive applies to every criterion when it is set. Otherwise equals and regex are case
+	// sensitive, and the other criteria are not.
+	CaseSensitive *bool `yaml:"caseSensitive,omitempty"`
+	// NormalizeWhitespace trims the strings and collapses runs of whitespace before comparing them
+	NormalizeWhitespace bool   `yaml:"normalizeWhitespace,omitempty"`
+	Mode                string `yaml:"mode,omitempty"`
+}
+
+const (
+	// StringModeAll passes a string evaluator when all of its criteria pass, which is the default
+	StringModeAll = "all"
+	// StringModeAny passes a string evaluator when any of its criteria passes
+	StringModeAny = "any"
+)
+
+// StringCriteria are the keys of a string evaluator that check the response, as opposed to options
+var StringCriteria = []string{"equals", "contains", "startsWith", "endsWith", "notContains", "containsAny", "containsAll", "regex", "minLength", "maxLength", "minLines", "maxLines"}
+
+// Templates returns the strings of the evaluator that are rendered with the test case variables
+func (e *StringEvaluator) Templates() []string {
+	templates := []string{e.Equals, e.Contains, e.StartsWith, e.EndsWith, e.NotContains, e.Regex}
+	templates = append(templates, e.ContainsAny...)
+	return append(templates, e.ContainsAll...)
+}
+
+// JSONEvaluator checks that the response is valid JSON. It can also check the response against
+// a JSON schema, and compare the value at a JSONPath with Equals, Contains and Regex.
+type JSONEvaluator struct {
+	Schema *JsonSchema `yaml:"schema,omitempty"`
+	// Path selects the value that Equals, Contains and Regex are checked against, which is the whole response by default
+	Path string `yaml:"path,omitempty"`
+	// Equals is compared with the value as JSON, ignoring key order and whitespace, or as a string when it is not valid JSON
+	Equals   string `yaml:"equals,omitempty"`
+	Contains string `yaml:"contains,omitempty"`
+	Regex    string `yaml:"regex,omitempty"`
+}
+
+// Templates returns the strings of the evaluator that are rendered with the test case variables
+func (e *JSONEvaluator) Templates() []string {
+	return []string{e.Equals, e.Contains, e.Regex}
 }
 
 // LLMEvaluator represents LLM-based evaluation
@@ -71,48 +129,191 @@ type Choice struct {
 	Score  float64 `yaml:"score"`
 }
 
-// JsonSchema represents a JSON schema for structured responses
+// JsonSchema represents a JSON schema for structured responses. It can be written as a JSON
+// string, as a YAML mapping, or as a mapping with a $ref to a JSON or YAML file relative to the
+// prompt file. It is marshaled back in the form it was read.
 type JsonSchema struct {
 	Raw    string
 	Parsed map[string]interface{}
+	// Ref is the path of the file the schema is read from, when it is written as a $ref
+	Ref string
+
+	// node is the mapping the schema was read from, to keep its key order when it is marshaled
+	node *yaml.Node
+}
+
+// jsonSchemaRefKey is the key of a jsonSchema mapping that references a file
+const jsonSchemaRefKey = "$ref"
+
+// schemaFileRef returns the $ref of a schema mapping that references a file, which has no other keys.
+// Local references such as #/$defs/item are part of the schema instead.
+func schemaFileRef(node *yaml.Node) *yaml.Node {
+	if node.Kind != yaml.MappingNode || len(node.Content) != 2 || node.Content[0].Value != jsonSchemaRefKey {
+		return nil
+	}
+	if ref := node.Content[1]; ref.Kind != yaml.ScalarNode || !strings.HasPrefix(ref.Value, "#") {
+		return ref
+	}
+	return nil
 }
 
 // UnmarshalYAML implements custom YAML unmarshaling for JsonSchema
-// Only supports JSON string format
 func (js *JsonSchema) UnmarshalYAML(node *yaml.Node) error {
-	// Only support string nodes (JSON format)
-	if node.Kind != yaml.ScalarNode {
-		return fmt.Errorf("jsonSchema must be a JSON string")
+	switch node.Kind {
+	case yaml.ScalarNode:
+		var jsonStr string
+		if err := node.Decode(&jsonStr); err != nil {
+			return err
+		}
+
+		// Parse and validate the JSON schema
+		var parsed map[string]interface{}
+		if err := json.Unmarshal([]byte(jsonStr), &parsed); err != nil {
+			return fmt.Errorf("invalid JSON in jsonSchema: %w", err)
+		}
+
+		js.Raw = jsonStr
+		js.Parsed = parsed
+		return nil
+	case yaml.MappingNode:
+		if ref := schemaFileRef(node); ref != nil {
+			if ref.Kind != yaml.ScalarNode || ref.Value == "" {
+				return fmt.Errorf("the $ref of a jsonSchema must be the path of a file")
+			}
+			// The file is read by resolveRef, once the directory of the prompt file is known
+			js.Ref = ref.Value
+			return nil
+		}
+
+		var parsed map[string]interface{}
+		if err := node.Decode(&parsed); err != nil {
+			return fmt.Errorf("invalid jsonSchema: %w", err)
+		}
+		if err := js.setParsed(parsed); err != nil {
+			return err
+		}
+		js.node = node
+		return nil
+	default:
+		return fmt.Errorf("jsonSchema must be a JSON string, a mapping or a mapping with a $ref")
 	}
+}
 
-	var jsonStr string
-	if err := node.Decode(&jsonStr); err != nil {
-		return err
+// MarshalYAML implements custom YAML marshaling for JsonSchema, keeping the form it was read in
+func (js JsonSchema) MarshalYAML() (interface{}, error) {
+	switch {
+	case js.Ref != "":
+		return map[string]string{jsonSchemaRefKey: js.Ref}, nil
+	case js.node != nil:
+		return js.node, nil
+	default:
+		return js.Raw, nil
 	}
+}
 
-	// Parse and validate the JSON schema
+// resolveRef reads the schema from the file of a $ref, relative to the given directory
+func (js *JsonSchema) resolveRef(dir string) error {
+	if js.Ref == "" {
+		return nil
+	}
+
+	path := js.Ref
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read jsonSchema: %w", err)
+	}
+	// YAML is a superset of JSON, so both formats can be read the same way
 	var parsed map[string]interface{}
-	if err := json.Unmarshal([]byte(jsonStr), &parsed); err != nil {
-		return fmt.Errorf("invalid JSON in jsonSchema: %w", err)
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return fmt.Errorf("invalid jsonSchema in %s: %w", path, err)
 	}
+	return js.setParsed(parsed)
+}
 
-	js.Raw = jsonStr
-	js.Parsed = parsed
+// setParsed sets the schema from a decoded YAML mapping. The schema is parsed again from its JSON,
+// so that numbers are float64 as in schemas written as JSON strings.
+func (js *JsonSchema) setParsed(parsed map[string]interface{}) error {
+	raw, err := json.Marshal(parsed)
+	if err != nil {
+		return fmt.Errorf("invalid jsonSchema: %w", err)
+	}
+	js.Parsed = nil
+	if err := json.Unmarshal(raw, &js.Parsed); err != nil {
+		return fmt.Errorf("invalid jsonSchema: %w", err)
+	}
+	js.Raw = string(raw)
 	return nil
 }
 
-// LoadFromFile loads and parses a prompt file from the given path
-func LoadFromFile(filePath string) (*File, error) {
-	data, err := os.ReadFile(filePath)
+// LoadOption configures how a prompt file is loaded
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	lookupEnv func(string) (string, bool)
+}
+
+// WithEnvInterpolation replaces ${env:NAME} references in the values of the prompt file with
+// the value of the NAME environment variable. Use $${env:NAME} to keep a literal reference.
+func WithEnvInterpolation() LoadOption {
+	return func(o *loadOptions) {
+		o.lookupEnv = os.LookupEnv
+	}
+}
+
+// LoadFromFile loads and parses a prompt file from the given path, resolving the files it extends
+// and includes
+func LoadFromFile(filePath string, opts ...LoadOption) (*File, error) {
+	var options loadOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	node, err := resolveImports(filePath)
 	if err != nil {
 		return nil, err
 	}
 
+	if options.lookupEnv != nil {
+		if err := interpolateEnv(node, options.lookupEnv); err != nil {
+			return nil, err
+		}
+	}
+
+	// A testData reference is decoded separately, and its rows are loaded from the files
+	var source *TestDataSource
+	if testData := mappingValue(node, "testData"); testData != nil && testData.Kind != yaml.SequenceNode && testData.Tag != "!!null" {
+		source, err = decodeTestDataSource(testData, filepath.Dir(filePath))
+		if err != nil {
+			return nil, err
+		}
+		removeMappingKey(node, "testData")
+	}
+
 	var promptFile File
-	if err := yaml.Unmarshal(data, &promptFile); err != nil {
+	if err := node.Decode(&promptFile); err != nil {
+		return nil, err
+	}
+
+	if promptFile.JsonSchema != nil {
+		if err := promptFile.JsonSchema.resolveRef(filepath.Dir(filePath)); err != nil {
+			return nil, err
+		}
+	}
+	if err := resolveEvaluatorRefs(promptFile.Evaluators, filepath.Dir(filePath)); err != nil {
 		return nil, err
 	}
 
+	if source != nil {
+		promptFile.TestData, err = source.Load()
+		if err != nil {
+			return nil, err
+		}
+		promptFile.TestDataSource = source
+	}
+
 	if err := promptFile.validateResponseFormat(); err != nil {
 		return nil, err
 	}
@@ -120,18 +321,15 @@ func LoadFromFile(filePath string) (*File, error) {
 	return &promptFile, nil
 }
 
-// SaveToFile saves the prompt file to the specified path
-func (f *File) SaveToFile(filePath string) error {
-	data, err := yaml.Marshal(f)
-	if err != nil {
-		return fmt.Errorf("failed to marshal prompt file: %w", err)
-	}
-
-	err = os.WriteFile(filePath, data, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to write prompt file: %w", err)
+// resolveEvaluatorRefs reads the schemas of json evaluators that are written as a $ref
+func resolveEvaluatorRefs(evaluators []Evaluator, dir string) error {
+	for _, evaluator := range evaluators {
+		if evaluator.JSON != nil && evaluator.JSON.Schema != nil {
+			if err := evaluator.JSON.Schema.resolveRef(dir); err != nil {
+				return fmt.Errorf("evaluator '%s': %w", evaluator.Name, err)
+			}
+		}
 	}
-
 	return nil
 }
 
@@ -165,39 +363,6 @@ func (f *File) validateResponseFormat() error {
 	return nil
 }
 
-// TemplateString templates a string with the given data using simple {{variable}} replacement
-func TemplateString(templateStr string, data interface{}) (string, error) {
-	result := templateStr
-
-	// Convert data to map[string]interface{} if it's not already
-	var dataMap map[string]interface{}
-	switch d := data.(type) {
-	case map[string]interface{}:
-		dataMap = d
-	case map[string]string:
-		dataMap = make(map[string]interface{})
-		for k, v := range d {
-			dataMap[k] = v
-		}
-	default:
-		// If it's not a map, we can't template it
-		return result, nil
-	}
-
-	// Replace all {{variable}} patterns with values from the data map
-	for key, value := range dataMap {
-		placeholder := "{{" + key + "}}"
-		if valueStr, ok := value.(string); ok {
-			result = strings.ReplaceAll(result, placeholder, valueStr)
-		} else {
-			// Convert non-string values to string
-			result = strings.ReplaceAll(result, placeholder, fmt.Sprintf("%v", value))
-		}
-	}
-
-	return result, nil
-}
-
 // GetAzureChatMessageRole converts a role string to azuremodels.ChatMessageRole
 func GetAzureChatMessageRole(role string) (azuremodels.ChatMessageRole, error) {
 	switch strings.ToLower(role) {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/github/gh-models/internal/azuremodels"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 func TestPromptFile(t *testing.T) {
@@ -206,6 +207,86 @@ messages:
 		require.Contains(t, required, "habitat")
 	})
 
+	t.Run("loads jsonSchema as a YAML mapping or a $ref and marshals it in the same form", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "schemas"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "schemas", "animal.json"),
+			[]byte(`{"name": "describe_animal", "schema": {"type": "object"}}`), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "schemas", "animal.yml"),
+			[]byte("name: describe_animal\nschema:\n  type: object\n"), 0644))
+
+		tests := []struct {
+			name       string
+			jsonSchema string
+		}{
+			{
+				name:       "mapping",
+				jsonSchema: "jsonSchema:\n    name: describe_animal\n    strict: true\n    schema:\n        type: object\n",
+			},
+			{
+				name:       "JSON file",
+				jsonSchema: "jsonSchema:\n    $ref: schemas/animal.json\n",
+			},
+			{
+				name:       "YAML file",
+				jsonSchema: "jsonSchema:\n    $ref: schemas/animal.yml\n",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				promptFilePath := filepath.Join(tmpDir, "test.prompt.yml")
+				yamlBody := "responseFormat: json_schema\n" + tt.jsonSchema + "messages:\n  - role: user\n    content: Hello\n"
+				require.NoError(t, os.WriteFile(promptFilePath, []byte(yamlBody), 0644))
+
+				promptFile, err := LoadFromFile(promptFilePath)
+				require.NoError(t, err)
+				require.Equal(t, "describe_animal", promptFile.JsonSchema.Parsed["name"])
+				require.Equal(t, map[string]interface{}{"type": "object"}, promptFile.JsonSchema.Parsed["schema"])
+
+				var parsed map[string]interface{}
+				require.NoError(t, json.Unmarshal([]byte(promptFile.JsonSchema.Raw), &parsed))
+				require.Equal(t, promptFile.JsonSchema.Parsed, parsed)
+
+				data, err := yaml.Marshal(map[string]interface{}{"jsonSchema": promptFile.JsonSchema})
+				require.NoError(t, err)
+				require.Equal(t, tt.jsonSchema, string(data))
+			})
+		}
+	})
+
+	t.Run("marshals a jsonSchema string as a string", func(t *testing.T) {
+		js := &JsonSchema{}
+		require.NoError(t, yaml.Unmarshal([]byte(`'{"name": "x", "schema": {}}'`), js))
+
+		data, err := yaml.Marshal(js)
+		require.NoError(t, err)
+		require.Equal(t, "'{\"name\": \"x\", \"schema\": {}}'\n", string(data))
+	})
+
+	t.Run("only reads a $ref without other keys from a file", func(t *testing.T) {
+		js := &JsonSchema{}
+		require.NoError(t, yaml.Unmarshal([]byte("$ref: \"#/$defs/order\"\n$defs:\n  order: {type: object}\n"), js))
+		require.Empty(t, js.Ref)
+		require.Equal(t, "#/$defs/order", js.Parsed["$ref"])
+
+		js = &JsonSchema{}
+		require.NoError(t, yaml.Unmarshal([]byte("$ref: \"#/$defs/order\"\n"), js))
+		require.Empty(t, js.Ref)
+
+		js = &JsonSchema{}
+		require.EqualError(t, yaml.Unmarshal([]byte("$ref: \"\"\n"), js), "the $ref of a jsonSchema must be the path of a file")
+	})
+
+	t.Run("reads a jsonSchema $ref with an absolute path", func(t *testing.T) {
+		schemaPath := filepath.Join(t.TempDir(), "schema.json")
+		require.NoError(t, os.WriteFile(schemaPath, []byte(`{"name": "x", "schema": {"type": "object"}}`), 0644))
+
+		js := &JsonSchema{Ref: schemaPath}
+		require.NoError(t, js.resolveRef(t.TempDir()))
+		require.Equal(t, "x", js.Parsed["name"])
+	})
+
 	t.Run("validates invalid responseFormat", func(t *testing.T) {
 		const yamlBody = `
 name: Invalid Response Format Test
@@ -297,4 +378,38 @@ messages:
 		require.Equal(t, "object", schemaContent["type"])
 		require.Contains(t, schemaContent, "properties")
 	})
+
+	t.Run("interpolates environment variables when enabled", func(t *testing.T) {
+		const yamlBody = `
+name: Env Prompt
+model: ${env:PROMPT_MODEL}
+modelParameters:
+  maxTokens: ${env:PROMPT_MAX_TOKENS}
+messages:
+  - role: system
+    content: "Answer for ${env:PROMPT_TEAM}. Keep $${env:PROMPT_TEAM} as is."
+  - role: user
+    content: "{{input}}"
+`
+		t.Setenv("PROMPT_MODEL", "openai/gpt-4o-mini")
+		t.Setenv("PROMPT_MAX_TOKENS", "42")
+		t.Setenv("PROMPT_TEAM", "the docs team")
+
+		promptFilePath := filepath.Join(t.TempDir(), "env.prompt.yml")
+		require.NoError(t, os.WriteFile(promptFilePath, []byte(yamlBody), 0644))
+
+		promptFile, err := LoadFromFile(promptFilePath, WithEnvInterpolation())
+		require.NoError(t, err)
+		require.Equal(t, "openai/gpt-4o-mini", promptFile.Model)
+		require.Equal(t, 42, *promptFile.ModelParameters.MaxTokens)
+		require.Equal(t, "Answer for the docs team. Keep ${env:PROMPT_TEAM} as is.", promptFile.Messages[0].Content)
+
+		// Without interpolation the reference is not a valid maxTokens value
+		_, err = LoadFromFile(promptFilePath)
+		require.Error(t, err)
+
+		require.NoError(t, os.WriteFile(promptFilePath, []byte("name: Env Prompt\nmodel: openai/gpt-4o\nmessages:\n  - role: user\n    content: ${env:PROMPT_MISSING}\n"), 0644))
+		_, err = LoadFromFile(promptFilePath, WithEnvInterpolation())
+		require.EqualError(t, err, "line 5: environment variable PROMPT_MISSING is not set")
+	})
 }
@@ -0,0 +1,280 @@
+package prompt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlIndent is the indentation of the YAML written to prompt files
+const yamlIndent = 2
+
+// SaveToFile writes the whole prompt file to the specified path, replacing the file when it exists.
+// When testData references files, the rows are written to those files and the reference is kept.
+func (f *File) SaveToFile(filePath string) error {
+	if f.TestDataSource != nil {
+		if err := f.TestDataSource.Save(f.TestData); err != nil {
+			return err
+		}
+	}
+
+	data, err := f.marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal prompt file: %w", err)
+	}
+	return writePromptFile(filePath, data)
+}
+
+// UpdateTestDataAndEvaluators saves the testData and evaluators of the prompt file to the specified
+// path, and writes the whole file when it does not exist yet.
+//
+// When the file already exists, only its testData and evaluators are updated, so that the comments,
+// key order and formatting of the rest of the file are kept. Items of those lists that did not
+// change keep their formatting as well. When testData references files, the rows are written to
+// those files and the reference is kept.
+func (f *File) UpdateTestDataAndEvaluators(filePath string) error {
+	existing, err := os.ReadFile(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f.SaveToFile(filePath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read prompt file: %w", err)
+	}
+
+	if f.TestDataSource != nil {
+		if err := f.TestDataSource.Save(f.TestData); err != nil {
+			return err
+		}
+	}
+
+	data, err := f.patch(filePath, existing)
+	if err != nil {
+		return fmt.Errorf("failed to marshal prompt file: %w", err)
+	}
+	return writePromptFile(filePath, data)
+}
+
+func writePromptFile(filePath string, data []byte) error {
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write prompt file: %w", err)
+	}
+	return nil
+}
+
+// marshal encodes the whole prompt file
+func (f *File) marshal() ([]byte, error) {
+	var node yaml.Node
+	if err := node.Encode(f); err != nil {
+		return nil, err
+	}
+
+	if f.TestDataSource != nil {
+		var reference yaml.Node
+		if err := reference.Encode(f.TestDataSource); err != nil {
+			return nil, err
+		}
+		removeMappingKey(&node, "testData")
+		insertMappingValue(&node, "testData", &reference, "evaluators")
+	}
+	return encodeYAML(&node)
+}
+
+// patch updates the testData and evaluators of an existing prompt file, leaving the rest untouched.
+// Only the items that the file does not inherit from the files it extends are written, and include
+// items are kept as long as the items they include are unchanged.
+func (f *File) patch(filePath string, data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return f.marshal()
+	}
+	root := doc.Content[0]
+
+	// Lists that are unchanged are kept as written. The current file cannot be loaded if it is
+	// invalid, in which case both lists are written without looking at its imports.
+	current, err := LoadFromFile(filePath)
+	if err != nil {
+		current = &File{}
+	}
+	inherited, err := inheritedNode(filePath, root)
+	if err != nil {
+		inherited = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	dir := filepath.Dir(filePath)
+
+	if f.TestDataSource == nil && !sameItems(current.TestData, f.TestData) {
+		own := ownItems[TestDataItem](filePath, root, "testData")
+		if err := patchList(root, "testData", f.TestData, own, "evaluators"); err != nil {
+			return nil, err
+		}
+	}
+
+	if !sameItems(current.Evaluators, f.Evaluators) {
+		own := ownItems[Evaluator](filePath, root, "evaluators")
+		inheritedEvaluators, err := decodeItems[Evaluator](listItems(inherited, "evaluators"), dir)
+		if err != nil {
+			return nil, err
+		}
+
+		// Evaluators are merged by name, so an inherited evaluator is only written when the file
+		// already overrides it, or when it was changed
+		var evaluators []Evaluator
+		for _, evaluator := range f.Evaluators {
+			isInherited := slices.ContainsFunc(inheritedEvaluators, func(e Evaluator) bool { return reflect.DeepEqual(e, evaluator) })
+			isOwn := slices.ContainsFunc(own, func(item ownItem[Evaluator]) bool {
+				return slices.ContainsFunc(item.values, func(e Evaluator) bool { return e.Name == evaluator.Name })
+			})
+			if !isInherited || isOwn {
+				evaluators = append(evaluators, evaluator)
+			}
+		}
+		if err := patchList(root, "evaluators", evaluators, own, ""); err != nil {
+			return nil, err
+		}
+	}
+	return encodeYAML(&doc)
+}
+
+func sameItems[T any](current, updated []T) bool {
+	if len(current) == 0 && len(updated) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(current, updated)
+}
+
+// ownItem is an item of a list as written in a prompt file, along with the values it stands for:
+// the items of the included file for an include item, and the item itself otherwise
+type ownItem[T any] struct {
+	node   *yaml.Node
+	values []T
+}
+
+// ownItems returns the items of a list of the prompt file. Items that cannot be read are left out,
+// so that they are replaced.
+func ownItems[T any](filePath string, root *yaml.Node, key string) []ownItem[T] {
+	var items []ownItem[T]
+	for _, node := range listItems(root, key) {
+		expanded, err := expandItem(filePath, key, node)
+		if err != nil {
+			continue
+		}
+		values, err := decodeItems[T](expanded, filepath.Dir(filePath))
+		if err != nil || len(values) == 0 {
+			continue
+		}
+		items = append(items, ownItem[T]{node: node, values: values})
+	}
+	return items
+}
+
+// listItems returns the items of a list in the mapping, or nil if it is not set
+func listItems(root *yaml.Node, key string) []*yaml.Node {
+	list := mappingValue(root, key)
+	if list == nil || list.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return list.Content
+}
+
+// decodeItems decodes the nodes of list items as they are when the prompt file is loaded
+func decodeItems[T any](nodes []*yaml.Node, dir string) ([]T, error) {
+	var items []T
+	if err := (&yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: nodes}).Decode(&items); err != nil {
+		return nil, err
+	}
+	if evaluators, ok := any(items).([]Evaluator); ok {
+		if err := resolveEvaluatorRefs(evaluators, dir); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// patchList replaces the items of a list in the mapping. The nodes of the file's own items, including
+// include items, are reused where they stand for the next items, so that their formatting is kept. A
+// missing list is added before the given key, or at the end.
+func patchList[T any](root *yaml.Node, key string, items []T, own []ownItem[T], before string) error {
+	existing := mappingValue(root, key)
+	if len(items) == 0 {
+		removeMappingKey(root, key)
+		return nil
+	}
+
+	list := existing
+	if list == nil || list.Kind != yaml.SequenceNode {
+		list = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	}
+	used := make([]bool, len(own))
+
+	content := make([]*yaml.Node, 0, len(items))
+	for pos := 0; pos < len(items); {
+		node, n := findItemNode(own, used, items[pos:])
+		if node == nil {
+			node = &yaml.Node{}
+			if err := node.Encode(items[pos]); err != nil {
+				return err
+			}
+			n = 1
+		}
+		content = append(content, node)
+		pos += n
+	}
+	list.Content = content
+
+	if existing == nil {
+		insertMappingValue(root, key, list, before)
+	} else if existing != list {
+		setMappingValue(root, key, list)
+	}
+	return nil
+}
+
+// findItemNode returns an unused item node whose values are the first of the given items, and the
+// number of items it stands for
+func findItemNode[T any](own []ownItem[T], used []bool, items []T) (*yaml.Node, int) {
+	for i, item := range own {
+		n := len(item.values)
+		if used[i] || n > len(items) {
+			continue
+		}
+		if reflect.DeepEqual(item.values, items[:n]) {
+			used[i] = true
+			return item.node, n
+		}
+	}
+	return nil, 0
+}
+
+// insertMappingValue adds a key to the mapping before the given key, or at the end if it is not set
+func insertMappingValue(node *yaml.Node, key string, value *yaml.Node, before string) {
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == before {
+			node.Content = slices.Insert(node.Content, i, keyNode, value)
+			return
+		}
+	}
+	node.Content = append(node.Content, keyNode, value)
+}
+
+func encodeYAML(node *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(yamlIndent)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,245 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateTestDataAndEvaluators(t *testing.T) {
+	t.Run("only updates testData and evaluators of existing files", func(t *testing.T) {
+		const original = `# A prompt with comments
+model: openai/gpt-4o # the default model
+name: Summarizer
+responseFormat: json_schema
+jsonSchema: '{"name": "summary", "schema": {"type": "object"}}'
+messages:
+  - role: system
+    content: |
+      You summarize text.
+      Keep it short.
+  - role: user
+    content: "{{input}}"
+testData:
+  - input: old
+evaluators:
+  # Checks the output
+  - name: contains-summary
+    string:
+      contains: summary
+`
+		filePath := filepath.Join(t.TempDir(), "test.prompt.yml")
+		require.NoError(t, os.WriteFile(filePath, []byte(original), 0644))
+
+		pf, err := LoadFromFile(filePath)
+		require.NoError(t, err)
+		pf.TestData = []TestDataItem{{"input": "new", "expected": "summary"}}
+		pf.Evaluators = append(pf.Evaluators, Evaluator{Name: "similarity", Uses: "github/similarity"})
+		require.NoError(t, pf.UpdateTestDataAndEvaluators(filePath))
+
+		data, err := os.ReadFile(filePath)
+		require.NoError(t, err)
+		require.Equal(t, `# A prompt with comments
+model: openai/gpt-4o # the default model
+name: Summarizer
+responseFormat: json_schema
+jsonSchema: '{"name": "summary", "schema": {"type": "object"}}'
+messages:
+  - role: system
+    content: |
+      You summarize text.
+      Keep it short.
+  - role: user
+    content: "{{input}}"
+testData:
+  - expected: summary
+    input: new
+evaluators:
+  # Checks the output
+  - name: contains-summary
+    string:
+      contains: summary
+  - name: similarity
+    uses: github/similarity
+`, string(data))
+	})
+
+	t.Run("adds missing lists and keeps unchanged includes", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"evaluators.yml": "- name: similarity\n  uses: github/similarity\n",
+			"test.prompt.yml": `messages:
+  - role: user
+    content: hi
+evaluators:
+  - include: evaluators.yml
+`,
+		})
+		filePath := filepath.Join(dir, "test.prompt.yml")
+
+		pf, err := LoadFromFile(filePath)
+		require.NoError(t, err)
+		pf.TestData = []TestDataItem{{"input": "one"}}
+		require.NoError(t, pf.UpdateTestDataAndEvaluators(filePath))
+
+		data, err := os.ReadFile(filePath)
+		require.NoError(t, err)
+		require.Equal(t, `messages:
+  - role: user
+    content: hi
+testData:
+  - input: one
+evaluators:
+  - include: evaluators.yml
+`, string(data))
+	})
+
+	t.Run("keeps include items when their items are kept", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"shared.yml": "- input: one\n",
+			"test.prompt.yml": `messages:
+  - role: user
+    content: hi
+testData:
+  - include: shared.yml
+evaluators:
+  - include: evaluators.yml
+  - name: exact
+    string:
+      equals: "{{expected}}"
+`,
+			"evaluators.yml": "- name: similarity\n  uses: github/similarity\n",
+		})
+		filePath := filepath.Join(dir, "test.prompt.yml")
+
+		pf, err := LoadFromFile(filePath)
+		require.NoError(t, err)
+		pf.TestData = append(pf.TestData, TestDataItem{"input": "two"})
+		pf.Evaluators = pf.Evaluators[:1]
+		require.NoError(t, pf.UpdateTestDataAndEvaluators(filePath))
+
+		data, err := os.ReadFile(filePath)
+		require.NoError(t, err)
+		require.Equal(t, `messages:
+  - role: user
+    content: hi
+testData:
+  - include: shared.yml
+  - input: two
+evaluators:
+  - include: evaluators.yml
+`, string(data))
+	})
+
+	t.Run("does not copy inherited evaluators", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"base.prompt.yml": `model: openai/gpt-4o
+messages:
+  - role: user
+    content: "{{input}}"
+evaluators:
+  - name: similarity
+    uses: github/similarity
+  - name: polite
+    string:
+      notContains: rude
+`,
+			"child.prompt.yml": `extends: base.prompt.yml
+evaluators:
+  - name: polite
+    string:
+      notContains: impolite
+`,
+		})
+		filePath := filepath.Join(dir, "child.prompt.yml")
+
+		pf, err := LoadFromFile(filePath)
+		require.NoError(t, err)
+		pf.TestData = []TestDataItem{{"input": "one"}}
+		maxLength := 100
+		pf.Evaluators = append(pf.Evaluators, Evaluator{Name: "short", String: &StringEvaluator{MaxLength: &maxLength}})
+		require.NoError(t, pf.UpdateTestDataAndEvaluators(filePath))
+
+		data, err := os.ReadFile(filePath)
+		require.NoError(t, err)
+		require.Equal(t, `extends: base.prompt.yml
+testData:
+  - input: one
+evaluators:
+  - name: polite
+    string:
+      notContains: impolite
+  - name: short
+    string:
+      maxLength: 100
+`, string(data))
+
+		saved, err := LoadFromFile(filePath)
+		require.NoError(t, err)
+		require.Equal(t, pf.Evaluators, saved.Evaluators)
+	})
+
+	t.Run("writes new files in full", func(t *testing.T) {
+		filePath := filepath.Join(t.TempDir(), "new.prompt.yml")
+		pf := &File{Name: "New", Messages: []Message{{Role: "user", Content: "hi"}}}
+		require.NoError(t, pf.UpdateTestDataAndEvaluators(filePath))
+
+		saved, err := LoadFromFile(filePath)
+		require.NoError(t, err)
+		require.Equal(t, pf.Messages, saved.Messages)
+	})
+}
+
+func TestSaveToFile(t *testing.T) {
+	t.Run("writes new files in full", func(t *testing.T) {
+		filePath := filepath.Join(t.TempDir(), "new.prompt.yml")
+		pf := &File{
+			Name:     "New",
+			Model:    "openai/gpt-4o",
+			Messages: []Message{{Role: "user", Content: "{{input}}"}},
+			TestData: []TestDataItem{{"input": "hi"}},
+		}
+		require.NoError(t, pf.SaveToFile(filePath))
+
+		data, err := os.ReadFile(filePath)
+		require.NoError(t, err)
+		require.Equal(t, `name: New
+description: ""
+model: openai/gpt-4o
+messages:
+  - role: user
+    content: '{{input}}'
+testData:
+  - input: hi
+`, string(data))
+	})
+
+	t.Run("replaces existing files", func(t *testing.T) {
+		filePath := filepath.Join(t.TempDir(), "test.prompt.yml")
+		require.NoError(t, os.WriteFile(filePath, []byte(`# An old prompt
+model: openai/a
+modelParameters:
+  temperature: 0.5
+messages:
+  - role: user
+    content: old
+`), 0644))
+
+		pf := &File{Name: "New", Model: "openai/b", Messages: []Message{{Role: "user", Content: "new"}}}
+		require.NoError(t, pf.SaveToFile(filePath))
+
+		data, err := os.ReadFile(filePath)
+		require.NoError(t, err)
+		require.Equal(t, `name: New
+description: ""
+model: openai/b
+messages:
+  - role: user
+    content: new
+`, string(data))
+	})
+}
@@ -0,0 +1,368 @@
+package prompt
+
+import (
+	"reflect"
+	"strings"
+)
+
+// PromptFileSchemaID is the URL of the published JSON Schema for .prompt.yml files
+const PromptFileSchemaID = "https://raw.githubusercontent.com/github/gh-models/main/schemas/prompt.schema.json"
+
+// schemaField documents a field of the prompt file types in the JSON Schema
+type schemaField struct {
+	description string
+	required    bool
+	// extra holds additional keywords for the field, such as enum or minimum
+	extra map[string]interface{}
+	// schema replaces the schema of the field's type, for fields that read the type differently
+	schema map[string]interface{}
+}
+
+// schemaFields describes every field of File and its nested types, keyed by type and YAML name.
+// Adding a field to one of the types without describing it here fails the tests.
+var schemaFields = map[string]schemaField{
+	"File.name":            {description: "The name of the prompt."},
+	"File.description":     {description: "A description of what the prompt does."},
+	"File.model":           {description: "The ID of the model to run the prompt with, such as openai/gpt-4o."},
+	"File.models":          {description: "The IDs of several models to evaluate the prompt with in gh models eval, to compare their results."},
+	"File.modelParameters": {description: "Parameters sent to the model with each request."},
+	"File.responseFormat": {
+		description: "The format of the model's responses.",
+		extra:       map[string]interface{}{"enum": []interface{}{"text", "json_object", "json_schema"}},
+	},
+	"File.jsonSchema": {description: "The JSON Schema of the response when responseFormat is json_schema."},
+	"File.variables":  {description: "The template variables the messages expect, checked by run, eval and generate."},
+	"File.messages":   {description: "The messages sent to the model. Their content can use {{variable}} templates."},
+	"File.testData":   {description: "Rows of template variables, each used for one test case by gh models eval, or a reference to files with the rows."},
+	"File.evaluators": {description: "The evaluators that score each response in gh models eval."},
+
+	"ModelParameters.maxTokens": {
+		description: "The maximum number of tokens to generate.",
+		extra:       map[string]interface{}{"minimum": 1},
+	},
+	"ModelParameters.temperature": {
+		description: "The sampling temperature. Higher values make responses more random.",
+		extra:       map[string]interface{}{"minimum": 0, "maximum": 2},
+	},
+	"ModelParameters.topP": {
+		description: "The nucleus sampling probability mass.",
+		extra:       map[string]interface{}{"minimum": 0, "maximum": 1},
+	},
+
+	"Variable.name": {description: "The name of the variable, as used in {{name}}.", required: true},
+	"Variable.type": {
+		description: "The type of the value. Variables without a type accept any value.",
+		extra:       map[string]interface{}{"enum": []interface{}{"string", "number", "integer", "boolean", "array", "object"}},
+	},
+	"Variable.description": {description: "What the variable is for, shown by gh models run --describe."},
+	"Variable.default":     {description: "The value used when the variable is not set or empty."},
+	"Variable.required":    {description: "Whether the variable must be set, unless it has a default."},
+
+	"Message.role": {
+		description: "The role of the message author.",
+		required:    true,
+		extra:       map[string]interface{}{"enum": []interface{}{"system", "user", "assistant"}},
+	},
+	"Message.content": {description: "The content of the message.", required: true},
+
+	"Evaluator.name":   {description: "The name of the evaluator, shown in the results.", required: true},
+	"Evaluator.string": {description: "Compares the response with strings."},
+	"Evaluator.llm":    {description: "Asks a model to grade the response."},
+	"Evaluator.json":   {description: "Checks that the response is valid JSON, and optionally its schema and the value at a JSONPath."},
+	"Evaluator.uses":   {description: "A built-in evaluator, such as github/similarity, or a command that evaluates the response, such as ./evaluators/check.sh. Commands only run with --allow-exec."},
+	"Evaluator.with":   {description: "Configuration passed to the command of a uses evaluator."},
+
+	"StringEvaluator.endsWith":    {description: "Passes if the response ends with this string."},
+	"StringEvaluator.startsWith":  {description: "Passes if the response starts with this string."},
+	"StringEvaluator.contains":    {description: "Passes if the response contains this string."},
+	"StringEvaluator.equals":      {description: "Passes if the response is equal to this string."},
+	"StringEvaluator.notContains": {description: "Passes if the response does not contain this string."},
+	"StringEvaluator.containsAny": {description: "Passes if the response contains any of these strings."},
+	"StringEvaluator.containsAll": {description: "Passes if the response contains all of these strings."},
+	"StringEvaluator.regex":       {description: "Passes if the response matches this regular expression, in Go syntax."},
+	"StringEvaluator.minLength": {
+		description: "Passes if the response has at least this many characters.",
+		extra:       map[string]interface{}{"minimum": 0},
+	},
+	"StringEvaluator.maxLength": {
+		description: "Passes if the response has at most this many characters.",
+		extra:       map[string]interface{}{"minimum": 0},
+	},
+	"StringEvaluator.minLines": {
+		description: "Passes if the response has at least this many lines.",
+		extra:       map[string]interface{}{"minimum": 0},
+	},
+	"StringEvaluator.maxLines": {
+		description: "Passes if the response has at most this many lines.",
+		extra:       map[string]interface{}{"minimum": 0},
+	},
+	"StringEvaluator.caseSensitive":       {description: "Whether every comparison is case sensitive. By default only equals and regex are."},
+	"StringEvaluator.normalizeWhitespace": {description: "Trims the response and the strings, and collapses runs of whitespace, before comparing them."},
+	"StringEvaluator.mode": {
+		description: "Whether the evaluator passes when all of its criteria pass, or when any of them does.",
+		extra:       map[string]interface{}{"enum": []interface{}{"all", "any"}, "default": "all"},
+	},
+
+	"JSONEvaluator.schema": {
+		description: "The JSON Schema the response must match, as a JSON string, a mapping or a $ref to a file.",
+		// Unlike jsonSchema, the schema is not wrapped in name and schema properties
+		schema: map[string]interface{}{
+			"anyOf": []interface{}{
+				map[string]interface{}{"type": "string"},
+				map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{jsonSchemaRefKey: map[string]interface{}{"type": "string", "description": "The path of a JSON or YAML file with the schema, relative to this file, or a reference within the schema."}},
+				},
+			},
+		},
+	},
+	"JSONEvaluator.path":     {description: "A JSONPath such as $.items[0].name that selects the value to check. Defaults to the whole response."},
+	"JSONEvaluator.equals":   {description: "Passes if the value is equal to this JSON, ignoring key order and whitespace, or to this string."},
+	"JSONEvaluator.contains": {description: "Passes if the value, or its JSON when it is not a string, contains this string."},
+	"JSONEvaluator.regex":    {description: "Passes if the value, or its JSON when it is not a string, matches this regular expression."},
+
+	"LLMEvaluator.modelId":      {description: "The ID of the model that grades the response.", required: true},
+	"LLMEvaluator.prompt":       {description: "The grading prompt. It can use {{completion}} and the test case variables.", required: true},
+	"LLMEvaluator.choices":      {description: "The answers the grading model can give, with their scores.", required: true},
+	"LLMEvaluator.systemPrompt": {description: "The system prompt of the grading model."},
+
+	"Choice.choice": {description: "An answer the grading model can give.", required: true},
+	"Choice.score":  {description: "The score of the answer, from 0 to 1.", required: true},
+}
+
+// schemaTypeOverrides replaces the schema of types that are not decoded field by field
+var schemaTypeOverrides = map[reflect.Type]map[string]interface{}{
+	reflect.TypeOf(JsonSchema{}): {
+		"description": `The schema's "name", "strict" and "schema" properties, as a JSON string, a mapping, or a $ref to a JSON or YAML file.`,
+		"anyOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{
+				"type":                 "object",
+				"properties":           map[string]interface{}{jsonSchemaRefKey: map[string]interface{}{"type": "string", "description": "The path of a JSON or YAML file with the schema, relative to this file."}},
+				"required":             []interface{}{jsonSchemaRefKey},
+				"additionalProperties": false,
+			},
+			map[string]interface{}{
+				"type":     "object",
+				"required": []interface{}{"name", "schema"},
+				"properties": map[string]interface{}{
+					"name":   map[string]interface{}{"type": "string"},
+					"strict": map[string]interface{}{"type": "boolean"},
+					"schema": map[string]interface{}{"type": "object"},
+				},
+			},
+		},
+	},
+	reflect.TypeOf(TestDataItem{}): {
+		"type":        "object",
+		"description": "The values of the template variables for one test case.",
+	},
+}
+
+// includeSchema is the schema of list items that include the items of another file
+var includeSchema = map[string]interface{}{
+	"type":                 "object",
+	"properties":           map[string]interface{}{includeKey: map[string]interface{}{"type": "string", "description": "The path of a file with items to include, relative to this file."}},
+	"required":             []interface{}{includeKey},
+	"additionalProperties": false,
+}
+
+// testDataSourceSchema is the schema of a testData reference to files
+var testDataSourceSchema = map[string]interface{}{
+	"anyOf": []interface{}{
+		map[string]interface{}{"type": "string", "description": "The path or glob pattern of CSV, JSONL, JSON or YAML files, relative to this file."},
+		map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"file": map[string]interface{}{"type": "string", "description": "The path or glob pattern of CSV, JSONL, JSON or YAML files, relative to this file."},
+				"columns": map[string]interface{}{
+					"type":                 "object",
+					"description":          "Maps template variable names to the names of the columns in the files.",
+					"additionalProperties": map[string]interface{}{"type": "string"},
+				},
+			},
+			"required":             []interface{}{"file"},
+			"additionalProperties": false,
+		},
+	},
+}
+
+// schemaExtras adds keywords to the schema of struct types
+var schemaExtras = map[reflect.Type]map[string]interface{}{
+	reflect.TypeOf(File{}): {
+		// Messages can be inherited from the files a prompt file extends
+		"anyOf": []interface{}{
+			map[string]interface{}{"required": []interface{}{"messages"}},
+			map[string]interface{}{"required": []interface{}{extendsKey}},
+		},
+	},
+	reflect.TypeOf(Evaluator{}): {
+		"oneOf": []interface{}{
+			map[string]interface{}{"required": []interface{}{"string"}},
+			map[string]interface{}{"required": []interface{}{"llm"}},
+			map[string]interface{}{"required": []interface{}{"json"}},
+			map[string]interface{}{"required": []interface{}{"uses"}},
+		},
+	},
+	reflect.TypeOf(StringEvaluator{}): {"anyOf": stringCriteriaSchema()},
+}
+
+// stringCriteriaSchema requires a string evaluator to have at least one criterion
+func stringCriteriaSchema() []interface{} {
+	schemas := make([]interface{}, len(StringCriteria))
+	for i, criterion := range StringCriteria {
+		schemas[i] = map[string]interface{}{"required": []interface{}{criterion}}
+	}
+	return schemas
+}
+
+// PromptFileSchema returns a JSON Schema for .prompt.yml files, generated from File and its nested types
+func PromptFileSchema() map[string]interface{} {
+	g := &schemaGenerator{defs: make(map[string]interface{})}
+	schema := g.structSchema(reflect.TypeOf(File{}))
+
+	result := map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     PromptFileSchemaID,
+		"title":   "GitHub Models prompt file",
+		"$defs":   g.defs,
+	}
+	g.defs["Include"] = includeSchema
+
+	properties := schema["properties"].(map[string]interface{})
+	properties[extendsKey] = map[string]interface{}{
+		"description": "Prompt files to inherit fields from, relative to this file. Later files override earlier ones.",
+		"anyOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+	}
+	for _, section := range includableSections {
+		list := properties[section].(map[string]interface{})
+		list["items"] = map[string]interface{}{
+			"anyOf": []interface{}{list["items"], map[string]interface{}{"$ref": "#/$defs/Include"}},
+		}
+	}
+
+	// Test data can also be read from files
+	g.defs["TestDataSource"] = testDataSourceSchema
+	testData := properties["testData"].(map[string]interface{})
+	description := testData["description"]
+	delete(testData, "description")
+	properties["testData"] = map[string]interface{}{
+		"description": description,
+		"anyOf":       []interface{}{testData, map[string]interface{}{"$ref": "#/$defs/TestDataSource"}},
+	}
+
+	for key, value := range schema {
+		result[key] = value
+	}
+	return result
+}
+
+type schemaGenerator struct {
+	defs map[string]interface{}
+}
+
+func (g *schemaGenerator) typeSchema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if override, ok := schemaTypeOverrides[t]; ok {
+		return copySchema(override)
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			// Reserve the name first, so that recursive types terminate
+			g.defs[t.Name()] = nil
+			g.defs[t.Name()] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []interface{}
+
+	for _, field := range schemaStructFields(t) {
+		name := yamlFieldName(field)
+		meta := schemaFields[t.Name()+"."+name]
+		schema := g.typeSchema(field.Type)
+		if meta.schema != nil {
+			schema = copySchema(meta.schema)
+		}
+
+		if meta.description != "" {
+			schema["description"] = meta.description
+		}
+		for key, value := range meta.extra {
+			schema[key] = value
+		}
+		if meta.required {
+			required = append(required, name)
+		}
+		properties[name] = schema
+	}
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	for key, value := range schemaExtras[t] {
+		schema[key] = value
+	}
+	return schema
+}
+
+// schemaStructFields returns the fields of a struct that are read from YAML
+func schemaStructFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Tag.Get("yaml") == "-" {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// yamlFieldName returns the name of a struct field in YAML
+func yamlFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+func copySchema(schema map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		result[key] = value
+	}
+	return result
+}
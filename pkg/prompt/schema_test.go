@@ -0,0 +1,145 @@
+package prompt
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// updateSchema rewrites the checked-in schema, see the schema target in the Makefile
+var updateSchema = flag.Bool("update", false, "update the checked-in prompt file schema")
+
+const schemaPath = "../../schemas/prompt.schema.json"
+
+func TestPromptFileSchema(t *testing.T) {
+	generated, err := json.MarshalIndent(PromptFileSchema(), "", "  ")
+	require.NoError(t, err)
+	generated = append(generated, '\n')
+
+	t.Run("checked-in schema is up to date", func(t *testing.T) {
+		if *updateSchema {
+			require.NoError(t, os.WriteFile(schemaPath, generated, 0644))
+		}
+
+		checkedIn, err := os.ReadFile(schemaPath)
+		require.NoError(t, err)
+		require.Equal(t, string(checkedIn), string(generated),
+			"schemas/prompt.schema.json is out of date, run: make schema")
+	})
+
+	t.Run("every field is described", func(t *testing.T) {
+		require.Empty(t, undescribedSchemaFields(), "add the fields to schemaFields in schema.go")
+	})
+
+	t.Run("example prompt files conform to the schema", func(t *testing.T) {
+		var schema map[string]interface{}
+		require.NoError(t, json.Unmarshal(generated, &schema))
+
+		files, err := filepath.Glob("../../examples/*prompt*.yml")
+		require.NoError(t, err)
+		require.NotEmpty(t, files)
+
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			require.NoError(t, err)
+
+			var value interface{}
+			require.NoError(t, yaml.Unmarshal(data, &value))
+			// Round-trip through JSON to get the types the validator expects
+			jsonData, err := json.Marshal(value)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(jsonData, &value))
+
+			require.Empty(t, ValidateJSONSchema(schema, value), file)
+		}
+	})
+
+	t.Run("accepts extends and include items", func(t *testing.T) {
+		var schema map[string]interface{}
+		require.NoError(t, json.Unmarshal(generated, &schema))
+
+		var value interface{}
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"extends": ["base.prompt.yml"],
+			"testData": [{"include": "data.yml"}, {"input": "hi"}],
+			"evaluators": [{"include": "shared.yml"}]
+		}`), &value))
+		require.Empty(t, ValidateJSONSchema(schema, value))
+	})
+
+	t.Run("accepts inline evaluator schemas", func(t *testing.T) {
+		var schema map[string]interface{}
+		require.NoError(t, json.Unmarshal(generated, &schema))
+
+		var value interface{}
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"messages": [{"role": "user", "content": "hi"}],
+			"evaluators": [
+				{"name": "inline", "json": {"schema": {"type": "object", "required": ["id"]}}},
+				{"name": "local ref", "json": {"schema": {"$ref": "#/$defs/order", "$defs": {"order": {"type": "object"}}}}},
+				{"name": "file", "json": {"schema": {"$ref": "schemas/order.json"}}},
+				{"name": "string", "json": {"schema": "{\"type\": \"object\"}"}}
+			]
+		}`), &value))
+		require.Empty(t, ValidateJSONSchema(schema, value))
+	})
+
+	t.Run("rejects invalid prompt files", func(t *testing.T) {
+		var schema map[string]interface{}
+		require.NoError(t, json.Unmarshal(generated, &schema))
+
+		var value interface{}
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"modelParameters": {"maxTokens": 0},
+			"messages": [{"role": "robot", "content": "hi"}],
+			"evaluators": [{"name": "both", "string": {"contains": "x"}, "uses": "github/similarity"}]
+		}`), &value))
+
+		var violations []string
+		for _, v := range ValidateJSONSchema(schema, value) {
+			violations = append(violations, v.String())
+		}
+		require.Equal(t, []string{
+			"$.evaluators[0]: value does not match any of the allowed schemas",
+			"$.messages[0]: value does not match any of the allowed schemas",
+			"$.modelParameters.maxTokens: value 0 is less than the minimum 1",
+		}, violations)
+	})
+}
+
+// undescribedSchemaFields returns the fields of the prompt file types that are missing from schemaFields
+func undescribedSchemaFields() []string {
+	var missing []string
+	seen := make(map[reflect.Type]bool)
+
+	var visit func(t reflect.Type)
+	visit = func(t reflect.Type) {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct || seen[t] {
+			return
+		}
+		seen[t] = true
+		if _, ok := schemaTypeOverrides[t]; ok {
+			return
+		}
+
+		for _, field := range schemaStructFields(t) {
+			key := fmt.Sprintf("%s.%s", t.Name(), yamlFieldName(field))
+			if _, ok := schemaFields[key]; !ok {
+				missing = append(missing, key)
+			}
+			visit(field.Type)
+		}
+	}
+	visit(reflect.TypeOf(File{}))
+	return missing
+}
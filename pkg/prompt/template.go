@@ -0,0 +1,476 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Prompt templates use a small subset of Mustache:
+//
+//	{{name}}                     the value of a variable
+//	{{user.name}}                a nested value, or {{items.0}} for an element of a list
+//	{{name | default: "none"}}   a fallback for a missing or empty value
+//	{{#items}}...{{/items}}      a section, rendered once per element of a list, or once if the value is truthy
+//	{{^items}}...{{/items}}      an inverted section, rendered if the value is missing or falsy
+//	{{.}}                        the current element inside a section
+//	{{! comment }}               a comment, which is removed
+//	\{{name}}                    a literal {{name}}
+//
+// Sections and comments that are alone on their line are removed along with the line. The values
+// nil, false, "" and empty lists or maps are falsy. Lists and maps are rendered as JSON.
+
+// TemplateOption configures how a template is rendered
+type TemplateOption func(*templateOptions)
+
+type templateOptions struct {
+	strict bool
+}
+
+// WithStrictVariables makes rendering fail when a template references a variable that is not
+// defined, instead of leaving the placeholder in the output. Sections treat missing variables
+// as falsy in both modes.
+func WithStrictVariables() TemplateOption {
+	return func(o *templateOptions) {
+		o.strict = true
+	}
+}
+
+// TemplateString renders a template with the given data
+func TemplateString(templateStr string, data interface{}, opts ...TemplateOption) (string, error) {
+	options := templateOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var dataMap map[string]interface{}
+	switch d := data.(type) {
+	case map[string]interface{}:
+		dataMap = d
+	case TestDataItem:
+		dataMap = d
+	case map[string]string:
+		dataMap = make(map[string]interface{}, len(d))
+		for k, v := range d {
+			dataMap[k] = v
+		}
+	case nil:
+		dataMap = map[string]interface{}{}
+	default:
+		// If it's not a map, we can't template it
+		return templateStr, nil
+	}
+
+	nodes, err := parseTemplate(templateStr)
+	if err != nil {
+		return "", err
+	}
+
+	r := &templateRenderer{options: options, stack: []interface{}{dataMap}}
+	var sb strings.Builder
+	r.render(&sb, nodes)
+
+	if len(r.missing) > 0 {
+		noun := "variable"
+		if len(r.missing) > 1 {
+			noun = "variables"
+		}
+		return "", fmt.Errorf("undefined template %s: %s", noun, strings.Join(r.missing, ", "))
+	}
+	return sb.String(), nil
+}
+
+// TemplateVariables returns the names of the variables a template references, in the order they
+// are first used. Variables inside sections are left out, since they can refer to the values of
+// the section.
+func TemplateVariables(templateStr string) ([]string, error) {
+	nodes, err := parseTemplate(templateStr)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, node := range nodes {
+		if node.kind == textNode || node.name == "." {
+			continue
+		}
+		name, _, _ := strings.Cut(node.name, ".")
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// TemplateError is a syntax error in a template
+type TemplateError struct {
+	// Line is the line of the template the error is on, starting at 1
+	Line    int
+	Message string
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+func templateErrorf(line int, format string, args ...interface{}) error {
+	return &TemplateError{Line: line, Message: fmt.Sprintf(format, args...)}
+}
+
+type templateNodeKind int
+
+const (
+	textNode templateNodeKind = iota
+	variableNode
+	sectionNode
+	invertedSectionNode
+)
+
+type templateNode struct {
+	kind templateNodeKind
+	// text is the literal text of a text node, or the original tag of a variable
+	text string
+	name string
+	// defaultValue is the value of the default filter, if any
+	defaultValue *string
+	line         int
+	children     []*templateNode
+}
+
+// parseTemplate parses a template into a tree of nodes
+func parseTemplate(templateStr string) ([]*templateNode, error) {
+	root := &templateNode{}
+	stack := []*templateNode{root}
+	var text strings.Builder
+
+	appendNode := func(node *templateNode) {
+		parent := stack[len(stack)-1]
+		if text.Len() > 0 {
+			parent.children = append(parent.children, &templateNode{kind: textNode, text: text.String()})
+			text.Reset()
+		}
+		if node != nil {
+			parent.children = append(parent.children, node)
+		}
+	}
+
+	pos := 0
+	for pos < len(templateStr) {
+		start := strings.Index(templateStr[pos:], "{{")
+		if start < 0 {
+			text.WriteString(templateStr[pos:])
+			break
+		}
+		start += pos
+
+		// \{{ is a literal {{
+		if start > 0 && templateStr[start-1] == '\\' {
+			text.WriteString(templateStr[pos : start-1])
+			text.WriteString("{{")
+			pos = start + 2
+			continue
+		}
+
+		open, closing := "{{", "}}"
+		if strings.HasPrefix(templateStr[start:], "{{{") {
+			open, closing = "{{{", "}}}"
+		}
+		end := strings.Index(templateStr[start+len(open):], closing)
+		if end < 0 {
+			// An unterminated tag is left as text
+			text.WriteString(templateStr[pos:])
+			break
+		}
+		end += start + len(open)
+		tagEnd := end + len(closing)
+		tag := templateStr[start:tagEnd]
+		content := strings.TrimSpace(templateStr[start+len(open) : end])
+		line := strings.Count(templateStr[:start], "\n") + 1
+
+		text.WriteString(templateStr[pos:start])
+		pos = tagEnd
+
+		sigil := byte(0)
+		if open == "{{" && content != "" && strings.ContainsRune("#^/!", rune(content[0])) {
+			sigil = content[0]
+		}
+
+		if sigil != 0 {
+			// Remove standalone section and comment tags along with their line
+			if lineStart, lineEnd, ok := standaloneTag(templateStr, start, tagEnd); ok {
+				current := text.String()
+				text.Reset()
+				text.WriteString(current[:len(current)-(start-lineStart)])
+				pos = lineEnd
+			}
+		}
+
+		switch sigil {
+		case '!':
+			continue
+		case '#', '^':
+			name := strings.TrimSpace(content[1:])
+			if name == "" {
+				return nil, templateErrorf(line, "section tag '%s' is missing a name", tag)
+			}
+			kind := sectionNode
+			if sigil == '^' {
+				kind = invertedSectionNode
+			}
+			node := &templateNode{kind: kind, name: name, line: line}
+			appendNode(node)
+			stack = append(stack, node)
+		case '/':
+			name := strings.TrimSpace(content[1:])
+			current := stack[len(stack)-1]
+			if len(stack) == 1 {
+				return nil, templateErrorf(line, "closing tag '%s' does not match any section", tag)
+			}
+			if current.name != name {
+				return nil, templateErrorf(line, "closing tag '%s' does not match section '%s' opened on line %d", tag, current.name, current.line)
+			}
+			appendNode(nil)
+			stack = stack[:len(stack)-1]
+		default:
+			node, err := parseVariableTag(tag, content, line)
+			if err != nil {
+				return nil, err
+			}
+			if node == nil {
+				text.WriteString(tag)
+				continue
+			}
+			appendNode(node)
+		}
+	}
+	appendNode(nil)
+
+	if len(stack) > 1 {
+		current := stack[len(stack)-1]
+		return nil, templateErrorf(current.line, "section '%s' is not closed", current.name)
+	}
+	return root.children, nil
+}
+
+// parseVariableTag parses the contents of a variable tag. It returns nil if the tag is empty.
+func parseVariableTag(tag, content string, line int) (*templateNode, error) {
+	name, filter, hasFilter := strings.Cut(content, "|")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, nil
+	}
+
+	node := &templateNode{kind: variableNode, text: tag, name: name, line: line}
+	if !hasFilter {
+		return node, nil
+	}
+
+	filterName, arg, _ := strings.Cut(strings.TrimSpace(filter), ":")
+	if strings.TrimSpace(filterName) != "default" {
+		return nil, templateErrorf(line, "unknown filter '%s' in '%s'", strings.TrimSpace(filterName), tag)
+	}
+
+	value, err := unquoteTemplateString(strings.TrimSpace(arg))
+	if err != nil {
+		return nil, templateErrorf(line, "the default filter in '%s' expects a quoted string", tag)
+	}
+	node.defaultValue = &value
+	return node, nil
+}
+
+func unquoteTemplateString(s string) (string, error) {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return s[1 : len(s)-1], nil
+	}
+	if len(s) >= 2 && s[0] == '"' {
+		return strconv.Unquote(s)
+	}
+	return "", strconv.ErrSyntax
+}
+
+// standaloneTag reports whether the tag between start and end is the only thing on its line
+// apart from whitespace, and if so returns the start of the line and the start of the next one.
+func standaloneTag(s string, start, end int) (int, int, bool) {
+	lineStart := strings.LastIndex(s[:start], "\n") + 1
+	if strings.TrimLeft(s[lineStart:start], " \t") != "" {
+		return 0, 0, false
+	}
+
+	lineEnd := len(s)
+	if i := strings.Index(s[end:], "\n"); i >= 0 {
+		lineEnd = end + i + 1
+	}
+	if strings.TrimRight(s[end:lineEnd], " \t\r\n") != "" {
+		return 0, 0, false
+	}
+	return lineStart, lineEnd, true
+}
+
+type templateRenderer struct {
+	options templateOptions
+	// stack holds the data for the template and the values of the enclosing sections
+	stack    []interface{}
+	missing  []string
+	reported map[string]bool
+}
+
+func (r *templateRenderer) render(sb *strings.Builder, nodes []*templateNode) {
+	for _, node := range nodes {
+		switch node.kind {
+		case textNode:
+			sb.WriteString(node.text)
+		case variableNode:
+			value, found := r.lookup(node.name)
+			if node.defaultValue != nil && (!found || value == nil || value == "") {
+				sb.WriteString(*node.defaultValue)
+				continue
+			}
+			if !found {
+				if r.options.strict {
+					r.addMissing(node)
+				}
+				// Missing variables are left as they are
+				sb.WriteString(node.text)
+				continue
+			}
+			sb.WriteString(formatTemplateValue(value))
+		case sectionNode:
+			value, found := r.lookup(node.name)
+			if !found || !isTruthy(value) {
+				continue
+			}
+			if items, ok := value.([]interface{}); ok {
+				for _, item := range items {
+					r.renderWith(sb, item, node.children)
+				}
+				continue
+			}
+			r.renderWith(sb, value, node.children)
+		case invertedSectionNode:
+			value, found := r.lookup(node.name)
+			if !found || !isTruthy(value) {
+				r.render(sb, node.children)
+			}
+		}
+	}
+}
+
+func (r *templateRenderer) renderWith(sb *strings.Builder, value interface{}, nodes []*templateNode) {
+	r.stack = append(r.stack, value)
+	r.render(sb, nodes)
+	r.stack = r.stack[:len(r.stack)-1]
+}
+
+// addMissing records an undefined variable, reporting each name once with the line it is first used on
+func (r *templateRenderer) addMissing(node *templateNode) {
+	if r.reported == nil {
+		r.reported = make(map[string]bool)
+	}
+	if r.reported[node.name] {
+		return
+	}
+	r.reported[node.name] = true
+	r.missing = append(r.missing, fmt.Sprintf("%s (line %d)", node.name, node.line))
+}
+
+// lookup resolves a name against the section values, from the innermost section outwards
+func (r *templateRenderer) lookup(name string) (interface{}, bool) {
+	if name == "." {
+		return r.stack[len(r.stack)-1], true
+	}
+
+	for i := len(r.stack) - 1; i >= 0; i-- {
+		m, ok := asTemplateMap(r.stack[i])
+		if !ok {
+			continue
+		}
+		// Keys that contain dots take precedence over nested lookups
+		if value, ok := m[name]; ok {
+			return value, true
+		}
+
+		first, rest, nested := strings.Cut(name, ".")
+		value, ok := m[first]
+		if !ok {
+			continue
+		}
+		if !nested {
+			return value, true
+		}
+		return lookupPath(value, strings.Split(rest, "."))
+	}
+	return nil, false
+}
+
+func lookupPath(value interface{}, path []string) (interface{}, bool) {
+	for _, key := range path {
+		if m, ok := asTemplateMap(value); ok {
+			if value, ok = m[key]; !ok {
+				return nil, false
+			}
+			continue
+		}
+		if items, ok := value.([]interface{}); ok {
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(items) {
+				return nil, false
+			}
+			value = items[index]
+			continue
+		}
+		return nil, false
+	}
+	return value, true
+}
+
+func asTemplateMap(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case TestDataItem:
+		return v, true
+	case map[string]string:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[key] = value
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+func isTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	}
+	if m, ok := asTemplateMap(value); ok {
+		return len(m) > 0
+	}
+	return true
+}
+
+func formatTemplateValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}, map[string]interface{}, TestDataItem, map[string]string:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,104 @@
+package prompt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateString(t *testing.T) {
+	data := map[string]interface{}{
+		"name":  "Ada",
+		"age":   36,
+		"empty": "",
+		"admin": false,
+		"user": map[string]interface{}{
+			"email": "ada@example.com",
+			"tags":  []interface{}{"math", "engines"},
+		},
+		"items": []interface{}{
+			map[string]interface{}{"title": "First", "done": true},
+			map[string]interface{}{"title": "Second", "done": false},
+		},
+		"dotted.key": "literal",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"variables", "Hello {{name}}, you are {{ age }} years old", "Hello Ada, you are 36 years old"},
+		{"triple braces", "Hello {{{name}}}", "Hello Ada"},
+		{"nested values", "{{user.email}} likes {{user.tags.1}}", "ada@example.com likes engines"},
+		{"keys containing dots", "{{dotted.key}}", "literal"},
+		{"lists and maps are rendered as JSON", "{{user.tags}}", `["math","engines"]`},
+		{"missing variables are left as is", "Hi {{missing}} and {{ user.phone }}", "Hi {{missing}} and {{ user.phone }}"},
+		{"default for missing values", `{{missing | default: "nobody"}}`, "nobody"},
+		{"default for empty values", `{{empty | default: 'n/a'}}`, "n/a"},
+		{"default is not used for set values", `{{name | default: "nobody"}} {{admin | default: "yes"}}`, "Ada false"},
+		{"sections loop over lists", "{{#items}}- {{title}} ({{name}})\n{{/items}}", "- First (Ada)\n- Second (Ada)\n"},
+		{"sections over scalar lists", "{{#user.tags}}[{{.}}]{{/user.tags}}", "[math][engines]"},
+		{"sections over maps", "{{#user}}{{email}}{{/user}}", "ada@example.com"},
+		{"conditional sections", "{{#admin}}admin{{/admin}}{{^admin}}user{{/admin}}{{#missing}}x{{/missing}}", "user"},
+		{"inverted sections for empty values", "{{^empty}}empty{{/empty}}{{^items}}none{{/items}}", "empty"},
+		{"nested sections", "{{#items}}{{#done}}{{title}} is done{{/done}}{{/items}}", "First is done"},
+		{"comments", "a{{! ignored }}b", "ab"},
+		{"escaped tags", `\{{name}} is {{name}}`, "{{name}} is Ada"},
+		{"empty tags are left as is", "{{}} and {{ }}", "{{}} and {{ }}"},
+		{"unterminated tags are left as is", "Hello {{name", "Hello {{name"},
+		{
+			"standalone section lines are removed",
+			"Items:\n  {{#items}}\n  - {{title}}\n  {{/items}}\n{{! the end }}\nDone",
+			"Items:\n  - First\n  - Second\nDone",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := TemplateString(tt.template, data)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+
+	t.Run("accepts string maps and test data", func(t *testing.T) {
+		result, err := TemplateString("{{a}} {{b}}", map[string]string{"a": "1"})
+		require.NoError(t, err)
+		require.Equal(t, "1 {{b}}", result)
+
+		result, err = TemplateString("{{a}}", TestDataItem{"a": 2})
+		require.NoError(t, err)
+		require.Equal(t, "2", result)
+	})
+
+	t.Run("strict mode reports undefined variables", func(t *testing.T) {
+		_, err := TemplateString("Hi {{missing}}\n{{user.phone}} {{missing}}", data, WithStrictVariables())
+		require.EqualError(t, err, "undefined template variables: missing (line 1), user.phone (line 2)")
+
+		result, err := TemplateString(`{{name}}{{#missing}}x{{/missing}}{{other | default: "-"}}`, data, WithStrictVariables())
+		require.NoError(t, err)
+		require.Equal(t, "Ada-", result)
+	})
+
+	t.Run("reports syntax errors", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			template string
+			expected string
+		}{
+			{"unclosed section", "a\n{{#items}}b", "line 2: section 'items' is not closed"},
+			{"mismatched closing tag", "{{#items}}\n{{/user}}", "line 2: closing tag '{{/user}}' does not match section 'items' opened on line 1"},
+			{"unexpected closing tag", "{{/items}}", "line 1: closing tag '{{/items}}' does not match any section"},
+			{"unknown filter", "{{name | upper}}", "line 1: unknown filter 'upper' in '{{name | upper}}'"},
+			{"unquoted default", "{{name | default: none}}", "line 1: the default filter in '{{name | default: none}}' expects a quoted string"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := TemplateString(tt.template, data)
+				require.EqualError(t, err, tt.expected)
+			})
+		}
+	})
+}
@@ -0,0 +1,318 @@
+package prompt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestDataSource references test data rows that are stored in CSV, JSONL, JSON or YAML files
+// instead of inline in the prompt file. In YAML it is either a path, or a mapping with the path
+// under file and an optional column mapping:
+//
+//	testData: data/cases.csv
+//
+//	testData:
+//	  file: data/*.jsonl
+//	  columns:
+//	    input: question
+//	    expected: answer
+type TestDataSource struct {
+	// File is the path or glob pattern of the files, relative to the prompt file
+	File string `yaml:"file"`
+	// Columns maps template variable names to the names of the columns in the files
+	Columns map[string]string `yaml:"columns,omitempty"`
+
+	// dir is the directory that File is relative to
+	dir string
+}
+
+// testDataFormats are the file extensions that test data can be read from
+var testDataFormats = []string{".csv", ".jsonl", ".json", ".yml", ".yaml"}
+
+// decodeTestDataSource decodes a testData reference, which is either a path or a mapping
+func decodeTestDataSource(node *yaml.Node, dir string) (*TestDataSource, error) {
+	source := &TestDataSource{dir: dir}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		source.File = node.Value
+	case yaml.MappingNode:
+		if err := node.Decode(source); err != nil {
+			return nil, fmt.Errorf("invalid testData reference: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("testData must be a list of rows, a path or a mapping with a file")
+	}
+	if source.File == "" {
+		return nil, fmt.Errorf("testData reference is missing the file")
+	}
+	return source, nil
+}
+
+// MarshalYAML writes the reference as a path when it has no column mapping
+func (s TestDataSource) MarshalYAML() (interface{}, error) {
+	if len(s.Columns) == 0 {
+		return s.File, nil
+	}
+	type plain TestDataSource
+	return plain(s), nil
+}
+
+// IsGlob reports whether the reference matches files with a glob pattern
+func (s *TestDataSource) IsGlob() bool {
+	return strings.ContainsAny(s.File, "*?[")
+}
+
+// Paths returns the files the reference points to, in lexical order
+func (s *TestDataSource) Paths() ([]string, error) {
+	pattern := filepath.Join(s.dir, s.File)
+	if !s.IsGlob() {
+		return []string{pattern}, nil
+	}
+
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid testData pattern '%s': %w", s.File, err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no test data files match '%s'", s.File)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Load reads the rows of all the referenced files, renaming mapped columns to their variable names
+func (s *TestDataSource) Load() ([]TestDataItem, error) {
+	paths, err := s.Paths()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []TestDataItem{}
+	for _, path := range paths {
+		fileRows, err := readTestDataFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read test data from %s: %w", path, err)
+		}
+		for _, row := range fileRows {
+			rows = append(rows, s.toVariables(row))
+		}
+	}
+	return rows, nil
+}
+
+// Save writes the rows to the referenced file, renaming variables to their mapped columns
+func (s *TestDataSource) Save(rows []TestDataItem) error {
+	if s.IsGlob() {
+		return fmt.Errorf("cannot write test data to the pattern '%s', reference a single file instead", s.File)
+	}
+
+	fileRows := make([]TestDataItem, len(rows))
+	for i, row := range rows {
+		fileRows[i] = s.toColumns(row)
+	}
+
+	path := filepath.Join(s.dir, s.File)
+	data, err := encodeTestDataFile(path, fileRows)
+	if err != nil {
+		return fmt.Errorf("failed to write test data to %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write test data to %s: %w", path, err)
+	}
+	return nil
+}
+
+func (s *TestDataSource) toVariables(row TestDataItem) TestDataItem {
+	if len(s.Columns) == 0 {
+		return row
+	}
+	result := TestDataItem{}
+	mapped := make(map[string]bool)
+	for variable, column := range s.Columns {
+		if value, ok := row[column]; ok {
+			result[variable] = value
+		}
+		mapped[column] = true
+	}
+	for key, value := range row {
+		if _, ok := result[key]; !ok && !mapped[key] {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+func (s *TestDataSource) toColumns(row TestDataItem) TestDataItem {
+	if len(s.Columns) == 0 {
+		return row
+	}
+	result := TestDataItem{}
+	for key, value := range row {
+		if column, ok := s.Columns[key]; ok {
+			result[column] = value
+		} else if _, ok := result[key]; !ok {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// readTestDataFile reads the rows of a test data file, choosing the format from its extension
+func readTestDataFile(path string) ([]TestDataItem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			return nil, nil
+		}
+		header := records[0]
+		var rows []TestDataItem
+		for _, record := range records[1:] {
+			row := TestDataItem{}
+			for i, column := range header {
+				row[column] = record[i]
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	case ".jsonl":
+		var rows []TestDataItem
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+			var row TestDataItem
+			if err := json.Unmarshal([]byte(text), &row); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			rows = append(rows, row)
+		}
+		return rows, scanner.Err()
+	case ".json":
+		var rows []TestDataItem
+		if err := json.Unmarshal(data, &rows); err != nil {
+			return nil, err
+		}
+		return rows, nil
+	case ".yml", ".yaml":
+		var rows []TestDataItem
+		if err := yaml.Unmarshal(data, &rows); err != nil {
+			return nil, err
+		}
+		return rows, nil
+	default:
+		return nil, fmt.Errorf("unsupported test data format '%s', expected one of: %s", filepath.Ext(path), strings.Join(testDataFormats, ", "))
+	}
+}
+
+// encodeTestDataFile encodes rows in the format of the file. CSV files keep the order of their
+// existing columns, and new columns are added in alphabetical order.
+func encodeTestDataFile(path string, rows []TestDataItem) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		header := existingCSVHeader(path)
+		var extra []string
+		for _, row := range rows {
+			for key := range row {
+				if !slices.Contains(header, key) && !slices.Contains(extra, key) {
+					extra = append(extra, key)
+				}
+			}
+		}
+		sort.Strings(extra)
+		header = append(header, extra...)
+
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		if err := w.Write(header); err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			record := make([]string, len(header))
+			for i, column := range header {
+				value, err := csvValue(row[column])
+				if err != nil {
+					return nil, err
+				}
+				record[i] = value
+			}
+			if err := w.Write(record); err != nil {
+				return nil, err
+			}
+		}
+		w.Flush()
+		return buf.Bytes(), w.Error()
+	case ".jsonl":
+		var buf bytes.Buffer
+		for _, row := range rows {
+			line, err := json.Marshal(row)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), nil
+	case ".json":
+		data, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case ".yml", ".yaml":
+		return yaml.Marshal(rows)
+	default:
+		return nil, fmt.Errorf("unsupported test data format '%s', expected one of: %s", filepath.Ext(path), strings.Join(testDataFormats, ", "))
+	}
+}
+
+// existingCSVHeader returns the header of a CSV file that is about to be overwritten
+func existingCSVHeader(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	header, err := csv.NewReader(f).Read()
+	if err != nil {
+		return nil
+	}
+	return header
+}
+
+// csvValue formats a value for a CSV cell, encoding lists and mappings as JSON
+func csvValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		return string(data), err
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
@@ -0,0 +1,148 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestDataSource(t *testing.T) {
+	const messages = "messages:\n  - role: user\n    content: \"{{input}}\"\n"
+
+	t.Run("loads rows from a CSV file with a column mapping", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"data/cases.csv": "question,answer,notes\nWhat is 2+2?,4,easy\n\"Hello, world?\",hi,\n",
+			"test.prompt.yml": messages + `testData:
+  file: data/cases.csv
+  columns:
+    input: question
+    expected: answer
+`,
+		})
+
+		pf, err := LoadFromFile(filepath.Join(dir, "test.prompt.yml"))
+		require.NoError(t, err)
+		require.Equal(t, []TestDataItem{
+			{"input": "What is 2+2?", "expected": "4", "notes": "easy"},
+			{"input": "Hello, world?", "expected": "hi", "notes": ""},
+		}, pf.TestData)
+		require.Equal(t, "data/cases.csv", pf.TestDataSource.File)
+	})
+
+	t.Run("loads rows from files matching a glob", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"data/b.jsonl":    "{\"input\": \"three\"}\n\n{\"input\": \"four\", \"n\": 4}\n",
+			"data/a.jsonl":    "{\"input\": \"one\"}\n{\"input\": \"two\"}\n",
+			"test.prompt.yml": messages + "testData: data/*.jsonl\n",
+		})
+
+		pf, err := LoadFromFile(filepath.Join(dir, "test.prompt.yml"))
+		require.NoError(t, err)
+		require.Equal(t, []TestDataItem{
+			{"input": "one"}, {"input": "two"}, {"input": "three"}, {"input": "four", "n": float64(4)},
+		}, pf.TestData)
+	})
+
+	t.Run("loads rows from JSON and YAML files", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"cases.json":      `[{"input": "from json"}]`,
+			"cases.yml":       "- input: from yaml\n",
+			"json.prompt.yml": messages + "testData: cases.json\n",
+			"yaml.prompt.yml": messages + "testData: cases.yml\n",
+		})
+
+		pf, err := LoadFromFile(filepath.Join(dir, "json.prompt.yml"))
+		require.NoError(t, err)
+		require.Equal(t, []TestDataItem{{"input": "from json"}}, pf.TestData)
+
+		pf, err = LoadFromFile(filepath.Join(dir, "yaml.prompt.yml"))
+		require.NoError(t, err)
+		require.Equal(t, []TestDataItem{{"input": "from yaml"}}, pf.TestData)
+	})
+
+	t.Run("saving writes rows to the referenced file", func(t *testing.T) {
+		dir := t.TempDir()
+		promptPath := filepath.Join(dir, "test.prompt.yml")
+		writeFiles(t, dir, map[string]string{
+			"cases.csv": "question,id\nold,1\n",
+			"test.prompt.yml": messages + `testData:
+  file: cases.csv
+  columns:
+    input: question
+`,
+		})
+
+		pf, err := LoadFromFile(promptPath)
+		require.NoError(t, err)
+		pf.TestData = []TestDataItem{
+			{"input": "new", "id": "2", "expected": "yes"},
+			{"input": "other, with comma", "id": "3"},
+		}
+		require.NoError(t, pf.SaveToFile(promptPath))
+
+		data, err := os.ReadFile(filepath.Join(dir, "cases.csv"))
+		require.NoError(t, err)
+		require.Equal(t, "question,id,expected\nnew,2,yes\n\"other, with comma\",3,\n", string(data))
+
+		data, err = os.ReadFile(promptPath)
+		require.NoError(t, err)
+		require.Contains(t, string(data), "testData:\n  file: cases.csv\n  columns:\n    input: question\n")
+
+		reloaded, err := LoadFromFile(promptPath)
+		require.NoError(t, err)
+		require.Equal(t, pf.TestData[0], reloaded.TestData[0])
+	})
+
+	t.Run("saving to a glob fails", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"a.jsonl":         "{\"input\": \"one\"}\n",
+			"test.prompt.yml": messages + "testData: \"*.jsonl\"\n",
+		})
+
+		pf, err := LoadFromFile(filepath.Join(dir, "test.prompt.yml"))
+		require.NoError(t, err)
+		err = pf.SaveToFile(filepath.Join(dir, "test.prompt.yml"))
+		require.ErrorContains(t, err, "cannot write test data to the pattern '*.jsonl'")
+	})
+
+	t.Run("references in extended files are relative to them", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"shared/cases.jsonl":      "{\"input\": \"shared\"}\n",
+			"shared/base.prompt.yml":  messages + "testData: cases.jsonl\n",
+			"prompts/test.prompt.yml": "extends: ../shared/base.prompt.yml\n",
+		})
+
+		pf, err := LoadFromFile(filepath.Join(dir, "prompts", "test.prompt.yml"))
+		require.NoError(t, err)
+		require.Equal(t, []TestDataItem{{"input": "shared"}}, pf.TestData)
+		require.Equal(t, "../shared/cases.jsonl", pf.TestDataSource.File)
+	})
+
+	t.Run("reports missing files and unsupported formats", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"cases.txt":          "input\n",
+			"missing.prompt.yml": messages + "testData: data/*.csv\n",
+			"format.prompt.yml":  messages + "testData: cases.txt\n",
+		})
+
+		_, err := LoadFromFile(filepath.Join(dir, "missing.prompt.yml"))
+		require.EqualError(t, err, "no test data files match 'data/*.csv'")
+
+		_, err = LoadFromFile(filepath.Join(dir, "format.prompt.yml"))
+		require.ErrorContains(t, err, "unsupported test data format '.txt'")
+
+		diagnostics, err := ValidateFile(filepath.Join(dir, "missing.prompt.yml"))
+		require.NoError(t, err)
+		require.Len(t, diagnostics, 1)
+		require.Equal(t, "no test data files match 'data/*.csv'", diagnostics[0].Message)
+		require.Equal(t, 4, diagnostics[0].Line)
+	})
+}
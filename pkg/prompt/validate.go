@@ -0,0 +1,821 @@
+package prompt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"gopkg.in/yaml.v3"
+)
+
+// Severity is the severity of a diagnostic
+type Severity string
+
+const (
+	// SeverityError is used for problems that stop the prompt file from working as intended
+	SeverityError Severity = "error"
+	// SeverityWarning is used for problems that are likely mistakes
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a problem found in a prompt file
+type Diagnostic struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	// Rule is the ID of the lint rule that reported the problem, if any
+	Rule string `json:"rule,omitempty"`
+}
+
+// String formats the diagnostic as file:line:column: severity: message, followed by the rule ID in brackets
+func (d Diagnostic) String() string {
+	location := d.File
+	if d.Line > 0 {
+		location += fmt.Sprintf(":%d", d.Line)
+		if d.Column > 0 {
+			location += fmt.Sprintf(":%d", d.Column)
+		}
+	}
+	if d.Rule != "" {
+		return fmt.Sprintf("%s: %s: %s [%s]", location, d.Severity, d.Message, d.Rule)
+	}
+	return fmt.Sprintf("%s: %s: %s", location, d.Severity, d.Message)
+}
+
+// ValidateOption configures how a prompt file is validated
+type ValidateOption func(*validateOptions)
+
+type validateOptions struct {
+	plugins map[string]bool
+}
+
+// WithPlugins sets the evaluator plugins that can be referenced with uses, such as github/similarity.
+// When it is not set, plugin names are not checked.
+func WithPlugins(names ...string) ValidateOption {
+	return func(o *validateOptions) {
+		if o.plugins == nil {
+			o.plugins = make(map[string]bool)
+		}
+		for _, name := range names {
+			o.plugins[name] = true
+		}
+	}
+}
+
+// validMessageRoles are the roles a message can have
+var validMessageRoles = []string{"system", "user", "assistant"}
+
+// validResponseFormats are the values of responseFormat
+var validResponseFormats = []string{"text", "json_object", "json_schema"}
+
+// yamlErrorLine extracts the line number from the errors returned by the YAML parser
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): `)
+
+// ValidateFile checks the prompt file at the given path and returns the problems found in it.
+// An error is only returned if the file cannot be read.
+func ValidateFile(filePath string, opts ...ValidateOption) ([]Diagnostic, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return Validate(filePath, data, opts...), nil
+}
+
+// Validate checks the contents of a prompt file and returns the problems found in it, ordered by position
+func Validate(filePath string, data []byte, opts ...ValidateOption) []Diagnostic {
+	v := &validator{file: filePath}
+	for _, opt := range opts {
+		opt(&v.options)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		message := err.Error()
+		line := 0
+		if match := yamlErrorLine.FindStringSubmatch(message); match != nil {
+			line, _ = strconv.Atoi(match[1])
+			message = strings.TrimPrefix(message, match[0])
+		}
+		message = strings.TrimPrefix(message, "yaml: ")
+		return []Diagnostic{{File: filePath, Line: line, Severity: SeverityError, Message: "invalid YAML: " + message}}
+	}
+
+	if len(doc.Content) == 0 {
+		return []Diagnostic{{File: filePath, Line: 1, Column: 1, Severity: SeverityError, Message: "the prompt file is empty"}}
+	}
+	v.validateFile(doc.Content[0])
+
+	sort.SliceStable(v.diagnostics, func(i, j int) bool {
+		a, b := v.diagnostics[i], v.diagnostics[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return v.diagnostics
+}
+
+type validator struct {
+	file        string
+	options     validateOptions
+	diagnostics []Diagnostic
+}
+
+func (v *validator) errorf(node *yaml.Node, format string, args ...interface{}) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{
+		File:     v.file,
+		Line:     node.Line,
+		Column:   node.Column,
+		Severity: SeverityError,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// fields checks that the node is a mapping with only the known keys, and returns its values by key
+func (v *validator) fields(node *yaml.Node, where string, known ...string) (map[string]*yaml.Node, bool) {
+	if node.Kind != yaml.MappingNode {
+		v.errorf(node, "%s must be a mapping", where)
+		return nil, false
+	}
+
+	values := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if _, ok := values[key.Value]; ok {
+			v.errorf(key, "duplicate key '%s' in %s", key.Value, where)
+			continue
+		}
+		if !slices.Contains(known, key.Value) {
+			v.errorf(key, "unknown key '%s' in %s, expected one of: %s", key.Value, where, strings.Join(known, ", "))
+			continue
+		}
+		values[key.Value] = value
+	}
+	return values, true
+}
+
+// required reports a missing key on the mapping node
+func (v *validator) required(node *yaml.Node, values map[string]*yaml.Node, where string, keys ...string) bool {
+	ok := true
+	for _, key := range keys {
+		if _, found := values[key]; !found {
+			v.errorf(node, "%s is missing the required key '%s'", where, key)
+			ok = false
+		}
+	}
+	return ok
+}
+
+func (v *validator) string(node *yaml.Node, where string) (string, bool) {
+	if node.Kind != yaml.ScalarNode || node.Tag == "!!null" {
+		v.errorf(node, "%s must be a string", where)
+		return "", false
+	}
+	return node.Value, true
+}
+
+func (v *validator) number(node *yaml.Node, where string, integer bool) (float64, bool) {
+	if node.Kind == yaml.ScalarNode && (node.Tag == "!!int" || (!integer && node.Tag == "!!float")) {
+		var value float64
+		if err := node.Decode(&value); err == nil {
+			return value, true
+		}
+	}
+	if integer {
+		v.errorf(node, "%s must be an integer", where)
+	} else {
+		v.errorf(node, "%s must be a number", where)
+	}
+	return 0, false
+}
+
+func (v *validator) sequence(node *yaml.Node, where string) ([]*yaml.Node, bool) {
+	if node.Kind != yaml.SequenceNode {
+		v.errorf(node, "%s must be a list", where)
+		return nil, false
+	}
+	return node.Content, true
+}
+
+// template checks the syntax of a templated string, translating the template's line numbers to the file
+func (v *validator) template(node *yaml.Node, where string) {
+	if node.Kind != yaml.ScalarNode {
+		return
+	}
+	_, err := parseTemplate(node.Value)
+
+	var templateErr *TemplateError
+	if !errors.As(err, &templateErr) {
+		return
+	}
+
+	diagnostic := *node
+	switch node.Style {
+	case yaml.LiteralStyle, yaml.FoldedStyle:
+		// Block scalars start on the line after the indicator
+		diagnostic.Line = node.Line + templateErr.Line
+		diagnostic.Column = 0
+	default:
+		if templateErr.Line > 1 {
+			diagnostic.Line = node.Line + templateErr.Line - 1
+			diagnostic.Column = 0
+		}
+	}
+	v.errorf(&diagnostic, "invalid template in %s: %s", where, templateErr.Message)
+}
+
+// validateExtends checks the files the prompt file extends, and returns the resolved prompt file
+// if they can be loaded
+func (v *validator) validateExtends(node *yaml.Node) *yaml.Node {
+	var paths []*yaml.Node
+	switch node.Kind {
+	case yaml.ScalarNode:
+		paths = []*yaml.Node{node}
+	case yaml.SequenceNode:
+		paths = node.Content
+	}
+	if len(paths) == 0 {
+		v.errorf(node, "extends must be a path or a list of paths")
+		return nil
+	}
+
+	ok := true
+	for _, path := range paths {
+		if _, valid := v.string(path, extendsKey); !valid || !v.importedFileExists(path) {
+			ok = false
+		}
+	}
+	if !ok {
+		return nil
+	}
+
+	resolved, err := resolveImports(v.file)
+	if err != nil {
+		v.errorf(node, "%v", err)
+		return nil
+	}
+	return resolved
+}
+
+// include checks a list item that includes another file, returning false if the item is not an include
+func (v *validator) include(item *yaml.Node) bool {
+	if _, ok := includeReference(item); !ok {
+		return false
+	}
+	path := item.Content[1]
+	if _, ok := v.string(path, includeKey); ok {
+		v.importedFileExists(path)
+	}
+	return true
+}
+
+// importedFileExists reports a path of extends or include that does not exist
+func (v *validator) importedFileExists(node *yaml.Node) bool {
+	path := node.Value
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(v.file), path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		v.errorf(node, "cannot find the file '%s'", node.Value)
+		return false
+	}
+	return true
+}
+
+func (v *validator) validateFile(root *yaml.Node) {
+	values, ok := v.fields(root, "the prompt file",
+		extendsKey, "name", "description", "model", "models", "modelParameters", "responseFormat", "jsonSchema", "variables", "messages", "testData", "evaluators")
+	if !ok {
+		return
+	}
+
+	// Required keys can be inherited from the files the prompt file extends
+	hasKey := func(key string) bool {
+		_, ok := values[key]
+		return ok
+	}
+	if node, ok := values[extendsKey]; ok {
+		if resolved := v.validateExtends(node); resolved != nil {
+			hasKey = func(key string) bool {
+				return mappingValue(resolved, key) != nil
+			}
+		} else {
+			hasKey = func(string) bool { return true }
+		}
+	}
+	if !hasKey("messages") {
+		v.errorf(root, "the prompt file is missing the required key 'messages'")
+	}
+
+	for _, key := range []string{"name", "description", "model"} {
+		if node, ok := values[key]; ok {
+			v.string(node, key)
+		}
+	}
+
+	if node, ok := values["models"]; ok {
+		if items, ok := v.sequence(node, "models"); ok {
+			for i, item := range items {
+				if model, ok := v.string(item, fmt.Sprintf("models[%d]", i)); ok && model == "" {
+					v.errorf(item, "models[%d] must not be empty", i)
+				}
+			}
+		}
+	}
+
+	if node, ok := values["modelParameters"]; ok {
+		v.validateModelParameters(node)
+	}
+
+	if node, ok := values["responseFormat"]; ok {
+		if format, ok := v.string(node, "responseFormat"); ok {
+			if !slices.Contains(validResponseFormats, format) {
+				v.errorf(node, "invalid responseFormat '%s', expected one of: %s", format, strings.Join(validResponseFormats, ", "))
+			} else if format == "json_schema" && !hasKey("jsonSchema") {
+				v.errorf(node, "jsonSchema is required when responseFormat is 'json_schema'")
+			}
+		}
+	}
+
+	if node, ok := values["jsonSchema"]; ok {
+		v.validateJSONSchema(node)
+	}
+
+	if node, ok := values["variables"]; ok {
+		v.validateVariables(node)
+	}
+
+	if node, ok := values["messages"]; ok {
+		v.validateMessages(node)
+	}
+
+	if node, ok := values["testData"]; ok {
+		if (node.Kind == yaml.ScalarNode && node.Tag != "!!null") || node.Kind == yaml.MappingNode {
+			v.validateTestDataSource(node)
+		} else if items, ok := v.sequence(node, "testData"); ok {
+			for i, item := range items {
+				if v.include(item) {
+					continue
+				}
+				if item.Kind != yaml.MappingNode {
+					v.errorf(item, "testData[%d] must be a mapping of variable names to values", i)
+				}
+			}
+		}
+	}
+
+	if node, ok := values["evaluators"]; ok {
+		v.validateEvaluators(node)
+	}
+}
+
+// validateTestDataSource checks a testData reference to files, and that the rows can be read from them
+func (v *validator) validateTestDataSource(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		values, ok := v.fields(node, "testData", "file", "columns")
+		if !ok {
+			return
+		}
+		if !v.required(node, values, "testData", "file") {
+			return
+		}
+		if _, ok := v.string(values["file"], "testData.file"); !ok {
+			return
+		}
+		if columns, ok := values["columns"]; ok {
+			if columns.Kind != yaml.MappingNode {
+				v.errorf(columns, "testData.columns must be a mapping of variable names to column names")
+				return
+			}
+			for i := 1; i < len(columns.Content); i += 2 {
+				if _, ok := v.string(columns.Content[i], "testData.columns."+columns.Content[i-1].Value); !ok {
+					return
+				}
+			}
+		}
+	}
+
+	source, err := decodeTestDataSource(node, filepath.Dir(v.file))
+	if err != nil {
+		v.errorf(node, "%v", err)
+		return
+	}
+	if _, err := source.Load(); err != nil {
+		v.errorf(node, "%v", err)
+	}
+}
+
+func (v *validator) validateModelParameters(node *yaml.Node) {
+	values, ok := v.fields(node, "modelParameters", "maxTokens", "temperature", "topP")
+	if !ok {
+		return
+	}
+
+	if maxTokens, ok := values["maxTokens"]; ok {
+		if value, ok := v.number(maxTokens, "maxTokens", true); ok && value < 1 {
+			v.errorf(maxTokens, "maxTokens must be at least 1")
+		}
+	}
+	if temperature, ok := values["temperature"]; ok {
+		if value, ok := v.number(temperature, "temperature", false); ok && (value < 0 || value > 2) {
+			v.errorf(temperature, "temperature must be between 0 and 2")
+		}
+	}
+	if topP, ok := values["topP"]; ok {
+		if value, ok := v.number(topP, "topP", false); ok && (value < 0 || value > 1) {
+			v.errorf(topP, "topP must be between 0 and 1")
+		}
+	}
+}
+
+func (v *validator) validateJSONSchema(node *yaml.Node) {
+	parsed, ok := v.parseJSONSchema(node, "jsonSchema")
+	if !ok {
+		return
+	}
+	if _, ok := parsed["schema"].(map[string]interface{}); !ok {
+		v.errorf(node, "jsonSchema must have a 'schema' object")
+	}
+}
+
+// parseJSONSchema reads a JSON schema written as a JSON string, a mapping or a $ref to a file
+func (v *validator) parseJSONSchema(node *yaml.Node, where string) (map[string]interface{}, bool) {
+	var parsed map[string]interface{}
+	switch {
+	case schemaFileRef(node) != nil:
+		ref := schemaFileRef(node)
+		if _, ok := v.string(ref, where+".$ref"); !ok || !v.importedFileExists(ref) {
+			return nil, false
+		}
+		schema := JsonSchema{Ref: ref.Value}
+		if err := schema.resolveRef(filepath.Dir(v.file)); err != nil {
+			v.errorf(ref, "%v", err)
+			return nil, false
+		}
+		parsed = schema.Parsed
+	case node.Kind == yaml.MappingNode:
+		if err := node.Decode(&parsed); err != nil {
+			v.errorf(node, "invalid %s: %v", where, err)
+			return nil, false
+		}
+	default:
+		raw, ok := v.string(node, where)
+		if !ok {
+			return nil, false
+		}
+		if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+			v.errorf(node, "%s must contain a JSON object: %v", where, err)
+			return nil, false
+		}
+	}
+	return parsed, true
+}
+
+func (v *validator) validateVariables(node *yaml.Node) {
+	variables, ok := v.sequence(node, "variables")
+	if !ok {
+		return
+	}
+
+	names := make(map[string]int)
+	for i, item := range variables {
+		where := fmt.Sprintf("variables[%d]", i)
+		values, ok := v.fields(item, where, "name", "type", "description", "default", "required")
+		if !ok {
+			continue
+		}
+		if !v.required(item, values, where, "name") {
+			continue
+		}
+
+		var variable Variable
+		name, ok := v.string(values["name"], where+".name")
+		if !ok {
+			continue
+		}
+		if previous, duplicate := names[name]; duplicate {
+			v.errorf(values["name"], "variable name '%s' is already used by variables[%d]", name, previous)
+		} else {
+			names[name] = i
+		}
+		variable.Name = name
+
+		if node, ok := values["description"]; ok {
+			v.string(node, where+".description")
+		}
+		if node, ok := values["required"]; ok && (node.Kind != yaml.ScalarNode || node.Tag != "!!bool") {
+			v.errorf(node, "%s.required must be true or false", where)
+		}
+		if node, ok := values["type"]; ok {
+			typeName, ok := v.string(node, where+".type")
+			if !ok {
+				continue
+			}
+			if !slices.Contains(VariableTypes, typeName) {
+				v.errorf(node, "invalid type '%s' in %s, expected one of: %s", typeName, where, strings.Join(VariableTypes, ", "))
+				continue
+			}
+			variable.Type = typeName
+		}
+		if node, ok := values["default"]; ok {
+			if err := node.Decode(&variable.Default); err != nil {
+				v.errorf(node, "invalid default in %s: %v", where, err)
+			} else if _, err := variable.convert(variable.Default); err != nil {
+				v.errorf(node, "invalid default in %s: %v", where, err)
+			}
+		}
+	}
+}
+
+func (v *validator) validateMessages(node *yaml.Node) {
+	messages, ok := v.sequence(node, "messages")
+	if !ok {
+		return
+	}
+	if len(messages) == 0 {
+		v.errorf(node, "messages must contain at least one message")
+	}
+
+	for i, message := range messages {
+		if v.include(message) {
+			continue
+		}
+		where := fmt.Sprintf("messages[%d]", i)
+		values, ok := v.fields(message, where, "role", "content")
+		if !ok {
+			continue
+		}
+		v.required(message, values, where, "role", "content")
+
+		if role, ok := values["role"]; ok {
+			if value, ok := v.string(role, where+".role"); ok && !slices.Contains(validMessageRoles, strings.ToLower(value)) {
+				v.errorf(role, "unknown role '%s' in %s, expected one of: %s", value, where, strings.Join(validMessageRoles, ", "))
+			}
+		}
+		if content, ok := values["content"]; ok {
+			if _, ok := v.string(content, where+".content"); ok {
+				v.template(content, where+".content")
+			}
+		}
+	}
+}
+
+func (v *validator) validateEvaluators(node *yaml.Node) {
+	evaluators, ok := v.sequence(node, "evaluators")
+	if !ok {
+		return
+	}
+
+	names := make(map[string]int)
+	for i, evaluator := range evaluators {
+		if v.include(evaluator) {
+			continue
+		}
+		where := fmt.Sprintf("evaluators[%d]", i)
+		values, ok := v.fields(evaluator, where, "name", "string", "llm", "json", "uses", "with")
+		if !ok {
+			continue
+		}
+		v.required(evaluator, values, where, "name")
+
+		if name, ok := values["name"]; ok {
+			if value, ok := v.string(name, where+".name"); ok {
+				if previous, duplicate := names[value]; duplicate {
+					v.errorf(name, "evaluator name '%s' is already used by evaluators[%d]", value, previous)
+				} else {
+					names[value] = i
+				}
+			}
+		}
+
+		var kinds []string
+		for _, kind := range []string{"string", "llm", "json", "uses"} {
+			if _, ok := values[kind]; ok {
+				kinds = append(kinds, kind)
+			}
+		}
+		switch len(kinds) {
+		case 0:
+			v.errorf(evaluator, "%s must have one of 'string', 'llm', 'json' or 'uses'", where)
+		case 1:
+		default:
+			v.errorf(evaluator, "%s must have only one of 'string', 'llm', 'json' or 'uses', but has %s", where, strings.Join(kinds, ", "))
+		}
+
+		if node, ok := values["string"]; ok {
+			v.validateStringEvaluator(node, where+".string")
+		}
+		if node, ok := values["llm"]; ok {
+			v.validateLLMEvaluator(node, where+".llm")
+		}
+		if node, ok := values["json"]; ok {
+			v.validateJSONEvaluator(node, where+".json")
+		}
+		isCommand := false
+		if node, ok := values["uses"]; ok {
+			if plugin, ok := v.string(node, where+".uses"); ok {
+				isCommand = IsCommandPlugin(plugin)
+				if isCommand {
+					v.validatePluginCommand(node, where+".uses")
+				} else if v.options.plugins != nil && !v.options.plugins[plugin] {
+					v.errorf(node, "unknown evaluator plugin '%s'", plugin)
+				}
+			}
+		}
+		if node, ok := values["with"]; ok {
+			if node.Kind != yaml.MappingNode {
+				v.errorf(node, "%s.with must be a mapping", where)
+			} else if !isCommand {
+				v.errorf(node, "%s.with is only used by evaluators that run a command with 'uses'", where)
+			}
+		}
+	}
+}
+
+// validatePluginCommand checks the command of a uses evaluator, and that its program exists when it
+// is a path
+func (v *validator) validatePluginCommand(node *yaml.Node, where string) {
+	args, err := ParsePluginCommand(node.Value, filepath.Dir(v.file))
+	if err != nil {
+		v.errorf(node, "invalid command in %s: %v", where, err)
+		return
+	}
+	// Programs given as paths are absolute, as they are when the eval command runs them
+	if !filepath.IsAbs(args[0]) {
+		return
+	}
+	if _, err := os.Stat(args[0]); err != nil {
+		// Report the program as it is written, rather than where it was resolved to
+		written, _ := shellquote.Split(node.Value)
+		v.errorf(node, "cannot find the program '%s'", written[0])
+	}
+}
+
+func (v *validator) validateStringEvaluator(node *yaml.Node, where string) {
+	values, ok := v.fields(node, where, slices.Concat(StringCriteria, []string{"caseSensitive", "normalizeWhitespace", "mode"})...)
+	if !ok {
+		return
+	}
+	if !slices.ContainsFunc(StringCriteria, func(key string) bool { return values[key] != nil }) {
+		v.errorf(node, "%s must have at least one of: %s", where, strings.Join(StringCriteria, ", "))
+	}
+
+	for _, key := range []string{"equals", "contains", "startsWith", "endsWith", "notContains", "regex"} {
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		if pattern, ok := v.string(value, where+"."+key); ok {
+			v.template(value, where+"."+key)
+			if key == "regex" {
+				v.regex(value, pattern, where)
+			}
+		}
+	}
+
+	for _, key := range []string{"containsAny", "containsAll"} {
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		if items, ok := v.sequence(value, where+"."+key); ok {
+			if len(items) == 0 {
+				v.errorf(value, "%s.%s must not be empty", where, key)
+			}
+			for i, item := range items {
+				if _, ok := v.string(item, fmt.Sprintf("%s.%s[%d]", where, key, i)); ok {
+					v.template(item, fmt.Sprintf("%s.%s[%d]", where, key, i))
+				}
+			}
+		}
+	}
+
+	bounds := make(map[string]float64)
+	for _, key := range []string{"minLength", "maxLength", "minLines", "maxLines"} {
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		if bound, ok := v.number(value, where+"."+key, true); ok {
+			if bound < 0 {
+				v.errorf(value, "%s must not be negative", key)
+			} else {
+				bounds[key] = bound
+			}
+		}
+	}
+	for _, unit := range []string{"Length", "Lines"} {
+		minimum, hasMin := bounds["min"+unit]
+		maximum, hasMax := bounds["max"+unit]
+		if hasMin && hasMax && minimum > maximum {
+			v.errorf(values["min"+unit], "min%[1]s must not be greater than max%[1]s", unit)
+		}
+	}
+
+	for _, key := range []string{"caseSensitive", "normalizeWhitespace"} {
+		if value, ok := values[key]; ok && (value.Kind != yaml.ScalarNode || value.Tag != "!!bool") {
+			v.errorf(value, "%s.%s must be true or false", where, key)
+		}
+	}
+	if value, ok := values["mode"]; ok {
+		if mode, ok := v.string(value, where+".mode"); ok && mode != StringModeAll && mode != StringModeAny {
+			v.errorf(value, "invalid mode '%s' in %s, expected one of: %s, %s", mode, where, StringModeAll, StringModeAny)
+		}
+	}
+}
+
+func (v *validator) validateJSONEvaluator(node *yaml.Node, where string) {
+	values, ok := v.fields(node, where, "schema", "path", "equals", "contains", "regex")
+	if !ok {
+		return
+	}
+
+	if schema, ok := values["schema"]; ok {
+		v.parseJSONSchema(schema, where+".schema")
+	}
+	if path, ok := values["path"]; ok {
+		if expr, ok := v.string(path, where+".path"); ok {
+			if _, err := ParseJSONPath(expr); err != nil {
+				v.errorf(path, "invalid path in %s: %v", where, err)
+			}
+		}
+	}
+	for _, key := range []string{"equals", "contains", "regex"} {
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		if pattern, ok := v.string(value, where+"."+key); ok {
+			v.template(value, where+"."+key)
+			if key == "regex" {
+				v.regex(value, pattern, where)
+			}
+		}
+	}
+}
+
+// regex reports a regular expression that does not compile. Patterns with template tags can only
+// be compiled once they are rendered.
+func (v *validator) regex(node *yaml.Node, pattern, where string) {
+	if strings.Contains(pattern, "{{") {
+		return
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		v.errorf(node, "invalid regex in %s: %v", where, err)
+	}
+}
+
+func (v *validator) validateLLMEvaluator(node *yaml.Node, where string) {
+	values, ok := v.fields(node, where, "modelId", "prompt", "choices", "systemPrompt")
+	if !ok {
+		return
+	}
+	v.required(node, values, where, "modelId", "prompt", "choices")
+
+	for _, key := range []string{"modelId", "systemPrompt"} {
+		if value, ok := values[key]; ok {
+			v.string(value, where+"."+key)
+		}
+	}
+	if prompt, ok := values["prompt"]; ok {
+		if _, ok := v.string(prompt, where+".prompt"); ok {
+			v.template(prompt, where+".prompt")
+		}
+	}
+
+	choicesNode, ok := values["choices"]
+	if !ok {
+		return
+	}
+	choices, ok := v.sequence(choicesNode, where+".choices")
+	if !ok {
+		return
+	}
+	if len(choices) == 0 {
+		v.errorf(choicesNode, "%s.choices must contain at least one choice", where)
+	}
+	for i, choice := range choices {
+		choiceWhere := fmt.Sprintf("%s.choices[%d]", where, i)
+		choiceValues, ok := v.fields(choice, choiceWhere, "choice", "score")
+		if !ok {
+			continue
+		}
+		v.required(choice, choiceValues, choiceWhere, "choice", "score")
+		if value, ok := choiceValues["choice"]; ok {
+			v.string(value, choiceWhere+".choice")
+		}
+		if value, ok := choiceValues["score"]; ok {
+			v.number(value, choiceWhere+".score", false)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package prompt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("accepts a valid prompt file", func(t *testing.T) {
+		const yamlBody = `
+name: Valid
+model: openai/gpt-4o
+modelParameters:
+  maxTokens: 100
+  temperature: 0.5
+responseFormat: json_schema
+jsonSchema: '{"name": "answer", "schema": {"type": "object"}}'
+messages:
+  - role: System
+    content: You are helpful.
+  - role: user
+    content: |
+      {{#items}}
+      - {{.}}
+      {{/items}}
+testData:
+  - items: [a, b]
+evaluators:
+  - name: exact
+    string:
+      equals: "{{expected}}"
+  - name: similarity
+    uses: github/similarity
+  - name: judge
+    llm:
+      modelId: openai/gpt-4o
+      prompt: "Is {{completion}} good?"
+      choices:
+        - choice: "yes"
+          score: 1
+        - choice: "no"
+          score: 0
+`
+		diagnostics := Validate("valid.prompt.yml", []byte(yamlBody), WithPlugins("github/similarity"))
+		require.Empty(t, diagnostics)
+	})
+
+	tests := []struct {
+		name     string
+		yamlBody string
+		expected []string
+	}{
+		{
+			name:     "invalid YAML",
+			yamlBody: "name: [unclosed\nmessages: []\n",
+			expected: []string{"test.prompt.yml:1: error: invalid YAML: did not find expected ',' or ']'"},
+		},
+		{
+			name:     "empty file",
+			yamlBody: "",
+			expected: []string{"test.prompt.yml:1:1: error: the prompt file is empty"},
+		},
+		{
+			name:     "unknown and missing keys",
+			yamlBody: "name: Test\nmesages: []\n",
+			expected: []string{
+				"test.prompt.yml:1:1: error: the prompt file is missing the required key 'messages'",
+				"test.prompt.yml:2:1: error: unknown key 'mesages' in the prompt file, expected one of: extends, name, description, model, models, modelParameters, responseFormat, jsonSchema, variables, messages, testData, evaluators",
+			},
+		},
+		{
+			name: "model parameters",
+			yamlBody: `modelParameters:
+  maxTokens: 0
+  temperature: hot
+  topP: 1.5
+messages:
+  - role: user
+    content: hi
+`,
+			expected: []string{
+				"test.prompt.yml:2:14: error: maxTokens must be at least 1",
+				"test.prompt.yml:3:16: error: temperature must be a number",
+				"test.prompt.yml:4:9: error: topP must be between 0 and 1",
+			},
+		},
+		{
+			name: "models",
+			yamlBody: `models:
+  - openai/gpt-4o
+  - ""
+  - [openai/gpt-4.1]
+messages:
+  - role: user
+    content: hi
+`,
+			expected: []string{
+				"test.prompt.yml:3:5: error: models[1] must not be empty",
+				"test.prompt.yml:4:5: error: models[2] must be a string",
+			},
+		},
+		{
+			name: "string evaluators",
+			yamlBody: `messages:
+  - role: user
+    content: hi
+evaluators:
+  - name: options only
+    string:
+      caseSensitive: true
+  - name: criteria
+    string:
+      regex: "a(b"
+      containsAny: []
+      containsAll: [yes, [no]]
+      minLength: 10
+      maxLength: 5
+      maxLines: -1
+      normalizeWhitespace: sometimes
+      mode: most
+`,
+			expected: []string{
+				"test.prompt.yml:7:7: error: evaluators[0].string must have at least one of: equals, contains, startsWith, endsWith, notContains, containsAny, containsAll, regex, minLength, maxLength, minLines, maxLines",
+				"test.prompt.yml:10:14: error: invalid regex in evaluators[1].string: error parsing regexp: missing closing ): `a(b`",
+				"test.prompt.yml:11:20: error: evaluators[1].string.containsAny must not be empty",
+				"test.prompt.yml:12:26: error: evaluators[1].string.containsAll[1] must be a string",
+				"test.prompt.yml:13:18: error: minLength must not be greater than maxLength",
+				"test.prompt.yml:15:17: error: maxLines must not be negative",
+				"test.prompt.yml:16:28: error: evaluators[1].string.normalizeWhitespace must be true or false",
+				"test.prompt.yml:17:13: error: invalid mode 'most' in evaluators[1].string, expected one of: all, any",
+			},
+		},
+		{
+			name: "json evaluators",
+			yamlBody: `messages:
+  - role: user
+    content: hi
+evaluators:
+  - name: valid
+    json: {}
+  - name: checks
+    json:
+      schema: '{"type": '
+      path: items[0]
+      regex: "(("
+`,
+			expected: []string{
+				"test.prompt.yml:9:15: error: evaluators[1].json.schema must contain a JSON object: unexpected end of JSON input",
+				"test.prompt.yml:10:13: error: invalid path in evaluators[1].json: JSONPath 'items[0]' must start with $",
+				"test.prompt.yml:11:14: error: invalid regex in evaluators[1].json: error parsing regexp: missing closing ): `((`",
+			},
+		},
+		{
+			name: "command evaluators",
+			yamlBody: `messages:
+  - role: user
+    content: hi
+evaluators:
+  - name: on path
+    uses: python3 check.py
+    with:
+      threshold: 0.5
+  - name: missing
+    uses: ./evaluators/missing.sh
+  - name: unquoted
+    uses: "check 'oops"
+  - name: builtin
+    uses: github/similarity
+    with: [1]
+  - name: config
+    uses: github/similarity
+    with:
+      threshold: 0.5
+`,
+			expected: []string{
+				"test.prompt.yml:10:11: error: cannot find the program './evaluators/missing.sh'",
+				"test.prompt.yml:12:11: error: invalid command in evaluators[2].uses: invalid command 'check 'oops': Unterminated single-quoted string",
+				"test.prompt.yml:15:11: error: evaluators[3].with must be a mapping",
+				"test.prompt.yml:19:7: error: evaluators[4].with is only used by evaluators that run a command with 'uses'",
+			},
+		},
+		{
+			name: "response format",
+			yamlBody: `responseFormat: xml
+jsonSchema: '{"name": "x"}'
+messages:
+  - role: user
+    content: hi
+`,
+			expected: []string{
+				"test.prompt.yml:1:17: error: invalid responseFormat 'xml', expected one of: text, json_object, json_schema",
+				"test.prompt.yml:2:13: error: jsonSchema must have a 'schema' object",
+			},
+		},
+		{
+			name: "jsonSchema mapping",
+			yamlBody: `jsonSchema:
+  name: answer
+  schema: object
+messages:
+  - role: user
+    content: hi
+`,
+			expected: []string{
+				"test.prompt.yml:2:3: error: jsonSchema must have a 'schema' object",
+			},
+		},
+		{
+			name: "jsonSchema $ref",
+			yamlBody: `jsonSchema:
+  $ref: missing.json
+messages:
+  - role: user
+    content: hi
+`,
+			expected: []string{
+				"test.prompt.yml:2:9: error: cannot find the file 'missing.json'",
+			},
+		},
+		{
+			name: "variables",
+			yamlBody: `variables:
+  - name: topic
+    type: text
+  - name: count
+    type: integer
+    default: many
+    required: yes please
+  - name: topic
+  - description: no name
+messages:
+  - role: user
+    content: hi
+`,
+			expected: []string{
+				"test.prompt.yml:3:11: error: invalid type 'text' in variables[0], expected one of: string, number, integer, boolean, array, object",
+				"test.prompt.yml:6:14: error: invalid default in variables[1]: variable 'count' must be an integer, got \"many\"",
+				"test.prompt.yml:7:15: error: variables[1].required must be true or false",
+				"test.prompt.yml:8:11: error: variable name 'topic' is already used by variables[0]",
+				"test.prompt.yml:9:5: error: variables[3] is missing the required key 'name'",
+			},
+		},
+		{
+			name: "messages",
+			yamlBody: `messages:
+  - role: robot
+    content: hi
+  - content: "Hello {{name | upper}}"
+  - role: user
+    content: |
+      line one
+      {{/items}}
+`,
+			expected: []string{
+				"test.prompt.yml:2:11: error: unknown role 'robot' in messages[0], expected one of: system, user, assistant",
+				"test.prompt.yml:4:5: error: messages[1] is missing the required key 'role'",
+				"test.prompt.yml:4:14: error: invalid template in messages[1].content: unknown filter 'upper' in '{{name | upper}}'",
+				"test.prompt.yml:8: error: invalid template in messages[2].content: closing tag '{{/items}}' does not match any section",
+			},
+		},
+		{
+			name: "evaluators",
+			yamlBody: `messages:
+  - role: user
+    content: hi
+testData:
+  - just a string
+evaluators:
+  - name: empty
+  - name: both
+    string:
+      contains: x
+    uses: github/similarity
+  - name: judge
+    llm:
+      prompt: hi
+      choices: []
+  - name: judge
+    uses: github/unknown
+`,
+			expected: []string{
+				"test.prompt.yml:5:5: error: testData[0] must be a mapping of variable names to values",
+				"test.prompt.yml:7:5: error: evaluators[0] must have one of 'string', 'llm', 'json' or 'uses'",
+				"test.prompt.yml:8:5: error: evaluators[1] must have only one of 'string', 'llm', 'json' or 'uses', but has string, uses",
+				"test.prompt.yml:14:7: error: evaluators[2].llm is missing the required key 'modelId'",
+				"test.prompt.yml:15:16: error: evaluators[2].llm.choices must contain at least one choice",
+				"test.prompt.yml:16:11: error: evaluator name 'judge' is already used by evaluators[2]",
+				"test.prompt.yml:17:11: error: unknown evaluator plugin 'github/unknown'",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := Validate("test.prompt.yml", []byte(tt.yamlBody), WithPlugins("github/similarity"))
+
+			var actual []string
+			for _, d := range diagnostics {
+				actual = append(actual, d.String())
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+
+	t.Run("plugins are not checked without WithPlugins", func(t *testing.T) {
+		const yamlBody = "messages:\n  - role: user\n    content: hi\nevaluators:\n  - name: custom\n    uses: github/anything\n"
+		require.Empty(t, Validate("test.prompt.yml", []byte(yamlBody)))
+	})
+}
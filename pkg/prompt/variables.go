@@ -0,0 +1,159 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Variable declares a template variable that the messages of a prompt file expect
+type Variable struct {
+	Name        string      `yaml:"name"`
+	Type        string      `yaml:"type,omitempty"`
+	Description string      `yaml:"description,omitempty"`
+	Default     interface{} `yaml:"default,omitempty"`
+	Required    bool        `yaml:"required,omitempty"`
+}
+
+// VariableTypes are the types a variable can be declared with. Variables without a type accept any value.
+var VariableTypes = []string{"string", "number", "integer", "boolean", "array", "object"}
+
+// ResolveVariables checks the values of the template variables against the variables declared by
+// the prompt file. String values, such as those passed with --var, are converted to the declared
+// type, and missing or empty values are replaced with their defaults. Values of variables that
+// are not declared are kept as they are.
+func (f *File) ResolveVariables(values map[string]interface{}) (map[string]interface{}, error) {
+	if len(f.Variables) == 0 {
+		return values, nil
+	}
+
+	resolved := make(map[string]interface{}, len(values)+len(f.Variables))
+	for key, value := range values {
+		resolved[key] = value
+	}
+
+	var problems []string
+	for _, variable := range f.Variables {
+		value, ok := resolved[variable.Name]
+		if !ok || value == nil || value == "" {
+			switch {
+			case variable.Default != nil:
+				value = variable.Default
+			case variable.Required:
+				problems = append(problems, fmt.Sprintf("missing required variable '%s'", variable.Name))
+				continue
+			default:
+				continue
+			}
+		}
+
+		converted, err := variable.convert(value)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		resolved[variable.Name] = converted
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid template variables: %s", strings.Join(problems, "; "))
+	}
+	return resolved, nil
+}
+
+// convert checks that the value has the type of the variable, converting strings if needed
+func (variable Variable) convert(value interface{}) (interface{}, error) {
+	invalid := func() error {
+		return fmt.Errorf("variable '%s' must be %s, got %s", variable.Name, withArticle(variable.Type), describeValue(value))
+	}
+
+	text, isString := value.(string)
+	switch variable.Type {
+	case "":
+		return value, nil
+	case "string":
+		switch value.(type) {
+		case string:
+			return value, nil
+		case int, int64, uint64, float64, bool:
+			return fmt.Sprint(value), nil
+		}
+	case "number":
+		switch v := value.(type) {
+		case int, int64, uint64, float64:
+			return v, nil
+		}
+		if isString {
+			if number, err := strconv.ParseFloat(strings.TrimSpace(text), 64); err == nil {
+				return number, nil
+			}
+		}
+	case "integer":
+		switch v := value.(type) {
+		case int, int64, uint64:
+			return v, nil
+		case float64:
+			if v == math.Trunc(v) {
+				return int(v), nil
+			}
+		}
+		if isString {
+			if number, err := strconv.Atoi(strings.TrimSpace(text)); err == nil {
+				return number, nil
+			}
+		}
+	case "boolean":
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		if isString {
+			if b, err := strconv.ParseBool(strings.TrimSpace(text)); err == nil {
+				return b, nil
+			}
+		}
+	case "array":
+		if list, ok := value.([]interface{}); ok {
+			return list, nil
+		}
+		if isString {
+			var list []interface{}
+			if err := json.Unmarshal([]byte(text), &list); err == nil {
+				return list, nil
+			}
+		}
+	case "object":
+		if m, ok := asTemplateMap(value); ok {
+			return m, nil
+		}
+		if isString {
+			var m map[string]interface{}
+			if err := json.Unmarshal([]byte(text), &m); err == nil && m != nil {
+				return m, nil
+			}
+		}
+	}
+	return nil, invalid()
+}
+
+func withArticle(typeName string) string {
+	if typeName == "array" || typeName == "object" || typeName == "integer" {
+		return "an " + typeName
+	}
+	return "a " + typeName
+}
+
+// describeValue describes a value in an error message
+func describeValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}, TestDataItem:
+		return "a mapping"
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,86 @@
+package prompt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveVariables(t *testing.T) {
+	pf := &File{
+		Variables: []Variable{
+			{Name: "topic", Type: "string", Required: true},
+			{Name: "count", Type: "integer", Default: 3},
+			{Name: "ratio", Type: "number"},
+			{Name: "verbose", Type: "boolean", Default: false},
+			{Name: "tags", Type: "array"},
+			{Name: "user", Type: "object"},
+			{Name: "anything"},
+		},
+	}
+
+	t.Run("converts strings and fills defaults", func(t *testing.T) {
+		resolved, err := pf.ResolveVariables(map[string]interface{}{
+			"topic":    "hyenas",
+			"ratio":    "0.5",
+			"verbose":  "",
+			"tags":     `["a", "b"]`,
+			"user":     `{"name": "Mona"}`,
+			"anything": 42,
+			"expected": "kept",
+		})
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{
+			"topic":    "hyenas",
+			"count":    3,
+			"ratio":    0.5,
+			"verbose":  false,
+			"tags":     []interface{}{"a", "b"},
+			"user":     map[string]interface{}{"name": "Mona"},
+			"anything": 42,
+			"expected": "kept",
+		}, resolved)
+	})
+
+	t.Run("accepts typed values", func(t *testing.T) {
+		resolved, err := pf.ResolveVariables(map[string]interface{}{
+			"topic": 7,
+			"count": float64(5),
+			"tags":  []interface{}{"a"},
+			"user":  TestDataItem{"name": "Mona"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "7", resolved["topic"])
+		require.Equal(t, 5, resolved["count"])
+		require.Equal(t, map[string]interface{}{"name": "Mona"}, resolved["user"])
+	})
+
+	t.Run("reports every problem", func(t *testing.T) {
+		_, err := pf.ResolveVariables(map[string]interface{}{
+			"topic":   "",
+			"count":   "2.5",
+			"verbose": "sometimes",
+			"tags":    map[string]interface{}{},
+		})
+		require.EqualError(t, err, "invalid template variables: missing required variable 'topic'; "+
+			"variable 'count' must be an integer, got \"2.5\"; "+
+			"variable 'verbose' must be a boolean, got \"sometimes\"; "+
+			"variable 'tags' must be an array, got a mapping")
+	})
+
+	t.Run("files without declarations accept anything", func(t *testing.T) {
+		values := map[string]interface{}{"topic": ""}
+		resolved, err := (&File{}).ResolveVariables(values)
+		require.NoError(t, err)
+		require.Equal(t, values, resolved)
+	})
+}
+
+func TestTemplateVariables(t *testing.T) {
+	names, err := TemplateVariables("{{name}} {{user.name}} {{#items}}{{title}}{{/items}} {{^empty}}{{.}}{{/empty}} {{name}}")
+	require.NoError(t, err)
+	require.Equal(t, []string{"name", "user", "items", "empty"}, names)
+
+	_, err = TemplateVariables("{{#open}}")
+	require.Error(t, err)
+}
@@ -4,6 +4,7 @@ package util
 import (
 	"fmt"
 	"io"
+	"os"
 	"strings"
 
 	"github.com/spf13/pflag"
@@ -22,7 +23,14 @@ func Ptr[T any](value T) *T {
 	return &value
 }
 
-// ParseTemplateVariables parses template variables from the --var flags
+// stdin is the reader used for --var values of "-". It is a variable so that tests can replace it.
+var stdin io.Reader = os.Stdin
+
+// ParseTemplateVariables parses template variables from the --var and --var-file flags.
+//
+// A --var value of @path is replaced with the contents of the file at path, and a value of -
+// is read from stdin. Use @@ to pass a value that starts with a literal @. Variables from
+// --var-file files are loaded first, so that --var values take precedence over them.
 func ParseTemplateVariables(flags *pflag.FlagSet) (map[string]string, error) {
 	varFlags, err := flags.GetStringArray("var")
 	if err != nil {
@@ -30,6 +38,26 @@ func ParseTemplateVariables(flags *pflag.FlagSet) (map[string]string, error) {
 	}
 
 	templateVars := make(map[string]string)
+
+	// --var-file is optional so that commands can support --var on its own
+	if flags.Lookup("var-file") != nil {
+		varFiles, err := flags.GetStringArray("var-file")
+		if err != nil {
+			return nil, err
+		}
+		for _, varFile := range varFiles {
+			fileVars, err := LoadVariablesFile(varFile)
+			if err != nil {
+				return nil, err
+			}
+			for key, value := range fileVars {
+				templateVars[key] = value
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	readStdin := false
 	for _, varFlag := range varFlags {
 		// Handle empty strings
 		if strings.TrimSpace(varFlag) == "" {
@@ -49,9 +77,31 @@ func ParseTemplateVariables(flags *pflag.FlagSet) (map[string]string, error) {
 		}
 
 		// Check for duplicate keys
-		if _, exists := templateVars[key]; exists {
+		if seen[key] {
 			return nil, fmt.Errorf("duplicate variable key '%s'", key)
 		}
+		seen[key] = true
+
+		switch {
+		case value == "-":
+			if readStdin {
+				return nil, fmt.Errorf("only one variable can be read from stdin, but '%s' also uses '-'", key)
+			}
+			readStdin = true
+			data, err := io.ReadAll(stdin)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read variable '%s' from stdin: %w", key, err)
+			}
+			value = string(data)
+		case strings.HasPrefix(value, "@@"):
+			value = value[1:]
+		case strings.HasPrefix(value, "@"):
+			data, err := os.ReadFile(value[1:])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read variable '%s' from file: %w", key, err)
+			}
+			value = string(data)
+		}
 
 		templateVars[key] = value
 	}
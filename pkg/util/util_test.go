@@ -1,6 +1,9 @@
 package util
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/spf13/pflag"
@@ -109,3 +112,100 @@ func TestParseTemplateVariables(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTemplateVariablesSources(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	notes := writeFile("notes.md", "# Notes\nline two\n")
+	yamlFile := writeFile("vars.yml", "name: Alice\nage: 30\nempty:\n")
+	jsonFile := writeFile("vars.json", `{"name": "Bob", "admin": true}`)
+	envFile := writeFile("vars.env", "# comment\nexport TOPIC=\"machine\\nlearning\"\nTONE='dry'\n\nname=Carol\n")
+
+	tests := []struct {
+		name     string
+		varFlags []string
+		varFiles []string
+		stdin    string
+		expected map[string]string
+		errorMsg string
+	}{
+		{
+			name:     "value from a file",
+			varFlags: []string{"notes=@" + notes},
+			expected: map[string]string{"notes": "# Notes\nline two\n"},
+		},
+		{
+			name:     "escaped @",
+			varFlags: []string{"handle=@@octocat"},
+			expected: map[string]string{"handle": "@octocat"},
+		},
+		{
+			name:     "value from stdin",
+			varFlags: []string{"diff=-"},
+			stdin:    "+ added line\n",
+			expected: map[string]string{"diff": "+ added line\n"},
+		},
+		{
+			name:     "YAML variables file",
+			varFiles: []string{yamlFile},
+			expected: map[string]string{"name": "Alice", "age": "30", "empty": ""},
+		},
+		{
+			name:     "later files and --var take precedence",
+			varFlags: []string{"age=31"},
+			varFiles: []string{yamlFile, jsonFile},
+			expected: map[string]string{"name": "Bob", "age": "31", "empty": "", "admin": "true"},
+		},
+		{
+			name:     ".env variables file",
+			varFiles: []string{envFile},
+			expected: map[string]string{"TOPIC": "machine\nlearning", "TONE": "dry", "name": "Carol"},
+		},
+		{
+			name:     "missing file",
+			varFlags: []string{"notes=@" + filepath.Join(dir, "missing.md")},
+			errorMsg: "failed to read variable 'notes' from file",
+		},
+		{
+			name:     "stdin used twice",
+			varFlags: []string{"a=-", "b=-"},
+			errorMsg: "only one variable can be read from stdin, but 'b' also uses '-'",
+		},
+		{
+			name:     "unsupported variables file",
+			varFiles: []string{notes},
+			errorMsg: "unsupported variables file",
+		},
+		{
+			name:     "nested values",
+			varFiles: []string{writeFile("nested.yml", "user:\n  name: Alice\n")},
+			errorMsg: "variable 'user' must be a string, number or boolean",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalStdin := stdin
+			stdin = strings.NewReader(tt.stdin)
+			t.Cleanup(func() { stdin = originalStdin })
+
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			flags.StringArray("var", tt.varFlags, "test flag")
+			flags.StringArray("var-file", tt.varFiles, "test flag")
+
+			result, err := ParseTemplateVariables(flags)
+
+			if tt.errorMsg != "" {
+				require.ErrorContains(t, err, tt.errorMsg)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadVariablesFile loads template variables from a YAML, JSON or .env file. The format is
+// chosen based on the file extension.
+func LoadVariablesFile(filePath string) (map[string]string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read variables file: %w", err)
+	}
+
+	var vars map[string]string
+	switch ext := strings.ToLower(filepath.Ext(filePath)); {
+	case ext == ".yml" || ext == ".yaml":
+		vars, err = parseStructuredVariables(data, yaml.Unmarshal)
+	case ext == ".json":
+		vars, err = parseStructuredVariables(data, json.Unmarshal)
+	case ext == ".env" || filepath.Base(filePath) == ".env":
+		vars, err = parseEnvVariables(data)
+	default:
+		return nil, fmt.Errorf("unsupported variables file '%s': expected a .yml, .yaml, .json or .env file", filePath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse variables file '%s': %w", filePath, err)
+	}
+
+	return vars, nil
+}
+
+func parseStructuredVariables(data []byte, unmarshal func([]byte, interface{}) error) (map[string]string, error) {
+	var raw map[string]interface{}
+	if err := unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	vars := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case nil:
+			vars[key] = ""
+		case string:
+			vars[key] = v
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("variable '%s' must be a string, number or boolean", key)
+		default:
+			vars[key] = fmt.Sprintf("%v", v)
+		}
+	}
+	return vars, nil
+}
+
+// parseEnvVariables parses KEY=VALUE lines, ignoring blank lines, comments and export prefixes.
+// Values can be wrapped in single quotes, or in double quotes to use escape sequences such as \n.
+func parseEnvVariables(data []byte) (map[string]string, error) {
+	vars := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNumber)
+		}
+
+		value = strings.TrimSpace(value)
+		switch {
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid quoted value: %w", lineNumber, err)
+			}
+			value = unquoted
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		}
+
+		vars[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return vars, nil
+}